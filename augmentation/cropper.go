@@ -2,7 +2,6 @@ package augmentation
 
 import (
 	"errors"
-	"math/rand"
 	"sync"
 
 	"github.com/gerardabello/weight"
@@ -13,9 +12,17 @@ type Cropper struct {
 	weight.DataSet
 	MaxAmount []int
 
+	rng   source
 	mutex sync.Mutex
 }
 
+//Seed seeds the random crops and the wrapped data set. See weight.RandDataSet.
+func (s *Cropper) Seed(seed int64) {
+	s.mutex.Lock()
+	s.rng.seed(s.DataSet, seed)
+	s.mutex.Unlock()
+}
+
 func (s *Cropper) GetDataSize() []int {
 	size := s.DataSet.GetDataSize()
 	for i := 0; i < len(size); i++ {
@@ -38,11 +45,12 @@ func (s *Cropper) GetNextSet() (*tensor.Tensor, *tensor.Tensor, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	rng := s.rng.get()
 	amount := make([]int, len(data.Size))
 	for i := 0; i < len(data.Size); i++ {
 		if s.MaxAmount[i] > 0 {
-			amount[i] = rand.Intn(s.MaxAmount[i])
-			if rand.NormFloat64() > 0 {
+			amount[i] = rng.Intn(s.MaxAmount[i])
+			if rng.NormFloat64() > 0 {
 				amount[i] = -amount[i]
 			}
 		}
@@ -50,18 +58,11 @@ func (s *Cropper) GetNextSet() (*tensor.Tensor, *tensor.Tensor, error) {
 		amount[i] -= s.MaxAmount[i]
 	}
 
-	ndata := tensor.NewTensor(s.GetDataSize()...)
-
-	n := data.GetNumberOfValues()
-	for i := 0; i < n; i++ {
-		p := data.FlatToDim(i)
-		for j := 0; j < len(amount); j++ {
-			p[j] += amount[j]
-		}
-		if ndata.InBounds(p...) {
-			ndata.SetVal(data.Values[i], p...)
-		}
+	//The crop starts at -amount in each dimension
+	crop := data.View()
+	for i, size := range s.GetDataSize() {
+		crop = crop.Range(i, -amount[i], -amount[i]+size)
 	}
 
-	return ndata, ans, nil
+	return crop.Contiguous(), ans, nil
 }
@@ -1,7 +1,7 @@
 package augmentation
 
 import (
-	"math/rand"
+	"sync"
 
 	"github.com/gerardabello/weight"
 	"github.com/gerardabello/weight/tensor"
@@ -11,6 +11,16 @@ type Scaler struct {
 	weight.DataSet
 	StDev float64
 	Mean  float64
+
+	rng   source
+	mutex sync.Mutex
+}
+
+//Seed seeds the random scales and the wrapped data set. See weight.RandDataSet.
+func (s *Scaler) Seed(seed int64) {
+	s.mutex.Lock()
+	s.rng.seed(s.DataSet, seed)
+	s.mutex.Unlock()
 }
 
 func (s *Scaler) GetNextSet() (*tensor.Tensor, *tensor.Tensor, error) {
@@ -19,7 +29,10 @@ func (s *Scaler) GetNextSet() (*tensor.Tensor, *tensor.Tensor, error) {
 		return nil, nil, err
 	}
 
-	scale := rand.NormFloat64()*s.StDev + s.Mean
+	s.mutex.Lock()
+	scale := s.rng.get().NormFloat64()*s.StDev + s.Mean
+	s.mutex.Unlock()
+
 	data.Mul(scale)
 
 	return data, ans, nil
@@ -2,7 +2,6 @@ package augmentation
 
 import (
 	"errors"
-	"math/rand"
 	"sync"
 
 	"github.com/gerardabello/weight"
@@ -25,9 +24,17 @@ type Shifter struct {
 	MaxAmount []int
 	Method    ShiftMethod
 
+	rng   source
 	mutex sync.Mutex
 }
 
+//Seed seeds the random shifts and the wrapped data set. See weight.RandDataSet.
+func (s *Shifter) Seed(seed int64) {
+	s.mutex.Lock()
+	s.rng.seed(s.DataSet, seed)
+	s.mutex.Unlock()
+}
+
 func (s *Shifter) GetNextSet() (*tensor.Tensor, *tensor.Tensor, error) {
 	data, ans, err := s.DataSet.GetNextSet()
 	if err != nil {
@@ -41,11 +48,12 @@ func (s *Shifter) GetNextSet() (*tensor.Tensor, *tensor.Tensor, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	rng := s.rng.get()
 	amount := make([]int, len(data.Size))
 	for i := 0; i < len(data.Size); i++ {
 		if s.MaxAmount[i] > 0 {
-			amount[i] = rand.Intn(s.MaxAmount[i])
-			if rand.NormFloat64() > 0 {
+			amount[i] = rng.Intn(s.MaxAmount[i])
+			if rng.NormFloat64() > 0 {
 				amount[i] = -amount[i]
 			}
 		}
@@ -0,0 +1,25 @@
+package augmentation
+
+import (
+	"math/rand"
+
+	"github.com/gerardabello/weight"
+)
+
+//source is the random source of an augmentation. It uses a random seed until seed is called, so the augmentations can be used without a trainer.
+type source struct {
+	rng *rand.Rand
+}
+
+func (s *source) get() *rand.Rand {
+	if s.rng == nil {
+		s.rng = rand.New(rand.NewSource(rand.Int63()))
+	}
+	return s.rng
+}
+
+//seed restarts the source with seed, and seeds the wrapped data set with a seed derived from it. See weight.RandDataSet.
+func (s *source) seed(ds weight.DataSet, seed int64) {
+	s.rng = rand.New(rand.NewSource(seed))
+	weight.SeedDataSet(ds, s.rng.Int63())
+}
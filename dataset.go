@@ -32,8 +32,25 @@ type DataSet interface {
 	IsAnswer(output *tensor.Tensor, answer *tensor.Tensor) bool
 }
 
-//TestLayer return accuracy for a given layer and a given DataSet
+//RandDataSet is a data set that uses random numbers, for example to shuffle or augment the data. The trainer seeds its train set at the beginning of each epoch, before calling Reset, so a training can be reproduced and resumed from a checkpoint. The values returned after Seed and Reset should only depend on the seed.
+type RandDataSet interface {
+	DataSet
+
+	//Seed restarts the random numbers of the data set with the given seed
+	Seed(seed int64)
+}
+
+//SeedDataSet seeds the data set if it implements RandDataSet, and does nothing otherwise
+func SeedDataSet(ds DataSet, seed int64) {
+	if rds, ok := ds.(RandDataSet); ok {
+		rds.Seed(seed)
+	}
+}
+
+//TestLayer return accuracy for a given layer and a given DataSet. The layer is set to inference mode (see ModeLayer).
 func TestLayer(layer Layer, ds DataSet) (float64, error) {
+	SetTraining(layer, false)
+
 	ds.Reset()
 	n := ds.GetSetSize()
 	ncorrect := 0
@@ -32,3 +32,80 @@ type BPLearnerLayer interface {
 	//GetParamGradPointers returns a slice of pointers to prameters and gradients (in the same order) so gradient descent can update them.
 	GetParamGradPointers() ([]*float64, []*float64)
 }
+
+//ModeLayer is a layer that behaves differently during training and inference, for example applying dropout only while training. Layers start in inference mode.
+type ModeLayer interface {
+	Layer
+
+	//SetTraining switches the layer to training mode (true) or inference mode (false)
+	SetTraining(training bool)
+}
+
+//SetTraining sets the mode of the layer if it implements ModeLayer, and does nothing otherwise
+func SetTraining(layer Layer, training bool) {
+	if ml, ok := layer.(ModeLayer); ok {
+		ml.SetTraining(training)
+	}
+}
+
+//RandLayer is a layer that uses random numbers, like dropout. The trainer seeds it before each batch with a seed taken from its own random source, so a training can be reproduced and resumed from a checkpoint. Layers that are never seeded use a random seed.
+type RandLayer interface {
+	Layer
+
+	//Seed restarts the random numbers of the layer with the given seed
+	Seed(seed int64)
+}
+
+//Seed seeds the layer if it implements RandLayer, and does nothing otherwise
+func Seed(layer Layer, seed int64) {
+	if rl, ok := layer.(RandLayer); ok {
+		rl.Seed(seed)
+	}
+}
+
+//StateLayer is a layer with values that are not parameters but change during training, like the running statistics of batch normalization. They are not updated with the gradient, but they must be saved and restored with the parameters, for example in checkpoints, so the layer gives the same outputs.
+type StateLayer interface {
+	Layer
+
+	//GetStatePointers returns a slice of pointers to the values of the state
+	GetStatePointers() []*float64
+}
+
+//GetStatePointers returns the state of the layer if it implements StateLayer, and nil otherwise
+func GetStatePointers(layer Layer) []*float64 {
+	if sl, ok := layer.(StateLayer); ok {
+		return sl.GetStatePointers()
+	}
+	return nil
+}
+
+//BatchLayer is a layer that can process a batch of examples at once, which is much faster than one by one for layers that can use matrix-matrix products. A batch is a tensor with one more dimension than the examples: [input size..., batch size], so each example is contiguous in memory.
+type BatchLayer interface {
+	BPLearnerLayer
+
+	//SupportsBatch returns true if the layer can process batches. Layers that contain other layers (like networks) implement BatchLayer, but only support batches if all the layers inside them do.
+	SupportsBatch() bool
+
+	//ActivateBatch is like Activate for a batch of examples. It returns a batch [output size..., batch size].
+	ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error)
+
+	//BackPropagateBatch is like BackPropagate for the batch of the last ActivateBatch. The gradients of the parameters of all the examples are accumulated.
+	BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error)
+}
+
+//SupportsBatch returns true if the layer implements BatchLayer and supports batches
+func SupportsBatch(layer Layer) bool {
+	bl, ok := layer.(BatchLayer)
+	return ok && bl.SupportsBatch()
+}
+
+//Float32Layer is a layer that can be activated with float32 tensors. It is meant for inference, where float32 precision is usually enough and the layer uses half the memory bandwidth. The parameters are converted to float32 the first time Activate32 is called.
+type Float32Layer interface {
+	Layer
+
+	//Activate32 is like Activate for a float32 tensor
+	Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error)
+
+	//SyncFloat32 converts the parameters of the layer to float32 again. It must be called if the parameters change after the first Activate32, for example after training.
+	SyncFloat32()
+}
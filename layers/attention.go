@@ -0,0 +1,387 @@
+package layers
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas64"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//SelfAttentionLayer is a multi-head scaled dot-product self-attention layer. It takes a sequence [features, timesteps] and returns a sequence of the same size where each timestep is a weighted sum of the values of all timesteps:
+//
+// Q, K, V = X Wq, X Wk, X Wv
+// head_h = softmax(Q_h K_h^T / sqrt(features/heads)) V_h
+// Y = concat(head_1, ..., head_n) Wo
+//
+//If causal is true each timestep can only attend to itself and the previous ones.
+//
+//The parameters are stored like in a DenseLayer with input [features] and output [4*features]: first the rows of Wq, then Wk, Wv and Wo.
+type SelfAttentionLayer struct {
+	BaseLayer
+
+	features, timesteps, heads int
+
+	causal bool
+
+	//Projections [timesteps, 3*features] (a row with q, k and v for each timestep), attention weights of each head [heads, timesteps, timesteps] and concatenated heads [timesteps, features] of the last activation
+	qkv     []float64
+	attn    []float64
+	context []float64
+
+	//Temp slices for the backward pass
+	dqkv     []float64
+	dcontext []float64
+	dattn    []float64
+}
+
+type selfAttentionConfig struct {
+	Features, Timesteps, Heads int
+	Causal                     bool
+}
+
+//NewSelfAttentionLayer creates a new SelfAttentionLayer. features must be divisible by heads.
+func NewSelfAttentionLayer(features, timesteps, heads int, causal bool) *SelfAttentionLayer {
+	if features <= 0 || timesteps <= 0 || heads <= 0 {
+		panic("Self-attention layer sizes must be bigger than 0")
+	}
+
+	if features%heads != 0 {
+		panic("Number of features should be divisible by the number of heads")
+	}
+
+	l := &SelfAttentionLayer{}
+	l.BaseLayer.Init([]int{features, timesteps}, []int{features, timesteps})
+	l.id = "SelfAttention-" + l.id
+
+	l.features = features
+	l.timesteps = timesteps
+	l.heads = heads
+	l.causal = causal
+
+	l.weights = tensor.NewTensor(features, 4*features)
+	l.weightsGrad = tensor.NewTensor(features, 4*features)
+
+	stdev := math.Sqrt(1.0 / float64(features))
+	for i := range l.weights.Values {
+		l.weights.Values[i] = rand.NormFloat64() * stdev
+	}
+
+	l.bias = tensor.NewTensor(4 * features)
+	l.biasGrad = tensor.NewTensor(4 * features)
+
+	l.qkv = make([]float64, timesteps*3*features)
+	l.attn = make([]float64, heads*timesteps*timesteps)
+	l.context = make([]float64, timesteps*features)
+
+	l.dqkv = make([]float64, timesteps*3*features)
+	l.dcontext = make([]float64, timesteps*features)
+	l.dattn = make([]float64, timesteps)
+
+	return l
+}
+
+//CreateSlave creates a slave of the SelfAttentionLayer. See EnslaverLayer in package weight for more information on layer slaves.
+func (l *SelfAttentionLayer) CreateSlave() weight.Layer {
+	nl := NewSelfAttentionLayer(l.features, l.timesteps, l.heads, l.causal)
+
+	nl.weights = l.weights
+	nl.bias = l.bias
+
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *SelfAttentionLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("SelfAttention", l.ID(), selfAttentionConfig{l.features, l.timesteps, l.heads, l.causal}, l.weights, l.bias)
+}
+
+func loadSelfAttentionLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := selfAttentionConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewSelfAttentionLayer(c.Features, c.Timesteps, c.Heads, c.Causal)
+	l.id = spec.ID
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+//MACs returns the number of multiply-adds needed to activate one example. See MACLayer.
+func (l *SelfAttentionLayer) MACs() int {
+	//The projections of each timestep, and the scores and weighted sum of every pair of timesteps
+	return l.timesteps*len(l.weights.Values) + 2*l.timesteps*l.timesteps*l.features
+}
+
+//matrix returns the sequence values as a [timesteps, cols] matrix
+func (l *SelfAttentionLayer) matrix(values []float64, cols int) blas64.General {
+	return blas64.General{Rows: l.timesteps, Cols: cols, Stride: cols, Data: values}
+}
+
+//params returns the weights of the rows [from, to) of the parameters as a matrix
+func (l *SelfAttentionLayer) params(w *tensor.Tensor, from, to int) blas64.General {
+	d := l.features
+	return blas64.General{Rows: to - from, Cols: d, Stride: d, Data: w.Values[from*d : to*d]}
+}
+
+func (l *SelfAttentionLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	d := l.features
+	T := l.timesteps
+	dh := d / l.heads
+	scale := 1 / math.Sqrt(float64(dh))
+
+	//Projections
+	qkv := l.matrix(l.qkv, 3*d)
+	blas64.Gemm(blas.NoTrans, blas.Trans, 1, l.matrix(input.Values, d), l.params(l.weights, 0, 3*d), 0, qkv)
+	for t := 0; t < T; t++ {
+		row := l.qkv[t*3*d : (t+1)*3*d]
+		for i := range row {
+			row[i] += l.bias.Values[i]
+		}
+	}
+
+	for i := range l.context {
+		l.context[i] = 0
+	}
+
+	for h := 0; h < l.heads; h++ {
+		q, k, v := h*dh, d+h*dh, 2*d+h*dh
+
+		for t := 0; t < T; t++ {
+			a := l.attn[(h*T+t)*T : (h*T+t+1)*T]
+			qt := l.qkv[t*3*d+q : t*3*d+q+dh]
+
+			//Scores, masking the future timesteps if causal
+			last := T
+			if l.causal {
+				last = t + 1
+			}
+
+			max := math.Inf(-1)
+			for s := 0; s < last; s++ {
+				ks := l.qkv[s*3*d+k : s*3*d+k+dh]
+				score := 0.0
+				for i, v := range qt {
+					score += v * ks[i]
+				}
+				a[s] = score * scale
+				max = math.Max(max, a[s])
+			}
+
+			sum := 0.0
+			for s := 0; s < last; s++ {
+				a[s] = math.Exp(a[s] - max)
+				sum += a[s]
+			}
+
+			ct := l.context[t*d+h*dh : t*d+(h+1)*dh]
+			for s := 0; s < T; s++ {
+				if s >= last {
+					a[s] = 0
+					continue
+				}
+
+				a[s] /= sum
+
+				vs := l.qkv[s*3*d+v : s*3*d+v+dh]
+				for i, val := range vs {
+					ct[i] += a[s] * val
+				}
+			}
+		}
+	}
+
+	//Output projection
+	blas64.Gemm(blas.NoTrans, blas.Trans, 1, l.matrix(l.context, d), l.params(l.weights, 3*d, 4*d), 0, l.matrix(l.output.Values, d))
+	for t := 0; t < T; t++ {
+		row := l.output.Values[t*d : (t+1)*d]
+		for i := range row {
+			row[i] += l.bias.Values[3*d+i]
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+func (l *SelfAttentionLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	d := l.features
+	T := l.timesteps
+	dh := d / l.heads
+	scale := 1 / math.Sqrt(float64(dh))
+
+	//Output projection
+	matErr := l.matrix(err.Values, d)
+	blas64.Gemm(blas.Trans, blas.NoTrans, 1, matErr, l.matrix(l.context, d), 1, l.params(l.weightsGrad, 3*d, 4*d))
+	blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, matErr, l.params(l.weights, 3*d, 4*d), 0, l.matrix(l.dcontext, d))
+	for t := 0; t < T; t++ {
+		row := err.Values[t*d : (t+1)*d]
+		for i, v := range row {
+			l.biasGrad.Values[3*d+i] += v
+		}
+	}
+
+	for i := range l.dqkv {
+		l.dqkv[i] = 0
+	}
+
+	for h := 0; h < l.heads; h++ {
+		q, k, v := h*dh, d+h*dh, 2*d+h*dh
+
+		for t := 0; t < T; t++ {
+			a := l.attn[(h*T+t)*T : (h*T+t+1)*T]
+			dct := l.dcontext[t*d+h*dh : t*d+(h+1)*dh]
+
+			//Gradient of the attention weights and of the values
+			weighted := 0.0
+			for s := 0; s < T; s++ {
+				vs := l.qkv[s*3*d+v : s*3*d+v+dh]
+				dvs := l.dqkv[s*3*d+v : s*3*d+v+dh]
+
+				da := 0.0
+				for i, g := range dct {
+					da += g * vs[i]
+					dvs[i] += a[s] * g
+				}
+				l.dattn[s] = da
+				weighted += a[s] * da
+			}
+
+			//Gradient of the scores through the softmax, and of the queries and keys
+			qt := l.qkv[t*3*d+q : t*3*d+q+dh]
+			dqt := l.dqkv[t*3*d+q : t*3*d+q+dh]
+			for s := 0; s < T; s++ {
+				ds := a[s] * (l.dattn[s] - weighted) * scale
+				if ds == 0 {
+					continue
+				}
+
+				ks := l.qkv[s*3*d+k : s*3*d+k+dh]
+				dks := l.dqkv[s*3*d+k : s*3*d+k+dh]
+				for i := range dqt {
+					dqt[i] += ds * ks[i]
+					dks[i] += ds * qt[i]
+				}
+			}
+		}
+	}
+
+	//Projections
+	dqkv := l.matrix(l.dqkv, 3*d)
+	blas64.Gemm(blas.Trans, blas.NoTrans, 1, dqkv, l.matrix(l.lastInput.Values, d), 1, l.params(l.weightsGrad, 0, 3*d))
+	blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, dqkv, l.params(l.weights, 0, 3*d), 0, l.matrix(l.propagation.Values, d))
+	for t := 0; t < T; t++ {
+		row := l.dqkv[t*3*d : (t+1)*3*d]
+		for i, v := range row {
+			l.biasGrad.Values[i] += v
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+//PositionalEncodingLayer adds the sinusoidal positional encoding of the original transformer to a sequence [features, timesteps], so the layers after it can know the position of each timestep. It has no parameters.
+type PositionalEncodingLayer struct {
+	BaseLayer
+
+	encoding []float64
+}
+
+//NewPositionalEncodingLayer creates a new PositionalEncodingLayer
+func NewPositionalEncodingLayer(features, timesteps int) *PositionalEncodingLayer {
+	l := &PositionalEncodingLayer{}
+	l.BaseLayer.Init([]int{features, timesteps}, []int{features, timesteps})
+	l.id = "PositionalEncoding-" + l.id
+
+	l.encoding = make([]float64, features*timesteps)
+	for t := 0; t < timesteps; t++ {
+		for f := 0; f < features; f++ {
+			angle := float64(t) / math.Pow(10000, float64(f-f%2)/float64(features))
+			if f%2 == 0 {
+				l.encoding[t*features+f] = math.Sin(angle)
+			} else {
+				l.encoding[t*features+f] = math.Cos(angle)
+			}
+		}
+	}
+
+	return l
+}
+
+func (l *PositionalEncodingLayer) CreateSlave() weight.Layer {
+	size := l.GetInputSize()
+	nl := NewPositionalEncodingLayer(size[0], size[1])
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *PositionalEncodingLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("PositionalEncoding", l.ID(), sizeConfig{InputSize: l.GetInputSize()})
+}
+
+func loadPositionalEncodingLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := sizeConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewPositionalEncodingLayer(c.InputSize[0], c.InputSize[1])
+	l.id = spec.ID
+
+	return l, nil
+}
+
+func (l *PositionalEncodingLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	for i, v := range input.Values {
+		l.output.Values[i] = v + l.encoding[i]
+	}
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+func (l *PositionalEncodingLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	copy(l.propagation.Values, err.Values)
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
@@ -0,0 +1,78 @@
+package layers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestSelfAttentionSingleTimestep(t *testing.T) {
+	layer := NewSelfAttentionLayer(2, 1, 1, false)
+
+	//With one timestep the attention weight is 1, so the output is the value projected by Wo
+	layer.weights.Values = []float64{
+		0, 0, 0, 0, //Wq
+		0, 0, 0, 0, //Wk
+		2, 0, 0, 3, //Wv
+		0, 1, 1, 0, //Wo
+	}
+
+	out, err := layer.Activate(&tensor.Tensor{Size: []int{2, 1}, Values: []float64{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.InDeltaSlice(t, []float64{6, 2}, out.Values, 1e-12)
+}
+
+func TestSelfAttentionCausal(t *testing.T) {
+	layer := NewSelfAttentionLayer(4, 3, 2, true)
+
+	input := randomTensor(4, 3)
+	out, err := layer.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := append([]float64{}, out.Values[:4]...)
+
+	//Changing the future does not change the first timestep
+	for i := 4; i < len(input.Values); i++ {
+		input.Values[i]++
+	}
+
+	out, err = layer.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.InDeltaSlice(t, first, out.Values[:4], 1e-12)
+}
+
+func TestSelfAttentionGradients(t *testing.T) {
+	checkGradients(t, NewSelfAttentionLayer(4, 3, 2, false), randomTensor(4, 3))
+	checkGradients(t, NewSelfAttentionLayer(6, 4, 3, true), randomTensor(6, 4))
+}
+
+func TestPositionalEncoding(t *testing.T) {
+	layer := NewPositionalEncodingLayer(4, 2)
+
+	out, err := layer.Activate(tensor.NewTensor(4, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//The first timestep has angle 0 in all frequencies
+	assert.InDeltaSlice(t, []float64{0, 1, 0, 1}, out.Values[:4], 1e-12)
+	assert.InDelta(t, 0.8414709848, out.Values[4], 1e-9)
+}
+
+func TestPositionwiseDenseGradients(t *testing.T) {
+	checkGradients(t, NewPositionwiseDenseLayer(3, 5, 4), randomTensor(3, 4))
+}
+
+func TestTransformerBlockGradients(t *testing.T) {
+	checkGradients(t, NewFeedForwardBlock(4, 6, 3), randomTensor(4, 3))
+	checkGradients(t, NewTransformerBlock(4, 6, 3, 2, true), randomTensor(4, 3))
+}
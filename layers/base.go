@@ -23,6 +23,15 @@ type BaseLayer struct {
 
 	lastInput *tensor.Tensor
 
+	//Used by the layers that implement weight.BatchLayer. They are allocated for the size of the last batch.
+	batchOutput      tensor.Tensor
+	batchPropagation tensor.Tensor
+	lastBatch        *tensor.Tensor
+
+	//Used by the layers that implement weight.Float32Layer. The parameters are nil until they are needed, see SyncFloat32.
+	output32          tensor.Tensor32
+	weights32, bias32 *tensor.Tensor32
+
 	mutex *sync.Mutex
 }
 
@@ -72,12 +81,111 @@ func (l *BaseLayer) Activate(input *tensor.Tensor) error {
 	return nil
 }
 
+//BackPropagate checks that the layer has been activated and that the error has the size of the output, and zeroes the propagation
 func (l *BaseLayer) BackPropagate(err *tensor.Tensor) error {
+	if l.lastInput == nil {
+		return errors.New("BackPropagate called before Activate")
+	}
+
+	if !err.HasSize(l.GetOutputSize()) {
+		return fmt.Errorf("Layer has output size %d but error is size %d", l.GetOutputSize(), err.Size)
+	}
+
 	l.propagation.Zero(0)
 
 	return nil
 }
 
+//ActivateBatch checks that the input is a batch of inputs of the layer, stores it and allocates the output for the batch. It returns the number of examples in the batch.
+func (l *BaseLayer) ActivateBatch(input *tensor.Tensor) (int, error) {
+	n, err := batchLen(l.GetInputSize(), input)
+	if err != nil {
+		return 0, err
+	}
+
+	l.lastBatch = input
+
+	allocBatch(&l.batchOutput, l.GetOutputSize(), n)
+
+	return n, nil
+}
+
+//BackPropagateBatch checks that the error is a batch of outputs of the layer with the same number of examples than the last activated batch, and allocates the propagation for the batch
+func (l *BaseLayer) BackPropagateBatch(err *tensor.Tensor) (int, error) {
+	if l.lastBatch == nil {
+		return 0, errors.New("Layer cannot propagate the error of a batch because it has not activated any batch")
+	}
+
+	n, e := batchLen(l.GetOutputSize(), err)
+	if e != nil {
+		return 0, e
+	}
+
+	if n != l.lastBatch.Size[len(l.lastBatch.Size)-1] {
+		return 0, fmt.Errorf("Error has %d examples but the last activated batch had %d", n, l.lastBatch.Size[len(l.lastBatch.Size)-1])
+	}
+
+	allocBatch(&l.batchPropagation, l.GetInputSize(), n)
+	l.batchPropagation.Zero(0)
+
+	return n, nil
+}
+
+//Activate32 checks the size of the input, allocates the float32 output and converts the parameters to float32 if they were not converted yet
+func (l *BaseLayer) Activate32(input *tensor.Tensor32) error {
+	if !input.HasSize(l.GetInputSize()) {
+		return fmt.Errorf("Layer has input size %d but input is size %d", l.GetInputSize(), input.Size)
+	}
+
+	if !l.output32.HasSize(l.GetOutputSize()) {
+		err := l.output32.Allocate(l.GetOutputSize()...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if l.weights != nil && l.weights32 == nil {
+		l.weights32 = l.weights.ToFloat32()
+	}
+	if l.bias != nil && l.bias32 == nil {
+		l.bias32 = l.bias.ToFloat32()
+	}
+
+	return nil
+}
+
+//SyncFloat32 discards the float32 parameters, so they are converted again from the current ones in the next Activate32. See weight.Float32Layer.
+func (l *BaseLayer) SyncFloat32() {
+	l.mutex.Lock()
+	l.weights32 = nil
+	l.bias32 = nil
+	l.mutex.Unlock()
+}
+
+//batchLen returns the number of examples of a batch of tensors of the given size
+func batchLen(size []int, batch *tensor.Tensor) (int, error) {
+	if len(batch.Size) != len(size)+1 || !sameSize(batch.Size[:len(size)], size) {
+		return 0, fmt.Errorf("Layer has size %v but batch is size %v", size, batch.Size)
+	}
+
+	return batch.Size[len(size)], nil
+}
+
+//allocBatch allocates t for a batch of n tensors of the given size, unless it already has that size
+func allocBatch(t *tensor.Tensor, size []int, n int) {
+	if len(t.Size) == len(size)+1 && t.Size[len(size)] == n {
+		return
+	}
+
+	batchSize := make([]int, len(size), len(size)+1)
+	copy(batchSize, size)
+
+	err := t.Allocate(append(batchSize, n)...)
+	if err != nil {
+		panic(err)
+	}
+}
+
 func (l *BaseLayer) GetParamGradPointers() ([]*float64, []*float64) {
 	params := []*float64{}
 	grads := []*float64{}
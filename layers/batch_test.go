@@ -0,0 +1,189 @@
+package layers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//checkBatch compares the outputs, propagations and accumulated gradients of a batch of n examples with the ones of the examples processed one by one
+func checkBatch(t *testing.T, layer weight.BatchLayer, n int) {
+	const tolerance = 1e-9
+
+	assert.True(t, layer.SupportsBatch())
+
+	inSize := layer.GetInputSize()
+	outSize := layer.GetOutputSize()
+	ni := tensor.SizeLength(inSize)
+	no := tensor.SizeLength(outSize)
+
+	input := randomTensor(append(append([]int{}, inSize...), n)...)
+	errs := randomTensor(append(append([]int{}, outSize...), n)...)
+
+	_, grads := layer.GetParamGradPointers()
+	zero := func() {
+		for _, g := range grads {
+			*g = 0
+		}
+	}
+
+	//One by one
+	zero()
+	outs := make([]float64, 0, no*n)
+	props := make([]float64, 0, ni*n)
+	for b := 0; b < n; b++ {
+		in := &tensor.Tensor{Size: inSize, Values: input.Values[b*ni : (b+1)*ni]}
+		out, err := layer.Activate(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outs = append(outs, out.Values...)
+
+		e := &tensor.Tensor{Size: outSize, Values: errs.Values[b*no : (b+1)*no]}
+		prop, err := layer.BackPropagate(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		props = append(props, prop.Values...)
+	}
+
+	expectedGrads := make([]float64, len(grads))
+	for i, g := range grads {
+		expectedGrads[i] = *g
+	}
+
+	//As a batch
+	zero()
+	out, err := layer.ActivateBatch(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, errs.Size, out.Size)
+	assert.InDeltaSlice(t, outs, out.Values, tolerance)
+
+	prop, err := layer.BackPropagateBatch(errs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, input.Size, prop.Size)
+	assert.InDeltaSlice(t, props, prop.Values, tolerance)
+
+	for i, g := range grads {
+		assert.InDelta(t, expectedGrads[i], *g, tolerance, "Wrong gradient of parameter %d", i)
+	}
+}
+
+//checkBatchGradients compares the propagation and the gradients of a batch with the ones computed numerically. Unlike checkBatch, it also works with layers where the output of an example depends on the rest of the batch.
+func checkBatchGradients(t *testing.T, layer weight.BatchLayer, input *tensor.Tensor) {
+	const h = 1e-6
+	const tolerance = 1e-5
+
+	outSize := append(append([]int{}, layer.GetOutputSize()...), input.Size[len(input.Size)-1])
+	g := randomTensor(outSize...)
+
+	loss := func() float64 {
+		out, err := layer.ActivateBatch(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := 0.0
+		for i, v := range out.Values {
+			sum += v * g.Values[i]
+		}
+		return sum
+	}
+
+	params, grads := layer.GetParamGradPointers()
+	for _, gr := range grads {
+		*gr = 0
+	}
+
+	loss()
+	prop, err := layer.BackPropagateBatch(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	propagation := prop.Copy()
+
+	for i := range input.Values {
+		v := input.Values[i]
+		input.Values[i] = v + h
+		lp := loss()
+		input.Values[i] = v - h
+		lm := loss()
+		input.Values[i] = v
+
+		assert.InDelta(t, (lp-lm)/(2*h), propagation.Values[i], tolerance, "Wrong propagation of input %d", i)
+	}
+
+	for i := range params {
+		v := *params[i]
+		*params[i] = v + h
+		lp := loss()
+		*params[i] = v - h
+		lm := loss()
+		*params[i] = v
+
+		assert.InDelta(t, (lp-lm)/(2*h), *grads[i], tolerance, "Wrong gradient of parameter %d", i)
+	}
+}
+
+func TestBatchDense(t *testing.T) {
+	checkBatch(t, NewDenseLayer([]int{4, 3}, []int{5}), 7)
+	checkBatch(t, NewDenseLayer([]int{6}, []int{2, 3}), 1)
+}
+
+func TestBatchConvolutional(t *testing.T) {
+	checkBatch(t, NewConvolutionalLayer(6, 5, 3, 4, 1, 1, 1, 1, 1, 1), 3)
+	checkBatch(t, NewConvolutionalLayer(7, 7, 2, 3, 1, 2, 2, 1, 0, 1), 4)
+	checkBatch(t, NewDilatedConvolutionalLayer(7, 7, 2, 3, 1, 1, 1, 1, 2, 2, 2, 2), 2)
+	checkBatch(t, NewGroupedConvolutionalLayer(5, 5, 4, 6, 1, 1, 1, 1, 1, 1, 2), 3)
+	checkBatch(t, NewConv1DLayer(9, 3, 4, 1, 2, 1), 3)
+}
+
+func TestBatchPool(t *testing.T) {
+	checkBatch(t, NewPoolLayer([]int{6, 6, 2}, []int{2, 2, 1}), 3)
+	checkBatch(t, NewStridedPoolLayer([]int{5, 5, 2}, []int{3, 3, 1}, []int{2, 2, 1}, []int{1, 1, 0}, AvgPool), 3)
+	checkBatch(t, NewGlobalAvgPoolLayer(3, 3, 4), 3)
+}
+
+func TestBatchActivations(t *testing.T) {
+	checkBatch(t, NewReLULayer(4, 3), 5)
+	checkBatch(t, NewLeakyReLULayer(4, 3), 5)
+	checkBatch(t, NewSigmoidLayer(4, 3), 5)
+	checkBatch(t, NewSoftmaxLayer(6), 5)
+	checkBatch(t, NewIdentityLayer(4, 3), 5)
+	checkBatch(t, NewReshaperLayer([]int{4, 3}, []int{12}), 5)
+}
+
+func TestBatchFFNet(t *testing.T) {
+	checkBatch(t, newMergeNet(t, Merge{Mode: MultiplyMerge}, []int{3, 2}, []int{3, 2}, []int{3, 2}), 4)
+	checkBatch(t, newMergeNet(t, NewConcatMerge(1), []int{3, 1}, []int{3, 2}, []int{3, 3}), 4)
+
+	net, err := NewSequentialNet(
+		NewConvolutionalLayer(6, 6, 1, 2, 1, 1, 1, 1, 1, 1),
+		NewReLULayer(6, 6, 2),
+		NewPoolLayer([]int{6, 6, 2}, []int{2, 2, 1}),
+		NewDenseLayer([]int{3, 3, 2}, []int{4}),
+		NewSoftmaxLayer(4),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBatch(t, net, 3)
+
+	//The size of the batch can change between calls
+	checkBatch(t, net, 5)
+	checkBatch(t, net, 3)
+
+	//A layer without batch support makes the whole network unsupported
+	net, err = NewSequentialNet(NewDenseLayer([]int{3}, []int{3}), NewLayerNormLayer(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, net.SupportsBatch())
+	assert.False(t, weight.SupportsBatch(net))
+}
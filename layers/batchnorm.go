@@ -0,0 +1,358 @@
+package layers
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//BatchNormLayer normalizes each channel of the input to zero mean and unit variance, and then applies a learnable scale (gamma) and shift (beta). For inputs with 3 dimensions [w,h,c] the channel is the last dimension, for any other input each value is normalized independently.
+//
+//In training mode (see weight.ModeLayer) the layer normalizes with the mean and variance of the batch, and the gradient goes through them, so it needs the whole batch at once: it must be activated with ActivateBatch (see weight.BatchLayer), and Activate returns an error. The BPTrainer uses batches when all the layers of the network support them. If the trainer uses more than one goroutine, each one normalizes its part of the batch. The statistics of each batch also update the running statistics, an exponential moving average of the mean and variance that is shared with all the slaves. They are not parameters, so the trainer saves them in checkpoints and early stopping snapshots through weight.StateLayer.
+//
+//In inference mode the layer normalizes with the running statistics, with Activate or ActivateBatch.
+type BatchNormLayer struct {
+	BaseLayer
+
+	channels int
+	spatial  int
+
+	//Momentum of the moving average, applied once per batch. Values close to 1 make the statistics change slowly.
+	Momentum float64
+
+	//Epsilon is added to the variance to avoid dividing by 0
+	Epsilon float64
+
+	training bool
+
+	//Running statistics. They are shared with all the slaves.
+	stats *batchNormStats
+
+	//Statistics used in the last activation, needed to backpropagate
+	mean   []float64
+	invStd []float64
+
+	//Statistics used in the last batch. batchStats is true if they were the statistics of the batch (in training mode) instead of the running ones.
+	batchMean   []float64
+	batchInvStd []float64
+	batchStats  bool
+}
+
+type batchNormStats struct {
+	mutex *sync.Mutex
+
+	//mean and mean of the squares of each channel
+	mean   *tensor.Tensor
+	meanSq *tensor.Tensor
+}
+
+type batchNormConfig struct {
+	InputSize []int
+	Momentum  float64
+	Epsilon   float64
+}
+
+//NewBatchNormLayer creates a new BatchNormLayer with gamma set to 1 and beta to 0
+func NewBatchNormLayer(size ...int) *BatchNormLayer {
+	layer := &BatchNormLayer{}
+	layer.BaseLayer.Init(size, size)
+	layer.id = "BatchNorm-" + layer.id
+
+	layer.Momentum = 0.9
+	layer.Epsilon = 1e-5
+
+	if len(size) == 3 {
+		layer.channels = size[2]
+		layer.spatial = size[0] * size[1]
+	} else {
+		layer.channels = tensor.SizeLength(size)
+		layer.spatial = 1
+	}
+
+	layer.weights = tensor.NewTensor(layer.channels)
+	layer.weightsGrad = tensor.NewTensor(layer.channels)
+	layer.weights.Zero(1)
+
+	layer.bias = tensor.NewTensor(layer.channels)
+	layer.biasGrad = tensor.NewTensor(layer.channels)
+
+	layer.stats = &batchNormStats{
+		mutex:  &sync.Mutex{},
+		mean:   tensor.NewTensor(layer.channels),
+		meanSq: tensor.NewTensor(layer.channels),
+	}
+	//Start with mean 0 and variance 1, so the layer does not change the input until it has seen some data
+	layer.stats.meanSq.Zero(1)
+
+	layer.mean = make([]float64, layer.channels)
+	layer.invStd = make([]float64, layer.channels)
+	layer.batchMean = make([]float64, layer.channels)
+	layer.batchInvStd = make([]float64, layer.channels)
+
+	return layer
+}
+
+//CreateSlave creates a slave of the BatchNormLayer. See EnslaverLayer in package weight for more information on layer slaves. The slaves share the parameters and the running statistics. The slave starts in inference mode.
+func (l *BatchNormLayer) CreateSlave() weight.Layer {
+	nl := NewBatchNormLayer(l.GetInputSize()...)
+
+	nl.weights = l.weights
+	nl.bias = l.bias
+	nl.stats = l.stats
+
+	nl.Momentum = l.Momentum
+	nl.Epsilon = l.Epsilon
+
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *BatchNormLayer) Spec() (*LayerSpec, error) {
+	l.stats.mutex.Lock()
+	defer l.stats.mutex.Unlock()
+
+	return NewLayerSpec("BatchNorm", l.ID(), batchNormConfig{l.GetInputSize(), l.Momentum, l.Epsilon}, l.weights, l.bias, l.stats.mean, l.stats.meanSq)
+}
+
+func loadBatchNormLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := batchNormConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewBatchNormLayer(c.InputSize...)
+	l.id = spec.ID
+	l.Momentum = c.Momentum
+	l.Epsilon = c.Epsilon
+
+	return l, spec.CopyTensors(l.weights, l.bias, l.stats.mean, l.stats.meanSq)
+}
+
+//GetStatePointers returns the running mean and mean of the squares of each channel. See weight.StateLayer.
+func (l *BatchNormLayer) GetStatePointers() []*float64 {
+	state := make([]*float64, 0, 2*l.channels)
+	for i := range l.stats.mean.Values {
+		state = append(state, &l.stats.mean.Values[i])
+	}
+	for i := range l.stats.meanSq.Values {
+		state = append(state, &l.stats.meanSq.Values[i])
+	}
+	return state
+}
+
+//SetTraining switches between the statistics of the batch (true) and the running statistics (false)
+func (l *BatchNormLayer) SetTraining(training bool) {
+	l.mutex.Lock()
+	l.training = training
+	l.mutex.Unlock()
+}
+
+//RunningStats returns a copy of the running mean and variance of each channel
+func (l *BatchNormLayer) RunningStats() (mean, variance []float64) {
+	l.stats.mutex.Lock()
+	defer l.stats.mutex.Unlock()
+
+	mean = make([]float64, l.channels)
+	variance = make([]float64, l.channels)
+	for c := range mean {
+		mean[c] = l.stats.mean.Values[c]
+		variance[c] = math.Max(0, l.stats.meanSq.Values[c]-mean[c]*mean[c])
+	}
+	return
+}
+
+//runningStatistics stores the running mean and the inverse of the standard deviation of each channel in mean and invStd. It takes a snapshot, as other slaves can update them before this layer backpropagates.
+func (l *BatchNormLayer) runningStatistics(mean, invStd []float64) {
+	m, variance := l.RunningStats()
+	for c := 0; c < l.channels; c++ {
+		mean[c] = m[c]
+		invStd[c] = 1 / math.Sqrt(variance[c]+l.Epsilon)
+	}
+}
+
+//batchStatistics stores the mean and the inverse of the standard deviation of each channel of a batch of n examples in mean and invStd, and updates the running statistics with them
+func (l *BatchNormLayer) batchStatistics(values []float64, n int, mean, invStd []float64) {
+	size := l.channels * l.spatial
+	count := float64(n * l.spatial)
+
+	l.stats.mutex.Lock()
+	for c := 0; c < l.channels; c++ {
+		sum := 0.0
+		for b := 0; b < n; b++ {
+			for _, v := range values[b*size+c*l.spatial : b*size+(c+1)*l.spatial] {
+				sum += v
+			}
+		}
+		mean[c] = sum / count
+
+		sumSq := 0.0
+		for b := 0; b < n; b++ {
+			for _, v := range values[b*size+c*l.spatial : b*size+(c+1)*l.spatial] {
+				sumSq += (v - mean[c]) * (v - mean[c])
+			}
+		}
+		variance := sumSq / count
+		invStd[c] = 1 / math.Sqrt(variance+l.Epsilon)
+
+		m := l.Momentum
+		l.stats.mean.Values[c] = m*l.stats.mean.Values[c] + (1-m)*mean[c]
+		l.stats.meanSq.Values[c] = m*l.stats.meanSq.Values[c] + (1-m)*(variance+mean[c]*mean[c])
+	}
+	l.stats.mutex.Unlock()
+}
+
+//normalize computes the output of n examples with the given statistics
+func (l *BatchNormLayer) normalize(in, out []float64, n int, mean, invStd []float64) {
+	size := l.channels * l.spatial
+
+	for c := 0; c < l.channels; c++ {
+		scale := l.weights.Values[c] * invStd[c]
+		shift := l.bias.Values[c] - scale*mean[c]
+
+		for b := 0; b < n; b++ {
+			start := b*size + c*l.spatial
+			for i := start; i < start+l.spatial; i++ {
+				out[i] = in[i]*scale + shift
+			}
+		}
+	}
+}
+
+//backAffine computes the gradients of gamma and beta and propagates the error of n examples normalized with fixed statistics (the running ones)
+func (l *BatchNormLayer) backAffine(in, errs, prop []float64, n int, mean, invStd []float64) {
+	size := l.channels * l.spatial
+
+	for c := 0; c < l.channels; c++ {
+		scale := l.weights.Values[c] * invStd[c]
+
+		for b := 0; b < n; b++ {
+			start := b*size + c*l.spatial
+			for i := start; i < start+l.spatial; i++ {
+				xhat := (in[i] - mean[c]) * invStd[c]
+				l.weightsGrad.Values[c] += errs[i] * xhat
+				l.biasGrad.Values[c] += errs[i]
+				prop[i] = errs[i] * scale
+			}
+		}
+	}
+}
+
+//backBatch computes the gradients of gamma and beta and propagates the error of n examples normalized with their own statistics. The gradient goes through the mean and the variance of the batch.
+func (l *BatchNormLayer) backBatch(in, errs, prop []float64, n int, mean, invStd []float64) {
+	size := l.channels * l.spatial
+	count := float64(n * l.spatial)
+
+	for c := 0; c < l.channels; c++ {
+		sumErr := 0.0
+		sumErrXhat := 0.0
+		for b := 0; b < n; b++ {
+			start := b*size + c*l.spatial
+			for i := start; i < start+l.spatial; i++ {
+				xhat := (in[i] - mean[c]) * invStd[c]
+				sumErr += errs[i]
+				sumErrXhat += errs[i] * xhat
+			}
+		}
+
+		l.weightsGrad.Values[c] += sumErrXhat
+		l.biasGrad.Values[c] += sumErr
+
+		//dx = gamma*invStd/N * (N*dy - sum(dy) - xhat*sum(dy*xhat))
+		scale := l.weights.Values[c] * invStd[c] / count
+		for b := 0; b < n; b++ {
+			start := b*size + c*l.spatial
+			for i := start; i < start+l.spatial; i++ {
+				xhat := (in[i] - mean[c]) * invStd[c]
+				prop[i] = scale * (count*errs[i] - sumErr - xhat*sumErrXhat)
+			}
+		}
+	}
+}
+
+//Activate normalizes the input with the running statistics. In training mode it returns an error, as the statistics of the batch are needed (see ActivateBatch).
+func (l *BatchNormLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	if l.training {
+		l.mutex.Unlock()
+		return nil, errors.New("BatchNormLayer needs the whole batch in training mode. Use ActivateBatch, or a network where all the layers support batches (see weight.BatchLayer)")
+	}
+
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	l.runningStatistics(l.mean, l.invStd)
+	l.normalize(input.Values, l.output.Values, 1, l.mean, l.invStd)
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+//BackPropagate computes the gradients of gamma and beta and propagates the error of the last activation
+func (l *BatchNormLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	l.backAffine(l.lastInput.Values, err.Values, l.propagation.Values, 1, l.mean, l.invStd)
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *BatchNormLayer) SupportsBatch() bool {
+	return true
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer. In training mode the batch is normalized with its own statistics, which also update the running statistics.
+func (l *BatchNormLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	l.batchStats = l.training
+	if l.training {
+		l.batchStatistics(input.Values, n, l.batchMean, l.batchInvStd)
+	} else {
+		l.runningStatistics(l.batchMean, l.batchInvStd)
+	}
+
+	l.normalize(input.Values, l.batchOutput.Values, n, l.batchMean, l.batchInvStd)
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *BatchNormLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	if l.batchStats {
+		l.backBatch(l.lastBatch.Values, err.Values, l.batchPropagation.Values, n, l.batchMean, l.batchInvStd)
+	} else {
+		l.backAffine(l.lastBatch.Values, err.Values, l.batchPropagation.Values, n, l.batchMean, l.batchInvStd)
+	}
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
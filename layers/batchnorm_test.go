@@ -0,0 +1,244 @@
+package layers
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestBatchNormInitialActivation(t *testing.T) {
+	layer := NewBatchNormLayer(2, 2, 3)
+
+	input := tensor.NewTensor(2, 2, 3)
+	for i := range input.Values {
+		input.Values[i] = rand.NormFloat64()
+	}
+
+	out, err := layer.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Before training the statistics are mean 0 and variance 1, so the layer should not change the input
+	assert.InDeltaSlice(t, input.Values, out.Values, 1e-4, "Untrained layer should not change the input")
+}
+
+func TestBatchNormRunningStats(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewBatchNormLayer(4, 4, 2)
+	slave := layer.CreateSlave().(*BatchNormLayer)
+	layer.SetTraining(true)
+	slave.SetTraining(true)
+
+	input := tensor.NewTensor(4, 4, 2, 8)
+
+	for n := 0; n < 500; n++ {
+		//Channel 0 has mean 3 and variance 4, channel 1 has mean -1 and variance 0.25
+		for b := 0; b < 8; b++ {
+			for i := 0; i < 16; i++ {
+				input.Values[b*32+i] = 3 + 2*rand.NormFloat64()
+				input.Values[b*32+16+i] = -1 + 0.5*rand.NormFloat64()
+			}
+		}
+
+		//Alternate master and slave, as the trainer goroutines do
+		l := layer
+		if n%2 == 1 {
+			l = slave
+		}
+
+		_, err := l.ActivateBatch(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mean, variance := slave.RunningStats()
+	assert.InDelta(3, mean[0], 0.3)
+	assert.InDelta(4, variance[0], 0.8)
+	assert.InDelta(-1, mean[1], 0.1)
+	assert.InDelta(0.25, variance[1], 0.05)
+
+	//In inference mode the statistics do not change
+	layer.SetTraining(false)
+	input.Values[0] = 1000
+	_, err := layer.ActivateBatch(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, v := layer.RunningStats()
+	assert.Equal(mean, m)
+	assert.Equal(variance, v)
+}
+
+func TestBatchNormTraining(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewBatchNormLayer(3, 2, 2)
+	layer.Epsilon = 0
+	layer.weights.Values = []float64{2, 0.5}
+	layer.bias.Values = []float64{1, -3}
+	layer.SetTraining(true)
+
+	input := randomTensor(3, 2, 2, 5)
+	for i := range input.Values {
+		input.Values[i] = 4*input.Values[i] + 7
+	}
+
+	out, err := layer.ActivateBatch(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Each channel of the batch is normalized with its own statistics, so it has mean beta and variance gamma^2
+	for c := 0; c < 2; c++ {
+		values := []float64{}
+		for b := 0; b < 5; b++ {
+			values = append(values, out.Values[b*12+c*6:b*12+(c+1)*6]...)
+		}
+
+		mean := 0.0
+		for _, v := range values {
+			mean += v / float64(len(values))
+		}
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mean) * (v - mean) / float64(len(values))
+		}
+
+		assert.InDelta(layer.bias.Values[c], mean, 1e-9)
+		assert.InDelta(layer.weights.Values[c]*layer.weights.Values[c], variance, 1e-9)
+	}
+
+	//A single example cannot be normalized with the statistics of the batch
+	_, err = layer.Activate(randomTensor(3, 2, 2))
+	assert.Error(err)
+
+	layer.SetTraining(false)
+	_, err = layer.Activate(randomTensor(3, 2, 2))
+	assert.NoError(err)
+}
+
+func TestBatchNormBatch(t *testing.T) {
+	//In inference mode every example is normalized with the running statistics, as one by one
+	layer := NewBatchNormLayer(3, 3, 2)
+	layer.stats.mean.Values = []float64{0.5, -1}
+	layer.stats.meanSq.Values = []float64{2, 3}
+	layer.weights.Values = []float64{1.5, -0.5}
+	layer.bias.Values = []float64{0.2, 0.1}
+	checkBatch(t, layer, 4)
+
+	checkBatch(t, NewBatchNormLayer(5), 3)
+}
+
+func TestBatchNormGradients(t *testing.T) {
+	layer := NewBatchNormLayer(3, 2, 2)
+	layer.weights.Values = []float64{1.5, -0.5}
+	layer.bias.Values = []float64{0.2, 0.1}
+	layer.SetTraining(true)
+	checkBatchGradients(t, layer, randomTensor(3, 2, 2, 4))
+
+	layer = NewBatchNormLayer(5)
+	layer.SetTraining(true)
+	checkBatchGradients(t, layer, randomTensor(5, 6))
+
+	//In inference mode the statistics are constant
+	layer.SetTraining(false)
+	checkBatchGradients(t, layer, randomTensor(5, 6))
+}
+
+func TestBatchNormBackPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewBatchNormLayer(2)
+	layer.Epsilon = 0
+	layer.stats.mean.Values = []float64{1, -2}
+	layer.stats.meanSq.Values = []float64{1 + 4, 4 + 0.25}
+	layer.weights.Values = []float64{2, 3}
+	layer.bias.Values = []float64{0.5, -1}
+
+	input := &tensor.Tensor{Size: []int{2}, Values: []float64{3, -1}}
+
+	out, err := layer.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//(3-1)/2*2+0.5 and (-1+2)/0.5*3-1
+	assert.InDeltaSlice([]float64{2.5, 5}, out.Values, 1e-12)
+
+	grad := &tensor.Tensor{Size: []int{2}, Values: []float64{1, -2}}
+	prop, err := layer.BackPropagate(grad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.InDeltaSlice([]float64{1, -12}, prop.Values, 1e-12)
+	assert.InDeltaSlice([]float64{1, -4}, layer.weightsGrad.Values, 1e-12)
+	assert.InDeltaSlice([]float64{1, -2}, layer.biasGrad.Values, 1e-12)
+}
+
+func TestBatchNormSaveLoad(t *testing.T) {
+	layer := NewBatchNormLayer(3)
+	layer.Momentum = 0.5
+
+	layer.SetTraining(true)
+	_, err := layer.ActivateBatch(randomTensor(3, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net, err := NewSequentialNet(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = SaveNet(&b, net)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadNet(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadedLayer := loaded.nodes[0].layer.(*BatchNormLayer)
+	mean, variance := loadedLayer.RunningStats()
+	expectedMean, expectedVariance := layer.RunningStats()
+
+	assert.InDeltaSlice(t, expectedMean, mean, 1e-12, "Running mean should be saved")
+	assert.InDeltaSlice(t, expectedVariance, variance, 1e-12, "Running variance should be saved")
+	assert.Equal(t, 0.5, loadedLayer.Momentum)
+}
+
+func TestBatchNormState(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewBatchNormLayer(2, 2, 3)
+	state := layer.GetStatePointers()
+	assert.Len(state, 6)
+
+	//Mean of each channel, and then mean of the squares
+	for i, v := range []float64{1, 2, 3, 5, 8, 13} {
+		*state[i] = v
+	}
+	mean, variance := layer.RunningStats()
+	assert.Equal([]float64{1, 2, 3}, mean)
+	assert.Equal([]float64{4, 4, 4}, variance)
+
+	//Networks collect the state of all their layers, including the ones inside other networks
+	block := NewResidualBlock([]int{4, 4, 2}, 2, 1)
+	net, err := NewSequentialNet(block, NewBatchNormLayer(4, 4, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(block.GetStatePointers(), 8)
+	assert.Len(net.GetStatePointers(), 12)
+}
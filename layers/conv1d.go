@@ -0,0 +1,205 @@
+package layers
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//Conv1DLayer convolves sequences with shape [length, channels]. A sequence has the same memory layout as an image [length, 1, channels], so the layer uses a ConvolutionalLayer with height 1 and only changes the shape of the tensors.
+type Conv1DLayer struct {
+	*ConvolutionalLayer
+
+	inputSize  []int
+	outputSize []int
+
+	//Tensors that share the values with the ones of the inner layer, but have the 1D shape
+	inView   tensor.Tensor
+	errView  tensor.Tensor
+	outView  tensor.Tensor
+	propView tensor.Tensor
+
+	viewMutex *sync.Mutex
+}
+
+type conv1DConfig struct {
+	Length, Channels int
+	NKernels         int
+	KernelPad        int
+	Stride           int
+	Pad              int
+	Dilation         int
+}
+
+//NewConv1DLayer creates a new Conv1DLayer. The kernel has size kernelPad*2+1, like in NewConvolutionalLayer. The output size is [(length + pad*2 - (kernelPad*2+1))/stride + 1, nKernels].
+func NewConv1DLayer(length, channels, nKernels, kernelPad, stride, pad int) *Conv1DLayer {
+	return NewDilatedConv1DLayer(length, channels, nKernels, kernelPad, stride, pad, 1)
+}
+
+//NewDilatedConv1DLayer creates a Conv1DLayer with a dilated kernel. See NewDilatedConvolutionalLayer.
+func NewDilatedConv1DLayer(length, channels, nKernels, kernelPad, stride, pad, dilation int) *Conv1DLayer {
+	conv := newConvolutionalLayer(length, 1, channels, nKernels, kernelPad, 0, stride, 1, pad, 0, dilation, 1, 1)
+	conv.id = "Conv1D-" + RandomID(8)
+
+	out := conv.GetOutputSize()
+
+	return &Conv1DLayer{
+		ConvolutionalLayer: conv,
+		inputSize:          []int{length, channels},
+		outputSize:         []int{out[0], out[2]},
+		viewMutex:          &sync.Mutex{},
+	}
+}
+
+//CreateSlave creates a slave of the Conv1DLayer. See EnslaverLayer in package weight for more information on layer slaves.
+func (l *Conv1DLayer) CreateSlave() weight.Layer {
+	c := l.config()
+	nl := NewDilatedConv1DLayer(c.Length, c.Channels, c.NKernels, c.KernelPad, c.Stride, c.Pad, c.Dilation)
+
+	nl.id = l.ID()
+
+	nl.weights = l.weights
+	nl.bias = l.bias
+
+	return nl
+}
+
+func (l *Conv1DLayer) config() conv1DConfig {
+	return conv1DConfig{
+		Length:    l.inputSize[0],
+		Channels:  l.inputSize[1],
+		NKernels:  l.outputSize[1],
+		KernelPad: (l.weights.Size[0] - 1) / 2,
+		Stride:    l.strideX,
+		Pad:       l.padX,
+		Dilation:  l.dilationX,
+	}
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *Conv1DLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("Conv1D", l.ID(), l.config(), l.weights, l.bias)
+}
+
+func loadConv1DLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := conv1DConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewDilatedConv1DLayer(c.Length, c.Channels, c.NKernels, c.KernelPad, c.Stride, c.Pad, c.Dilation)
+	l.id = spec.ID
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+func (l *Conv1DLayer) GetInputSize() []int {
+	return l.inputSize
+}
+
+func (l *Conv1DLayer) GetOutputSize() []int {
+	return l.outputSize
+}
+
+//Activate convolves an input [length, channels]
+func (l *Conv1DLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.viewMutex.Lock()
+	defer l.viewMutex.Unlock()
+
+	if !input.HasSize(l.inputSize) {
+		return nil, fmt.Errorf("Layer has input size %d but input is size %d", l.inputSize, input.Size)
+	}
+
+	l.inView.Size = l.ConvolutionalLayer.GetInputSize()
+	l.inView.Values = input.Values
+
+	out, err := l.ConvolutionalLayer.Activate(&l.inView)
+	if err != nil {
+		return nil, err
+	}
+
+	l.outView.Size = l.outputSize
+	l.outView.Values = out.Values
+
+	return &l.outView, nil
+}
+
+func (l *Conv1DLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.viewMutex.Lock()
+	defer l.viewMutex.Unlock()
+
+	if !err.HasSize(l.outputSize) {
+		return nil, fmt.Errorf("Error gradient has size %v but layer output is %v", err.Size, l.outputSize)
+	}
+
+	l.errView.Size = l.ConvolutionalLayer.GetOutputSize()
+	l.errView.Values = err.Values
+
+	prop, e := l.ConvolutionalLayer.BackPropagate(&l.errView)
+	if e != nil {
+		return nil, e
+	}
+
+	l.propView.Size = l.inputSize
+	l.propView.Values = prop.Values
+
+	return &l.propView, nil
+}
+
+//NewPool1DLayer creates a PoolLayer for sequences [length, channels] that pools each channel independently
+func NewPool1DLayer(length, channels, kernel, stride, pad int, mode PoolMode) *PoolLayer {
+	return NewStridedPoolLayer([]int{length, channels}, []int{kernel, 1}, []int{stride, 1}, []int{pad, 0}, mode)
+}
+
+//batchView returns a tensor that shares the values of a batch but has a batch of the given size
+func batchView(batch *tensor.Tensor, size []int) *tensor.Tensor {
+	viewSize := make([]int, len(size), len(size)+1)
+	copy(viewSize, size)
+
+	return &tensor.Tensor{Size: append(viewSize, batch.Size[len(batch.Size)-1]), Values: batch.Values}
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer.
+func (l *Conv1DLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	if _, err := batchLen(l.inputSize, input); err != nil {
+		return nil, err
+	}
+
+	out, err := l.ConvolutionalLayer.ActivateBatch(batchView(input, l.ConvolutionalLayer.GetInputSize()))
+	if err != nil {
+		return nil, err
+	}
+
+	return batchView(out, l.outputSize), nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *Conv1DLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	if _, e := batchLen(l.outputSize, err); e != nil {
+		return nil, e
+	}
+
+	prop, e := l.ConvolutionalLayer.BackPropagateBatch(batchView(err, l.ConvolutionalLayer.GetOutputSize()))
+	if e != nil {
+		return nil, e
+	}
+
+	return batchView(prop, l.inputSize), nil
+}
+
+//Activate32 is like Activate for a float32 input. See weight.Float32Layer.
+func (l *Conv1DLayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	if !input.HasSize(l.inputSize) {
+		return nil, fmt.Errorf("Layer has input size %d but input is size %d", l.inputSize, input.Size)
+	}
+
+	out, err := l.ConvolutionalLayer.Activate32(&tensor.Tensor32{Size: l.ConvolutionalLayer.GetInputSize(), Values: input.Values})
+	if err != nil {
+		return nil, err
+	}
+
+	return &tensor.Tensor32{Size: l.outputSize, Values: out.Values}, nil
+}
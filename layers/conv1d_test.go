@@ -0,0 +1,64 @@
+package layers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestConv1DActivation(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewConv1DLayer(5, 2, 1, 1, 2, 1)
+
+	assert.Equal([]int{5, 2}, layer.GetInputSize())
+	assert.Equal([]int{3, 1}, layer.GetOutputSize())
+
+	layer.weights.Values = []float64{
+		1, 0, -1,
+		0, 2, 0,
+	}
+	layer.bias.Values = []float64{0.5}
+
+	data := &tensor.Tensor{Size: []int{5, 2}, Values: []float64{
+		1, 2, 3, 4, 5,
+		10, 20, 30, 40, 50,
+	}}
+
+	out, err := layer.Activate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal([]int{3, 1}, out.Size)
+	//Windows start at -1, 1 and 3. The padding is 0.
+	assert.InDeltaSlice([]float64{(0 - 2) + 20 + 0.5, (2 - 4) + 60 + 0.5, (4 - 0) + 100 + 0.5}, out.Values, 1e-12)
+}
+
+func TestConv1DGradients(t *testing.T) {
+	layer := NewDilatedConv1DLayer(9, 3, 4, 1, 1, 2, 2)
+	checkGradients(t, layer, randomTensor(9, 3))
+
+	slave := layer.CreateSlave().(*Conv1DLayer)
+	checkGradients(t, slave, randomTensor(9, 3))
+}
+
+func TestPool1D(t *testing.T) {
+	layer := NewPool1DLayer(5, 2, 3, 2, 1, MaxPool)
+
+	assert.Equal(t, []int{3, 2}, layer.GetOutputSize())
+
+	data := &tensor.Tensor{Size: []int{5, 2}, Values: []float64{
+		1, 5, 2, 4, 3,
+		-1, -2, -3, -4, -5,
+	}}
+
+	out, err := layer.Activate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.InDeltaSlice(t, []float64{5, 5, 4, -1, -2, -4}, out.Values, 1e-12)
+}
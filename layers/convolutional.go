@@ -7,12 +7,14 @@ import (
 	"math/rand"
 
 	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas32"
 	"github.com/gonum/blas/blas64"
 
 	"github.com/gerardabello/weight"
 	"github.com/gerardabello/weight/tensor"
 )
 
+//ConvolutionalLayer convolves a 3D input [w,h,c] with nKernels kernels. The kernels can be dilated (spaced values), and the channels can be split in groups that are convolved independently.
 type ConvolutionalLayer struct {
 	BaseLayer
 
@@ -20,8 +22,18 @@ type ConvolutionalLayer struct {
 	padX, padY                          int
 	strideX, strideY                    int
 	strideJumpsX, strideJumpsY          int
+	dilationX, dilationY                int
+	groups                              int
 
-	im2colTmp *tensor.Tensor
+	im2colTmp  *tensor.Tensor
+	colGradTmp *tensor.Tensor
+
+	//Buffers of ActivateBatch and BackPropagateBatch. The columns of all the examples of a group are stored side by side so each group is a single matrix multiplication.
+	batchCols, batchColGrad, batchProduct []float64
+	batchColTmp                           []float64
+
+	//Columns of the input of Activate32
+	im2col32 []float32
 }
 
 //NewConvolutionalLayer does what it says
@@ -40,10 +52,29 @@ type ConvolutionalLayer struct {
 // Instead of passing the kernel size, you specify the size as a padding of a kernel of one pixel. This is to avoid the possibility of a kernel size that has no center pixel. It's also easier to create a layer that does not change the input size if kernelPad = pad
 //
 // The filter cannot be bigger than the image so: kernelPadX*2+1 <= inputWidth && kernelPadY*2+1 <= inputHeight
-// The stride must divide the image in equal integer parts:  ((inputWidth + padX*2) - (1+kernelPadX*2)) % (strideX+1) == 0 && ((inputHeight + padY*2) - (1+kernelPadY*2)) % (strideY+1) == 0
+// The output size is ((inputWidth + padX*2) - (1+kernelPadX*2)) / strideX + 1, rounded down, and the same for the height. If the stride does not divide the image in equal parts, the last values are ignored.
 // If you want the output area to be the same as the input: kernelPadX == padX && kernelPadY == padY
 // More padding than spatial extend makes no sense so: padX <= kernelPadX && padY < kernelPadY
 func NewConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernelPadX, kernelPadY, strideX, strideY, padX, padY int) *ConvolutionalLayer {
+	return newConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernelPadX, kernelPadY, strideX, strideY, padX, padY, 1, 1, 1)
+}
+
+//NewDilatedConvolutionalLayer creates a ConvolutionalLayer whose kernel values are placed every dilationX (dilationY) input values, so a kernel covers (kernelPadX*2)*dilationX+1 values with the same number of parameters. The padding can be up to kernelPadX*dilationX; use that value to keep the input size.
+func NewDilatedConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernelPadX, kernelPadY, strideX, strideY, padX, padY, dilationX, dilationY int) *ConvolutionalLayer {
+	return newConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernelPadX, kernelPadY, strideX, strideY, padX, padY, dilationX, dilationY, 1)
+}
+
+//NewGroupedConvolutionalLayer creates a ConvolutionalLayer that splits the input channels and the kernels in groups. Each group of kernels only sees its group of inputDepth/groups channels, so the layer has groups times less parameters and operations. Both inputDepth and nKernels must be divisible by groups.
+func NewGroupedConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernelPadX, kernelPadY, strideX, strideY, padX, padY, groups int) *ConvolutionalLayer {
+	return newConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernelPadX, kernelPadY, strideX, strideY, padX, padY, 1, 1, groups)
+}
+
+//NewDepthwiseConvolutionalLayer creates a ConvolutionalLayer that convolves each input channel independently with depthMultiplier kernels (a grouped convolution with one group per channel). The output has inputDepth*depthMultiplier channels. Followed by a 1x1 convolution, it forms a depthwise-separable convolution (see NewDepthwiseSeparableBlock).
+func NewDepthwiseConvolutionalLayer(inputWidth, inputHeight, inputDepth, depthMultiplier, kernelPadX, kernelPadY, strideX, strideY, padX, padY int) *ConvolutionalLayer {
+	return newConvolutionalLayer(inputWidth, inputHeight, inputDepth, inputDepth*depthMultiplier, kernelPadX, kernelPadY, strideX, strideY, padX, padY, 1, 1, inputDepth)
+}
+
+func newConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernelPadX, kernelPadY, strideX, strideY, padX, padY, dilationX, dilationY, groups int) *ConvolutionalLayer {
 	if inputHeight <= 0 || inputWidth <= 0 || inputDepth <= 0 {
 		panic("Input sizes must be bigger than 0")
 	}
@@ -60,26 +91,31 @@ func NewConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernel
 		panic("Strides must be bigger than 0")
 	}
 
+	if dilationX <= 0 || dilationY <= 0 {
+		panic("Dilations must be bigger than 0")
+	}
+
+	if groups <= 0 || inputDepth%groups != 0 || nKernels%groups != 0 {
+		panic(fmt.Sprintf("Input depth (%d) and number of kernels (%d) must be divisible by the number of groups (%d)", inputDepth, nKernels, groups))
+	}
+
 	kernelWidth := kernelPadX*2 + 1
 	kernelHeight := kernelPadY*2 + 1
 
-	if !(kernelWidth <= inputWidth && kernelHeight <= inputHeight) {
+	//Area covered by the kernel taking the dilation into account
+	spanPadX := kernelPadX * dilationX
+	spanPadY := kernelPadY * dilationY
+
+	if !(spanPadX*2+1 <= inputWidth && spanPadY*2+1 <= inputHeight) {
 		panic("Kernel cannot be bigger than image")
 	}
 
-	if padX > kernelPadX || padY > kernelPadY {
+	if padX > spanPadX || padY > spanPadY {
 		panic("A padding bigger than the kernel padding makes no sense")
 	}
 
-	if ((inputWidth+(padX-kernelPadX)*2)+strideX-1)%strideX != 0 {
-		panic(fmt.Sprintf("StrideX of %d does not fit", strideX))
-	}
-	if ((inputHeight+(padY-kernelPadY)*2)+strideY-1)%strideY != 0 {
-		panic(fmt.Sprintf("StrideY of %d does not fit", strideY))
-	}
-
-	strideJumpsX := ((inputWidth + (padX-kernelPadX)*2) + strideX - 1) / strideX
-	strideJumpsY := ((inputHeight + (padY-kernelPadY)*2) + strideY - 1) / strideY
+	strideJumpsX := (inputWidth+(padX-spanPadX)*2-1)/strideX + 1
+	strideJumpsY := (inputHeight+(padY-spanPadY)*2-1)/strideY + 1
 
 	l := &ConvolutionalLayer{}
 
@@ -95,14 +131,18 @@ func NewConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernel
 	l.strideJumpsX = strideJumpsX
 	l.strideJumpsY = strideJumpsY
 
-	//initialize bias and kernel
+	l.dilationX = dilationX
+	l.dilationY = dilationY
+	l.groups = groups
+
+	//initialize bias and kernel. Each kernel only has the channels of its group.
 	l.bias = tensor.NewTensor(nKernels)
 	l.biasGrad = tensor.NewTensor(l.bias.Size...)
 
-	l.weights = tensor.NewTensor(kernelWidth, kernelHeight, inputDepth, nKernels)
+	l.weights = tensor.NewTensor(kernelWidth, kernelHeight, inputDepth/groups, nKernels)
 	l.weightsGrad = tensor.NewTensor(l.weights.Size...)
 
-	stdev := math.Sqrt(2.0 / float64(kernelHeight*kernelWidth*inputDepth))
+	stdev := math.Sqrt(2.0 / float64(kernelHeight*kernelWidth*inputDepth/groups))
 	for i := range l.weights.Values {
 		//Initialize weights with uniform random from -variance to variance
 		l.weights.Values[i] = rand.NormFloat64() * stdev
@@ -126,7 +166,7 @@ func (l *ConvolutionalLayer) CreateSlave() weight.Layer {
 	kernelPadX := (l.weights.Size[0] - 1) / 2
 	kernelPadY := (l.weights.Size[1] - 1) / 2
 
-	nl := NewConvolutionalLayer(l.inputWidth, l.inputHeight, l.inputDepth, l.GetOutputSize()[2], kernelPadX, kernelPadY, l.strideX, l.strideY, l.padX, l.padY)
+	nl := newConvolutionalLayer(l.inputWidth, l.inputHeight, l.inputDepth, l.GetOutputSize()[2], kernelPadX, kernelPadY, l.strideX, l.strideY, l.padX, l.padY, l.dilationX, l.dilationY, l.groups)
 
 	nl.id = l.ID()
 
@@ -136,11 +176,73 @@ func (l *ConvolutionalLayer) CreateSlave() weight.Layer {
 	return nl
 }
 
+type convConfig struct {
+	InputWidth, InputHeight, InputDepth int
+	NKernels                            int
+	KernelPadX, KernelPadY              int
+	StrideX, StrideY                    int
+	PadX, PadY                          int
+	DilationX, DilationY                int `json:",omitempty"`
+	Groups                              int `json:",omitempty"`
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *ConvolutionalLayer) Spec() (*LayerSpec, error) {
+	c := convConfig{
+		InputWidth:  l.inputWidth,
+		InputHeight: l.inputHeight,
+		InputDepth:  l.inputDepth,
+		NKernels:    l.GetOutputSize()[2],
+		KernelPadX:  (l.weights.Size[0] - 1) / 2,
+		KernelPadY:  (l.weights.Size[1] - 1) / 2,
+		StrideX:     l.strideX,
+		StrideY:     l.strideY,
+		PadX:        l.padX,
+		PadY:        l.padY,
+		DilationX:   l.dilationX,
+		DilationY:   l.dilationY,
+		Groups:      l.groups,
+	}
+	return NewLayerSpec("Conv", l.ID(), c, l.weights, l.bias)
+}
+
+func loadConvolutionalLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := convConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	//Files saved before dilation and groups were added do not have them
+	if c.DilationX == 0 {
+		c.DilationX = 1
+	}
+	if c.DilationY == 0 {
+		c.DilationY = 1
+	}
+	if c.Groups == 0 {
+		c.Groups = 1
+	}
+
+	l := newConvolutionalLayer(c.InputWidth, c.InputHeight, c.InputDepth, c.NKernels, c.KernelPadX, c.KernelPadY, c.StrideX, c.StrideY, c.PadX, c.PadY, c.DilationX, c.DilationY, c.Groups)
+	l.id = spec.ID
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+//MACs returns the number of multiply-adds needed to activate one example. See MACLayer.
+func (l *ConvolutionalLayer) MACs() int {
+	out := l.GetOutputSize()
+	//Each output value uses all the weights of its kernel
+	return tensor.SizeLength(out) * len(l.weights.Values) / out[2]
+}
+
 //Activate takes and input tensor and computes an output tensor where each value is the sum of the convolutions of the different input depths using different kernels.
 func (l *ConvolutionalLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	err := l.BaseLayer.Activate(input)
 	if err != nil {
+		l.mutex.Unlock()
 		return nil, err
 	}
 
@@ -150,98 +252,114 @@ func (l *ConvolutionalLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, err
 	return &l.output, nil
 }
 
+//BackPropagate computes the gradients with matrix multiplications using the columns of the last activation, and accumulates the propagated columns in the input with col2im
 func (l *ConvolutionalLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 
 	//We cannot back propagate a layer that has not been activated first
-	if l.lastInput == nil {
+	if l.lastInput == nil || l.im2colTmp == nil {
+		l.mutex.Unlock()
 		return nil, fmt.Errorf("weight.Layer cannot propagate error because it has not been activated or it has not been configured to retain inputs")
 	}
 
 	if len(err.Size) != len(l.GetOutputSize()) {
+		l.mutex.Unlock()
 		return nil, errors.New("Error gradient has wrong number of dimensions")
 	}
 	for i := 0; i < len(err.Size); i++ {
 		if err.Size[i] != l.GetOutputSize()[i] {
+			l.mutex.Unlock()
 			return nil, errors.New("Error gradient has wrong size")
 		}
 	}
 
 	e := l.BaseLayer.BackPropagate(err)
 	if e != nil {
+		l.mutex.Unlock()
 		return nil, e
 	}
 
-	lastInputs := l.lastInput.Values
+	kw, kh := l.weights.Size[0], l.weights.Size[1]
 
-	kernelPadX := (l.weights.Size[0] - 1) / 2
-	kernelPadY := (l.weights.Size[1] - 1) / 2
+	if l.colGradTmp == nil {
+		l.colGradTmp = tensor.NewTensor(l.im2colTmp.Size...)
+	}
 
-	startX := kernelPadX - l.padX
-	startY := kernelPadY - l.padY
-
-	if startX < 0 || startY < 0 {
-		return nil, fmt.Errorf("Padding is bigger than kernel padding")
-	}
-
-	//Store sizes
-	ws1 := l.weights.Size[1]
-	ws0 := l.weights.Size[0]
-	ws2 := l.weights.Size[2]
-
-	is1 := l.propagation.Size[1]
-	is0 := l.propagation.Size[0]
-
-	//////
-	for d := 0; d < l.weights.Size[3]; d++ {
-		var f = l.weights.Slice(d)
-		var fg = l.weightsGrad.Slice(d)
-		x := -kernelPadX
-		y := -kernelPadY
-		for ay := 0; ay < err.Size[1]; ay++ {
-			y += l.strideY
-			x = -kernelPadX
-			for ax := 0; ax < err.Size[0]; ax++ {
-				x += l.strideX
-				var grad = err.GetVal(ax, ay, d)
-
-				for fd := 0; fd < ws2; fd++ {
-					for fy := 0; fy < ws1; fy++ {
-						oy := y + fy
-						if oy >= 0 && oy < is1 {
-							for fx := 0; fx < ws0; fx++ {
-								ox := x + fx
-								if ox >= 0 && ox < is0 {
-									//TODO optimize this
-									wp := fd*(ws0*ws1) + fy*(ws0) + fx
-									pp := fd*(is0*is1) + oy*(is0) + ox
-
-									l.propagation.Values[pp] += grad * f.Values[wp]
-									fg.Values[wp] += grad * lastInputs[pp]
-
-								}
-							}
-						}
-					}
-				}
-				l.biasGrad.Values[d] += grad
-			}
-		}
+	positions := l.strideJumpsX * l.strideJumpsY
+	inDepth := l.inputDepth / l.groups
+
+	for g := 0; g < l.groups; g++ {
+		matKer, matCol, matOut := l.groupMatrices(g, l.weights, err)
+
+		matKerGrad := matKer
+		matKerGrad.Data = l.weightsGrad.Values[len(matKer.Data)*g : len(matKer.Data)*(g+1)]
+
+		matColGrad := matCol
+		matColGrad.Data = l.colGradTmp.Values[len(matCol.Data)*g : len(matCol.Data)*(g+1)]
+
+		//Gradient of the kernels: error x columns^T
+		blas64.Gemm(blas.NoTrans, blas.Trans, 1, matOut, matCol, 1, matKerGrad)
+
+		//Gradient of the columns: kernels^T x error. Then accumulate each column in its input window.
+		blas64.Gemm(blas.Trans, blas.NoTrans, 1, matKer, matOut, 0, matColGrad)
+
+		prop := l.propagation.Values[g*inDepth*l.inputWidth*l.inputHeight : (g+1)*inDepth*l.inputWidth*l.inputHeight]
+		col2imDilated(matColGrad.Data, inDepth, l.inputHeight, l.inputWidth, kh, kw, l.padY, l.padX, l.strideY, l.strideX, l.dilationY, l.dilationX, prop)
+	}
+
+	for i, v := range err.Values {
+		l.biasGrad.Values[i/positions] += v
 	}
 
 	l.mutex.Unlock()
 	return &l.propagation, nil
 }
 
+//groupMatrices returns the kernels, columns and output of group g as blas matrices
+func (l *ConvolutionalLayer) groupMatrices(g int, ker, out *tensor.Tensor) (matKer, matCol, matOut blas64.General) {
+	nKernels := ker.Size[3] / l.groups
+	ckk := ker.Size[0] * ker.Size[1] * ker.Size[2]
+	positions := l.im2colTmp.Size[0]
+	rows := l.im2colTmp.Size[1] / l.groups
+
+	matKer = blas64.General{
+		Rows:   nKernels,
+		Cols:   ckk,
+		Stride: ckk,
+		Data:   ker.Values[g*nKernels*ckk : (g+1)*nKernels*ckk],
+	}
+
+	matCol = blas64.General{
+		Rows:   rows,
+		Cols:   positions,
+		Stride: positions,
+		Data:   l.im2colTmp.Values[g*rows*positions : (g+1)*rows*positions],
+	}
+
+	matOut = blas64.General{
+		Rows:   nKernels,
+		Cols:   positions,
+		Stride: positions,
+		Data:   out.Values[g*nKernels*positions : (g+1)*nKernels*positions],
+	}
+
+	return
+}
+
 func (l *ConvolutionalLayer) ConvIm2Col(data, ker, out *tensor.Tensor, kernelSizeX, kernelSizeY, padX, padY, strideX, strideY int) {
 
 	if l.im2colTmp == nil {
-		//If its the first time, initialize the temp tensor to store the volume/image as columns
-		l.im2colTmp = tensor.NewTensor(im2colSize(data.Size[0], data.Size[1], data.Size[2], kernelSizeX, kernelSizeY, padX, padY, strideX, strideY))
+		//If its the first time, initialize the temp tensor to store the volume/image as columns. The columns of each group are stored one after the other.
+		l.im2colTmp = tensor.NewTensor(im2colSizeDilated(data.Size[0], data.Size[1], data.Size[2], kernelSizeX, kernelSizeY, padX, padY, strideX, strideY, l.dilationX, l.dilationY))
 	}
 
-	//Transform input volume/image as a matrix
-	im2col(data.Values, l.im2colTmp.Values, data.Size[0], data.Size[1], data.Size[2], kernelSizeX, kernelSizeY, padX, padY, strideX, strideY)
+	//Transform input volume/image as a matrix, one group at a time
+	inDepth := data.Size[2] / l.groups
+	groupSize := inDepth * data.Size[0] * data.Size[1]
+	colSize := len(l.im2colTmp.Values) / l.groups
+	for g := 0; g < l.groups; g++ {
+		im2colDilated(data.Values[g*groupSize:(g+1)*groupSize], l.im2colTmp.Values[g*colSize:(g+1)*colSize], data.Size[0], data.Size[1], inDepth, kernelSizeX, kernelSizeY, padX, padY, strideX, strideY, l.dilationX, l.dilationY)
+	}
 
 	//Add bias to output
 	n := out.GetNumberOfValues()
@@ -250,48 +368,211 @@ func (l *ConvolutionalLayer) ConvIm2Col(data, ker, out *tensor.Tensor, kernelSiz
 		out.Values[i] = l.bias.Values[i/dStride]
 	}
 
-	//Create blas matrix with underlying tensor values
-	matImg := blas64.General{
-		Rows:   l.im2colTmp.Size[1],
-		Cols:   l.im2colTmp.Size[0],
-		Stride: l.im2colTmp.Size[0],
-		Data:   l.im2colTmp.Values,
+	for g := 0; g < l.groups; g++ {
+		matKer, matImg, matOut := l.groupMatrices(g, ker, out)
+
+		//Calculate matrix multiply. We will add to matOut that already has the bias
+		blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, matKer, matImg, 1, matOut)
 	}
+}
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *ConvolutionalLayer) SupportsBatch() bool {
+	return true
+}
+
+//batchMatrices returns the kernels, the columns of the batch and the product of both of group g as blas matrices. The columns of example b are at b*positions in each row.
+func (l *ConvolutionalLayer) batchMatrices(g int, ker, cols, product []float64) (matKer, matCol, matProduct blas64.General) {
+	nKernels := l.weights.Size[3] / l.groups
+	ckk := l.weights.Size[0] * l.weights.Size[1] * l.weights.Size[2]
+	width := len(product) / l.weights.Size[3]
+
+	matKer = blas64.General{Rows: nKernels, Cols: ckk, Stride: ckk, Data: ker[g*nKernels*ckk : (g+1)*nKernels*ckk]}
+	matCol = blas64.General{Rows: ckk, Cols: width, Stride: width, Data: cols[g*ckk*width : (g+1)*ckk*width]}
+	matProduct = blas64.General{Rows: nKernels, Cols: width, Stride: width, Data: product[g*nKernels*width : (g+1)*nKernels*width]}
 
-	matKer := blas64.General{
-		Rows:   ker.Size[3],
-		Cols:   ker.Size[0] * ker.Size[1] * ker.Size[2],
-		Stride: ker.Size[0] * ker.Size[1] * ker.Size[2],
-		Data:   ker.Values,
+	return
+}
+
+//ActivateBatch is like Activate for a batch of examples. The columns of all the examples are multiplied by the kernels at once, which is much faster than one example at a time. See weight.BatchLayer.
+func (l *ConvolutionalLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
 	}
 
-	matOut := blas64.General{
-		Rows:   ker.Size[3],
-		Cols:   l.im2colTmp.Size[0],
-		Stride: l.im2colTmp.Size[0],
-		Data:   out.Values,
+	kw, kh := l.weights.Size[0], l.weights.Size[1]
+	positions, rows := im2colSizeDilated(l.inputWidth, l.inputHeight, l.inputDepth, kw, kh, l.padX, l.padY, l.strideX, l.strideY, l.dilationX, l.dilationY)
+	nKernels := l.weights.Size[3]
+
+	if len(l.batchCols) != rows*positions*n {
+		l.batchCols = make([]float64, rows*positions*n)
+		l.batchColGrad = make([]float64, rows*positions*n)
+		l.batchProduct = make([]float64, nKernels*positions*n)
+		l.batchColTmp = make([]float64, rows*positions)
 	}
 
-	//Calculate matrix multiply. We will add to matOut that already has the bias
-	blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, matKer, matImg, 1, matOut)
+	inSize := l.propagation.GetNumberOfValues()
+	inDepth := l.inputDepth / l.groups
+	groupSize := inDepth * l.inputWidth * l.inputHeight
+	colSize := rows * positions / l.groups
+
+	//Build the columns of each example and place them in the columns of the batch
+	for b := 0; b < n; b++ {
+		example := input.Values[b*inSize : (b+1)*inSize]
+		for g := 0; g < l.groups; g++ {
+			im2colDilated(example[g*groupSize:(g+1)*groupSize], l.batchColTmp[g*colSize:(g+1)*colSize], l.inputWidth, l.inputHeight, inDepth, kw, kh, l.padX, l.padY, l.strideX, l.strideY, l.dilationX, l.dilationY)
+		}
+
+		for r := 0; r < rows; r++ {
+			copy(l.batchCols[(r*n+b)*positions:(r*n+b+1)*positions], l.batchColTmp[r*positions:(r+1)*positions])
+		}
+	}
+
+	for g := 0; g < l.groups; g++ {
+		matKer, matCol, matProduct := l.batchMatrices(g, l.weights.Values, l.batchCols, l.batchProduct)
+		blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, matKer, matCol, 0, matProduct)
+	}
+
+	//The product has a row for each kernel, so move each example to its output and add the bias
+	outSize := l.output.GetNumberOfValues()
+	for b := 0; b < n; b++ {
+		for k := 0; k < nKernels; k++ {
+			out := l.batchOutput.Values[b*outSize+k*positions : b*outSize+(k+1)*positions]
+			product := l.batchProduct[(k*n+b)*positions : (k*n+b+1)*positions]
+			for i, v := range product {
+				out[i] = v + l.bias.Values[k]
+			}
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *ConvolutionalLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	kw, kh := l.weights.Size[0], l.weights.Size[1]
+	positions, rows := im2colSizeDilated(l.inputWidth, l.inputHeight, l.inputDepth, kw, kh, l.padX, l.padY, l.strideX, l.strideY, l.dilationX, l.dilationY)
+	nKernels := l.weights.Size[3]
+
+	//Arrange the error like the product of ActivateBatch, and compute the gradient of the bias
+	outSize := l.output.GetNumberOfValues()
+	for b := 0; b < n; b++ {
+		for k := 0; k < nKernels; k++ {
+			errs := err.Values[b*outSize+k*positions : b*outSize+(k+1)*positions]
+			copy(l.batchProduct[(k*n+b)*positions:(k*n+b+1)*positions], errs)
+			for _, v := range errs {
+				l.biasGrad.Values[k] += v
+			}
+		}
+	}
+
+	for g := 0; g < l.groups; g++ {
+		matKer, matCol, matErr := l.batchMatrices(g, l.weights.Values, l.batchCols, l.batchProduct)
+		matKerGrad, matColGrad, _ := l.batchMatrices(g, l.weightsGrad.Values, l.batchColGrad, l.batchProduct)
+
+		blas64.Gemm(blas.NoTrans, blas.Trans, 1, matErr, matCol, 1, matKerGrad)
+		blas64.Gemm(blas.Trans, blas.NoTrans, 1, matKer, matErr, 0, matColGrad)
+	}
+
+	//Take the column gradients of each example and accumulate them in its input windows
+	inSize := l.propagation.GetNumberOfValues()
+	inDepth := l.inputDepth / l.groups
+	groupSize := inDepth * l.inputWidth * l.inputHeight
+	colSize := rows * positions / l.groups
+
+	for b := 0; b < n; b++ {
+		for r := 0; r < rows; r++ {
+			copy(l.batchColTmp[r*positions:(r+1)*positions], l.batchColGrad[(r*n+b)*positions:(r*n+b+1)*positions])
+		}
+
+		prop := l.batchPropagation.Values[b*inSize : (b+1)*inSize]
+		for g := 0; g < l.groups; g++ {
+			col2imDilated(l.batchColTmp[g*colSize:(g+1)*colSize], inDepth, l.inputHeight, l.inputWidth, kh, kw, l.padY, l.padX, l.strideY, l.strideX, l.dilationY, l.dilationX, prop[g*groupSize:(g+1)*groupSize])
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
+
+//Activate32 is like Activate for a float32 input, computed with blas32. See weight.Float32Layer.
+func (l *ConvolutionalLayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	kw, kh, ckk, nKernels := l.weights.Size[0], l.weights.Size[1], l.weights.Size[0]*l.weights.Size[1]*l.weights.Size[2], l.weights.Size[3]
+	positions, rows := im2colSizeDilated(l.inputWidth, l.inputHeight, l.inputDepth, kw, kh, l.padX, l.padY, l.strideX, l.strideY, l.dilationX, l.dilationY)
+
+	if len(l.im2col32) != rows*positions {
+		l.im2col32 = make([]float32, rows*positions)
+	}
+
+	inDepth := l.inputDepth / l.groups
+	groupSize := inDepth * l.inputWidth * l.inputHeight
+	nk := nKernels / l.groups
+
+	//Start with the bias
+	for k := 0; k < nKernels; k++ {
+		out := l.output32.Values[k*positions : (k+1)*positions]
+		for i := range out {
+			out[i] = l.bias32.Values[k]
+		}
+	}
+
+	for g := 0; g < l.groups; g++ {
+		cols := l.im2col32[g*ckk*positions : (g+1)*ckk*positions]
+		im2colDilated32(input.Values[g*groupSize:(g+1)*groupSize], cols, l.inputWidth, l.inputHeight, inDepth, kw, kh, l.padX, l.padY, l.strideX, l.strideY, l.dilationX, l.dilationY)
+
+		matKer := blas32.General{Rows: nk, Cols: ckk, Stride: ckk, Data: l.weights32.Values[g*nk*ckk : (g+1)*nk*ckk]}
+		matCol := blas32.General{Rows: ckk, Cols: positions, Stride: positions, Data: cols}
+		matOut := blas32.General{Rows: nk, Cols: positions, Stride: positions, Data: l.output32.Values[g*nk*positions : (g+1)*nk*positions]}
+
+		blas32.Gemm(blas.NoTrans, blas.NoTrans, 1, matKer, matCol, 1, matOut)
+	}
+
+	l.mutex.Unlock()
+	return &l.output32, nil
 }
 
 func im2colSize(width int, height int, channels int, kernel_w int, kernel_h int, pad_w int, pad_h int, stride_w int, stride_h int) (int, int) {
-	var height_col int = (height+2*pad_h-kernel_h)/stride_h + 1
-	var width_col int = (width+2*pad_w-kernel_w)/stride_w + 1
+	return im2colSizeDilated(width, height, channels, kernel_w, kernel_h, pad_w, pad_h, stride_w, stride_h, 1, 1)
+}
+
+func im2colSizeDilated(width int, height int, channels int, kernel_w int, kernel_h int, pad_w int, pad_h int, stride_w int, stride_h int, dilation_w int, dilation_h int) (int, int) {
+	var height_col int = (height+2*pad_h-(dilation_h*(kernel_h-1)+1))/stride_h + 1
+	var width_col int = (width+2*pad_w-(dilation_w*(kernel_w-1)+1))/stride_w + 1
 	var channels_col int = channels * kernel_h * kernel_w
 
 	return width_col * height_col, channels_col
 }
 
 func im2col(im []float64, col []float64, width int, height int, channels int, kernel_w int, kernel_h int, pad_w int, pad_h int, stride_w int, stride_h int) {
-	var height_col int = (height+2*pad_h-kernel_h)/stride_h + 1
-	var width_col int = (width+2*pad_w-kernel_w)/stride_w + 1
+	im2colDilated(im, col, width, height, channels, kernel_w, kernel_h, pad_w, pad_h, stride_w, stride_h, 1, 1)
+}
+
+func im2colDilated(im []float64, col []float64, width int, height int, channels int, kernel_w int, kernel_h int, pad_w int, pad_h int, stride_w int, stride_h int, dilation_w int, dilation_h int) {
+	var height_col int = (height+2*pad_h-(dilation_h*(kernel_h-1)+1))/stride_h + 1
+	var width_col int = (width+2*pad_w-(dilation_w*(kernel_w-1)+1))/stride_w + 1
 	var channels_col int = channels * kernel_h * kernel_w
 
 	for c := 0; c < channels_col; c++ {
-		var w_offset int = c % kernel_w
-		var h_offset int = (c / kernel_w) % kernel_h
+		var w_offset int = (c % kernel_w) * dilation_w
+		var h_offset int = ((c / kernel_w) % kernel_h) * dilation_h
 		var c_im int = c / (kernel_h * kernel_w)
 		for h := 0; h < height_col; h++ {
 			for w := 0; w < width_col; w++ {
@@ -308,13 +589,41 @@ func im2col(im []float64, col []float64, width int, height int, channels int, ke
 	}
 }
 
+//im2colDilated32 is im2colDilated for float32 values
+func im2colDilated32(im []float32, col []float32, width int, height int, channels int, kernel_w int, kernel_h int, pad_w int, pad_h int, stride_w int, stride_h int, dilation_w int, dilation_h int) {
+	var height_col int = (height+2*pad_h-(dilation_h*(kernel_h-1)+1))/stride_h + 1
+	var width_col int = (width+2*pad_w-(dilation_w*(kernel_w-1)+1))/stride_w + 1
+	var channels_col int = channels * kernel_h * kernel_w
+
+	for c := 0; c < channels_col; c++ {
+		var w_offset int = (c % kernel_w) * dilation_w
+		var h_offset int = ((c / kernel_w) % kernel_h) * dilation_h
+		var c_im int = c / (kernel_h * kernel_w)
+		for h := 0; h < height_col; h++ {
+			for w := 0; w < width_col; w++ {
+				var h_pad int = h*stride_h - pad_h + h_offset
+				var w_pad int = w*stride_w - pad_w + w_offset
+				if h_pad >= 0 && h_pad < height && w_pad >= 0 && w_pad < width {
+					col[(c*height_col+h)*width_col+w] = im[(c_im*height+h_pad)*width+w_pad]
+				} else {
+					col[(c*height_col+h)*width_col+w] = 0
+				}
+			}
+		}
+	}
+}
+
 func col2im(col []float64, channels int, height int, width int, patch_h int, patch_w int, pad_h int, pad_w int, stride_h int, stride_w int, im []float64) {
-	var height_col int = (height+2*pad_h-patch_h)/stride_h + 1
-	var width_col int = (width+2*pad_w-patch_w)/stride_w + 1
+	col2imDilated(col, channels, height, width, patch_h, patch_w, pad_h, pad_w, stride_h, stride_w, 1, 1, im)
+}
+
+func col2imDilated(col []float64, channels int, height int, width int, patch_h int, patch_w int, pad_h int, pad_w int, stride_h int, stride_w int, dilation_h int, dilation_w int, im []float64) {
+	var height_col int = (height+2*pad_h-(dilation_h*(patch_h-1)+1))/stride_h + 1
+	var width_col int = (width+2*pad_w-(dilation_w*(patch_w-1)+1))/stride_w + 1
 	var channels_col int = channels * patch_h * patch_w
 	for c := 0; c < channels_col; c++ {
-		var w_offset int = c % patch_w
-		var h_offset int = (c / patch_w) % patch_h
+		var w_offset int = (c % patch_w) * dilation_w
+		var h_offset int = ((c / patch_w) % patch_h) * dilation_h
 		var c_im int = c / patch_h / patch_w
 		for h := 0; h < height_col; h++ {
 			for w := 0; w < width_col; w++ {
@@ -259,3 +259,59 @@ func TestIm2Col(t *testing.T) {
 	assert.InDeltaSlice([]float64{0.1, 0.2, 0.5, 0.6, 0.2, 0.3, 0.6, 0.7, 0.3, 0.4, 0.7, 0.8, 0.5, 0.6, 0.9, 1, 0.6, 0.7, 1, 1.1, 0.7, 0.8, 1.1, 1.2, 0.9, 1, 1.3, 1.4, 1, 1.1, 1.4, 1.5, 1.1, 1.2, 1.5, 1.6}, out, 0.001, "Unexpected value afeter im2col")
 
 }
+
+func TestConvolutionalGradients(t *testing.T) {
+	//Padding and stride 2 on an even input size
+	checkGradients(t, NewConvolutionalLayer(6, 4, 2, 3, 1, 1, 2, 2, 1, 1), randomTensor(6, 4, 2))
+
+	//Dilation
+	checkGradients(t, NewDilatedConvolutionalLayer(7, 6, 2, 2, 1, 1, 1, 1, 2, 1, 2, 2), randomTensor(7, 6, 2))
+
+	//Groups
+	checkGradients(t, NewGroupedConvolutionalLayer(5, 5, 4, 6, 1, 1, 1, 1, 1, 1, 2), randomTensor(5, 5, 4))
+
+	//Depthwise
+	checkGradients(t, NewDepthwiseConvolutionalLayer(5, 4, 3, 2, 1, 1, 2, 1, 1, 1), randomTensor(5, 4, 3))
+}
+
+func TestDilatedConvolutionalActivation(t *testing.T) {
+	layer := NewDilatedConvolutionalLayer(5, 5, 1, 1, 1, 1, 1, 1, 0, 0, 2, 2)
+
+	assert.Equal(t, []int{1, 1, 1}, layer.GetOutputSize())
+
+	layer.weights.Values = []float64{
+		1, 0, 1,
+		0, 1, 0,
+		0, 0, 1,
+	}
+
+	data := &tensor.Tensor{Size: []int{5, 5, 1}, Values: make([]float64, 25)}
+	for i := range data.Values {
+		data.Values[i] = float64(i)
+	}
+
+	//The kernel values are applied to the input at (0,0), (4,0), (2,2) and (4,4)
+	testConvolutionalActivation(t, layer, data, []float64{0 + 4 + 12 + 24})
+}
+
+func TestGroupedConvolutionalActivation(t *testing.T) {
+	layer := NewGroupedConvolutionalLayer(4, 4, 2, 2, 1, 1, 1, 1, 0, 0, 2)
+
+	assert.Equal(t, []int{3, 3, 1, 2}, layer.weights.Size)
+
+	//Each group should give the same result as a separate convolution of its channel
+	data := randomTensor(4, 4, 2)
+	out, err := layer.Activate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for g := 0; g < 2; g++ {
+		single := NewConvolutionalLayer(4, 4, 1, 1, 1, 1, 1, 1, 0, 0)
+		single.weights.Values = layer.weights.Values[g*9 : (g+1)*9]
+		single.bias.Values = layer.bias.Values[g : g+1]
+
+		channel := &tensor.Tensor{Size: []int{4, 4, 1}, Values: data.Values[g*16 : (g+1)*16]}
+		testConvolutionalActivation(t, single, channel, out.Values[g*4:(g+1)*4])
+	}
+}
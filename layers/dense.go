@@ -4,6 +4,10 @@ import (
 	"math"
 	"math/rand"
 
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas32"
+	"github.com/gonum/blas/blas64"
+
 	"github.com/gerardabello/weight"
 	"github.com/gerardabello/weight/tensor"
 )
@@ -58,6 +62,29 @@ func (l *DenseLayer) CreateSlave() weight.Layer {
 	return nl
 }
 
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *DenseLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("Dense", l.ID(), sizeConfig{l.GetInputSize(), l.GetOutputSize()}, l.weights, l.bias)
+}
+
+func loadDenseLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := sizeConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewDenseLayer(c.InputSize, c.OutputSize)
+	l.id = spec.ID
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+//MACs returns the number of multiply-adds needed to activate one example. See MACLayer.
+func (l *DenseLayer) MACs() int {
+	return len(l.weights.Values)
+}
+
 func (l *DenseLayer) GetNumberOfNeurons() int {
 	return tensor.SizeLength(l.GetOutputSize())
 }
@@ -71,6 +98,7 @@ func (l *DenseLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	err := l.BaseLayer.Activate(input)
 	if err != nil {
+		l.mutex.Unlock()
 		return nil, err
 	}
 
@@ -98,6 +126,7 @@ func (l *DenseLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	e := l.BaseLayer.BackPropagate(err)
 	if e != nil {
+		l.mutex.Unlock()
 		return nil, e
 	}
 
@@ -129,3 +158,86 @@ func (l *DenseLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Unlock()
 	return &l.propagation, nil
 }
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *DenseLayer) SupportsBatch() bool {
+	return true
+}
+
+//matrices returns the batch values, the output (or error) of the batch and the weights as matrices with a row for each example (or neuron in the weights)
+func (l *DenseLayer) matrices(n int, in, out, w []float64) (blas64.General, blas64.General, blas64.General) {
+	ni := l.GetNumberOfInputs()
+	no := l.GetNumberOfNeurons()
+
+	return blas64.General{Rows: n, Cols: ni, Stride: ni, Data: in},
+		blas64.General{Rows: n, Cols: no, Stride: no, Data: out},
+		blas64.General{Rows: no, Cols: ni, Stride: ni, Data: w}
+}
+
+//ActivateBatch is like Activate for a batch of examples, computed with a single matrix multiplication. See weight.BatchLayer.
+func (l *DenseLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	in, out, w := l.matrices(n, input.Values, l.batchOutput.Values, l.weights.Values)
+
+	//Start with the bias and add the weighted sum of the inputs
+	no := l.GetNumberOfNeurons()
+	for b := 0; b < n; b++ {
+		copy(out.Data[b*no:(b+1)*no], l.bias.Values)
+	}
+
+	blas64.Gemm(blas.NoTrans, blas.Trans, 1, in, w, 1, out)
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *DenseLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	in, errs, w := l.matrices(n, l.lastBatch.Values, err.Values, l.weights.Values)
+	prop, _, wg := l.matrices(n, l.batchPropagation.Values, nil, l.weightsGrad.Values)
+
+	blas64.Gemm(blas.Trans, blas.NoTrans, 1, errs, in, 1, wg)
+	blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, errs, w, 0, prop)
+
+	no := l.GetNumberOfNeurons()
+	for i, v := range err.Values {
+		l.biasGrad.Values[i%no] += v
+	}
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
+
+//Activate32 is like Activate for a float32 input, computed with blas32. See weight.Float32Layer.
+func (l *DenseLayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	ni := l.GetNumberOfInputs()
+	no := l.GetNumberOfNeurons()
+
+	w := blas32.General{Rows: no, Cols: ni, Stride: ni, Data: l.weights32.Values}
+
+	copy(l.output32.Values, l.bias32.Values)
+	blas32.Gemv(blas.NoTrans, 1, w, blas32.Vector{Inc: 1, Data: input.Values}, 1, blas32.Vector{Inc: 1, Data: l.output32.Values})
+
+	l.mutex.Unlock()
+	return &l.output32, nil
+}
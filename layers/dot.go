@@ -0,0 +1,73 @@
+package layers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/gerardabello/weight"
+)
+
+//WriteDOT writes the graph of the network in the DOT language of Graphviz. Each node shows the layer type, ID, input and output sizes and number of parameters, and edges show how the outputs of the layers are connected. Render it with `dot -Tsvg net.dot > net.svg`.
+func (n *FFNet) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "digraph %q {\n", n.ID())
+	fmt.Fprintf(bw, "\tnode [shape=box, fontname=monospace];\n")
+
+	for _, port := range n.inputs {
+		fmt.Fprintf(bw, "\t%q [shape=ellipse, label=%q];\n", "input:"+port.name, port.name)
+	}
+
+	for _, node := range n.nodes {
+		label := fmt.Sprintf("%s\n%s\nin %v out %v\nparams %d", layerType(node.layer), node.ID(), node.layer.GetInputSize(), node.layer.GetOutputSize(), paramCount(node.layer))
+		if len(node.parents) > 1 {
+			label += "\nmerge " + node.merge.String()
+		}
+
+		fmt.Fprintf(bw, "\t%q [label=%q];\n", node.ID(), label)
+	}
+
+	for _, port := range n.outputs {
+		fmt.Fprintf(bw, "\t%q [shape=ellipse, label=%q];\n", "output:"+port.name, port.name)
+	}
+
+	for _, port := range n.inputs {
+		fmt.Fprintf(bw, "\t%q -> %q;\n", "input:"+port.name, port.node.ID())
+	}
+
+	for _, node := range n.nodes {
+		for _, parent := range node.parents {
+			fmt.Fprintf(bw, "\t%q -> %q [label=%q];\n", parent.ID(), node.ID(), fmt.Sprint(parent.layer.GetOutputSize()))
+		}
+	}
+
+	for _, port := range n.outputs {
+		fmt.Fprintf(bw, "\t%q -> %q;\n", port.node.ID(), "output:"+port.name)
+	}
+
+	fmt.Fprintf(bw, "}\n")
+
+	return bw.Flush()
+}
+
+//layerType returns the name of the type of the layer, like "DenseLayer"
+func layerType(layer weight.Layer) string {
+	t := reflect.TypeOf(layer)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+//paramCount returns the number of trainable parameters of the layer, or 0 if it does not learn
+func paramCount(layer weight.Layer) int {
+	bpLayer, ok := layer.(weight.BPLearnerLayer)
+	if !ok {
+		return 0
+	}
+
+	params, _ := bpLayer.GetParamGradPointers()
+	return len(params)
+}
@@ -0,0 +1,225 @@
+package layers
+
+import (
+	"math/rand"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//DropoutLayer sets each input to 0 with probability Rate during training, and scales the rest by 1/(1-Rate) so the expected value does not change (inverted dropout). In inference mode it just copies the input. See weight.ModeLayer.
+type DropoutLayer struct {
+	BaseLayer
+
+	rate float64
+
+	//Number of consecutive values that are dropped together. 1 for normal dropout, the size of a channel for spatial dropout.
+	unit    int
+	spatial bool
+
+	training bool
+
+	//Value that multiplies each input in the last activation (0 or 1/(1-rate))
+	mask []float64
+
+	//Same for the last batch
+	batchMask []float64
+
+	//Each layer (and slave) has its own source so they do not compete for the global one. The trainer seeds it before each batch, see weight.RandLayer.
+	rng *rand.Rand
+}
+
+type dropoutConfig struct {
+	InputSize []int
+	Rate      float64
+	Spatial   bool
+}
+
+//NewDropoutLayer creates a new DropoutLayer that drops each value with probability rate
+func NewDropoutLayer(rate float64, size ...int) *DropoutLayer {
+	return newDropoutLayer(rate, false, size)
+}
+
+//NewSpatialDropoutLayer creates a DropoutLayer for 3D inputs [w,h,c] that drops whole channels instead of single values. Neighbouring values of a convolution output are strongly correlated, so dropping them independently does not regularize much.
+func NewSpatialDropoutLayer(rate float64, size ...int) *DropoutLayer {
+	if len(size) != 3 {
+		panic("Spatial dropout input should have 3 dimensions")
+	}
+	return newDropoutLayer(rate, true, size)
+}
+
+func newDropoutLayer(rate float64, spatial bool, size []int) *DropoutLayer {
+	if rate < 0 || rate >= 1 {
+		panic("Dropout rate should be in [0, 1)")
+	}
+
+	layer := &DropoutLayer{}
+	layer.BaseLayer.Init(size, size)
+	layer.id = "Dropout-" + layer.id
+
+	layer.rate = rate
+	layer.spatial = spatial
+	layer.unit = 1
+	if spatial {
+		layer.unit = size[0] * size[1]
+	}
+
+	layer.mask = make([]float64, tensor.SizeLength(size)/layer.unit)
+	layer.rng = rand.New(rand.NewSource(rand.Int63()))
+
+	return layer
+}
+
+//CreateSlave creates a slave of the DropoutLayer. The slave starts in inference mode.
+func (l *DropoutLayer) CreateSlave() weight.Layer {
+	nl := newDropoutLayer(l.rate, l.spatial, l.GetInputSize())
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *DropoutLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("Dropout", l.ID(), dropoutConfig{l.GetInputSize(), l.rate, l.spatial})
+}
+
+func loadDropoutLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := dropoutConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := newDropoutLayer(c.Rate, c.Spatial, c.InputSize)
+	l.id = spec.ID
+
+	return l, nil
+}
+
+//Seed restarts the random masks with the given seed, so the dropped values can be reproduced. See weight.RandLayer.
+func (l *DropoutLayer) Seed(seed int64) {
+	l.mutex.Lock()
+	l.rng.Seed(seed)
+	l.mutex.Unlock()
+}
+
+//SetTraining enables the dropout (true) or disables it (false)
+func (l *DropoutLayer) SetTraining(training bool) {
+	l.mutex.Lock()
+	l.training = training
+	l.mutex.Unlock()
+}
+
+func (l *DropoutLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	if !l.training {
+		copy(l.output.Values, input.Values)
+		l.mutex.Unlock()
+		return &l.output, nil
+	}
+
+	l.fillMask(l.mask)
+
+	for i, v := range input.Values {
+		l.output.Values[i] = v * l.mask[i/l.unit]
+	}
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+func (l *DropoutLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	if !l.training {
+		copy(l.propagation.Values, err.Values)
+		l.mutex.Unlock()
+		return &l.propagation, nil
+	}
+
+	for i, v := range err.Values {
+		l.propagation.Values[i] = v * l.mask[i/l.unit]
+	}
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+//fillMask sets each value of mask to 0 with probability rate and to 1/(1-rate) otherwise
+func (l *DropoutLayer) fillMask(mask []float64) {
+	scale := 1 / (1 - l.rate)
+	for i := range mask {
+		if l.rng.Float64() < l.rate {
+			mask[i] = 0
+		} else {
+			mask[i] = scale
+		}
+	}
+}
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *DropoutLayer) SupportsBatch() bool {
+	return true
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer. Each example gets its own mask.
+func (l *DropoutLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	if !l.training {
+		copy(l.batchOutput.Values, input.Values)
+		l.mutex.Unlock()
+		return &l.batchOutput, nil
+	}
+
+	if len(l.batchMask) != len(l.mask)*n {
+		l.batchMask = make([]float64, len(l.mask)*n)
+	}
+	l.fillMask(l.batchMask)
+
+	for i, v := range input.Values {
+		l.batchOutput.Values[i] = v * l.batchMask[i/l.unit]
+	}
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *DropoutLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	if !l.training {
+		copy(l.batchPropagation.Values, err.Values)
+		l.mutex.Unlock()
+		return &l.batchPropagation, nil
+	}
+
+	for i, v := range err.Values {
+		l.batchPropagation.Values[i] = v * l.batchMask[i/l.unit]
+	}
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
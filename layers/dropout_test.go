@@ -0,0 +1,148 @@
+package layers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestDropoutInference(t *testing.T) {
+	layer := NewDropoutLayer(0.5, 100)
+
+	input := tensor.NewTensor(100)
+	input.Zero(1)
+
+	out, err := layer.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, input.Values, out.Values, "Dropout should not change the input in inference mode")
+}
+
+func TestDropoutTraining(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewDropoutLayer(0.25, 10000)
+
+	net, err := NewSequentialNet(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	net.SetTraining(true)
+
+	input := tensor.NewTensor(10000)
+	input.Zero(1)
+
+	out, err := net.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dropped := 0
+	sum := 0.0
+	for _, v := range out.Values {
+		if v == 0 {
+			dropped++
+		} else {
+			assert.InDelta(1/0.75, v, 1e-12, "Kept values should be scaled")
+		}
+		sum += v
+	}
+
+	assert.InDelta(2500, dropped, 200, "About a quarter of the values should be dropped")
+	assert.InDelta(10000, sum, 300, "The expected value should not change")
+
+	grad := tensor.NewTensor(10000)
+	grad.Zero(1)
+
+	prop, err := net.BackPropagate(grad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(out.Values, prop.Values, "Gradient should be masked like the activation")
+}
+
+func TestSpatialDropout(t *testing.T) {
+	layer := NewSpatialDropoutLayer(0.5, 3, 3, 40)
+	layer.SetTraining(true)
+
+	input := tensor.NewTensor(3, 3, 40)
+	input.Zero(1)
+
+	out, err := layer.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for c := 0; c < 40; c++ {
+		channel := out.Values[c*9 : (c+1)*9]
+		for _, v := range channel {
+			if v != channel[0] {
+				t.Fatalf("Channel %d is not dropped as a whole: %v", c, channel)
+			}
+		}
+	}
+}
+
+func TestDropoutSeed(t *testing.T) {
+	input := tensor.NewTensor(1000)
+	input.Zero(1)
+
+	masks := [][]float64{}
+	for _, l := range []*DropoutLayer{NewDropoutLayer(0.5, 1000), NewDropoutLayer(0.5, 1000).CreateSlave().(*DropoutLayer)} {
+		l.SetTraining(true)
+		l.Seed(3)
+
+		out, err := l.Activate(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		masks = append(masks, append([]float64{}, out.Values...))
+	}
+
+	assert.Equal(t, masks[0], masks[1], "Layers with the same seed should drop the same values")
+}
+
+func TestDropoutBatch(t *testing.T) {
+	checkBatch(t, NewDropoutLayer(0.5, 4, 3), 5)
+
+	//With the same seed, a batch is dropped like the examples one by one
+	for _, layer := range []*DropoutLayer{NewDropoutLayer(0.5, 4, 3), NewSpatialDropoutLayer(0.5, 2, 2, 3)} {
+		layer.SetTraining(true)
+		ni := 12
+		input := randomTensor(append(append([]int{}, layer.GetInputSize()...), 4)...)
+
+		layer.Seed(5)
+		outs := []float64{}
+		for b := 0; b < 4; b++ {
+			out, err := layer.Activate(&tensor.Tensor{Size: layer.GetInputSize(), Values: input.Values[b*ni : (b+1)*ni]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			outs = append(outs, out.Values...)
+		}
+
+		layer.Seed(5)
+		out, err := layer.ActivateBatch(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, outs, out.Values)
+
+		prop, err := layer.BackPropagateBatch(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range prop.Values {
+			if out.Values[i] == 0 {
+				assert.Equal(t, 0.0, v, "Dropped values should not propagate")
+			} else {
+				assert.InDelta(t, 2*out.Values[i], v, 1e-12, "Kept values should be scaled")
+			}
+		}
+	}
+}
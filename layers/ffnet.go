@@ -3,6 +3,8 @@ package layers
 import (
 	"errors"
 	"fmt"
+	"math/rand"
+	"strings"
 
 	"github.com/gerardabello/weight"
 	"github.com/gerardabello/weight/debug"
@@ -20,99 +22,261 @@ type FFNode struct {
 	inputs  []chan *tensor.Tensor //come from parents
 	outputs []chan *tensor.Tensor //come from childs
 
-	input         *tensor.Tensor //tensor to store the sum of incoming inputs on Activate()
+	merge Merge //how the incoming inputs are combined
+
+	input         *tensor.Tensor //tensor to store the merge of incoming inputs on Activate()
 	gradientError *tensor.Tensor //tensor to store the sum of incoming gradient errors on BackPropagate()
+
+	lastInputs      []*tensor.Tensor //incoming inputs of the last Activate(), needed to split the gradient between the parents
+	parentGradients []*tensor.Tensor //tensors to store the gradient sent to each parent on BackPropagate()
+
+	batch bool //if the node is processing batches, see weight.BatchLayer
+
+	input32 *tensor.Tensor32 //tensor to store the merge of incoming inputs on Activate32()
 }
 
 func (n *FFNode) ID() string {
 	return n.layer.ID()
 }
 
-//Activate waits for the parent nodes to send its outputs, computes the sum of them and passes it to the underlying layer's Activate, then sends the result to all childs.
-func (n *FFNode) Activate() {
+//Activate waits for the parent nodes to send its outputs, merges them (see Merge) and passes the result to the underlying layer's Activate, then sends the result to all childs.
+//If any parent fails it sends nil. In that case, or if this node fails, the node reports the error to the net (only if it is the origin of the failure) and sends nil to all childs, so they do not wait forever.
+func (n *FFNode) Activate(errs chan<- error) {
 	np := len(n.inputs)
 	inputs := make([]*tensor.Tensor, np)
 
+	failed := false
 	for i := 0; i < np; i++ {
 		inputs[i] = <-n.inputs[i]
-		if !inputs[i].HasSize(n.layer.GetInputSize()) {
-			panic(fmt.Sprintf("One of inputs to node %s has not the correct size. Actual:%v expected:%v \n", n.ID(), inputs[i].Size, n.layer.GetInputSize()))
+		if inputs[i] == nil {
+			failed = true
+		}
+	}
+
+	var out *tensor.Tensor
+	if !failed {
+		var err error
+		out, err = n.activate(inputs)
+		if err != nil {
+			errs <- fmt.Errorf("Layer %s: %s", n.ID(), err.Error())
+			out = nil
 		}
 	}
 
-	//Create a tensor if it doesn't exist
-	if n.input == nil {
-		n.input = tensor.NewTensor(n.layer.GetInputSize()...)
+	//Send to all childs
+	for i := 0; i < len(n.outputs); i++ {
+		n.outputs[i] <- out
+	}
+}
+
+func (n *FFNode) activate(inputs []*tensor.Tensor) (out *tensor.Tensor, err error) {
+	//A panic inside a layer should not kill the whole program
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic during activation: %v", r)
+		}
+	}()
+
+	inputSize := n.withBatch(n.layer.GetInputSize(), inputs[0])
+
+	if n.merge.Mode == ConcatMerge {
+		sizes := make([][]int, len(inputs))
+		for i := range inputs {
+			sizes[i] = inputs[i].Size
+		}
+
+		size, err := n.merge.Size(sizes)
+		if err != nil {
+			return nil, err
+		}
+
+		if !sameSize(size, inputSize) {
+			return nil, fmt.Errorf("Concatenated inputs have not the correct size. Actual:%v expected:%v", size, inputSize)
+		}
 	} else {
-		n.input.Zero(0)
+		for i := range inputs {
+			if !inputs[i].HasSize(inputSize) {
+				return nil, fmt.Errorf("One of inputs has not the correct size. Actual:%v expected:%v", inputs[i].Size, inputSize)
+			}
+		}
 	}
 
-	err := n.input.Add(inputs...)
+	//Create a tensor if it doesn't exist or the size of the batch changed
+	if n.input == nil || !n.input.HasSize(inputSize) {
+		n.input = tensor.NewTensor(inputSize...)
+	}
+
+	err = n.merge.apply(n.input, inputs)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	//The gradient of a multiplication depends on the values of the other inputs, which may change before BackPropagate. The other modes only need the sizes.
+	if n.merge.Mode == MultiplyMerge {
+		if n.lastInputs == nil || !n.lastInputs[0].HasSize(inputs[0].Size) {
+			n.lastInputs = make([]*tensor.Tensor, len(inputs))
+			for i := range inputs {
+				n.lastInputs[i] = tensor.NewTensor(inputs[i].Size...)
+			}
+		}
+		for i := range inputs {
+			copy(n.lastInputs[i].Values, inputs[i].Values)
+		}
+	} else {
+		n.lastInputs = inputs
 	}
 
 	//The undelying layer does the actual computation
-	out, err := n.layer.Activate(n.input)
-	if err != nil {
-		panic(err)
+	if n.batch {
+		batchLayer, ok := n.layer.(weight.BatchLayer)
+		if !ok || !batchLayer.SupportsBatch() {
+			return nil, errors.New("Layer does not support batches")
+		}
+
+		return batchLayer.ActivateBatch(n.input)
 	}
 
-	//Send to all childs
-	for i := 0; i < len(n.outputs); i++ {
-		n.outputs[i] <- out
+	return n.layer.Activate(n.input)
+}
+
+//withBatch returns the size of a tensor of the node, adding the number of examples of t if the node is processing batches
+func (n *FFNode) withBatch(size []int, t *tensor.Tensor) []int {
+	if !n.batch || len(t.Size) == 0 {
+		return size
 	}
+
+	batchSize := make([]int, len(size), len(size)+1)
+	copy(batchSize, size)
+
+	return append(batchSize, t.Size[len(t.Size)-1])
 }
 
-//BackPropagate waits for the child nodes to send its propagated errors, computes the sum of them and passes it to the underlying layer's BackPropagate, then propagates the result to all parents.
-func (n *FFNode) BackPropagate() {
+//BackPropagate waits for the child nodes to send its propagated errors, computes the sum of them and passes it to the underlying layer's BackPropagate, then splits the result between the parents depending on the merge mode and propagates it to them.
+//Failures are handled the same way as in Activate.
+func (n *FFNode) BackPropagate(errs chan<- error) {
 	nc := len(n.outputs)
 
 	gradientErrors := make([]*tensor.Tensor, nc)
 
+	failed := false
 	for i := 0; i < nc; i++ {
 		gradientErrors[i] = <-n.outputs[i]
-		if !gradientErrors[i].HasSize(n.layer.GetOutputSize()) {
-			panic("One of errors to node has not the correct size")
+		if gradientErrors[i] == nil {
+			failed = true
+		}
+	}
+
+	props := make([]*tensor.Tensor, len(n.inputs))
+	if !failed {
+		var err error
+		props, err = n.backPropagate(gradientErrors)
+		if err != nil {
+			errs <- fmt.Errorf("Layer %s: %s", n.ID(), err.Error())
+			props = make([]*tensor.Tensor, len(n.inputs))
 		}
 	}
 
-	//Create a tensor if it doesn't exist
-	if n.gradientError == nil {
-		n.gradientError = tensor.NewTensor(n.layer.GetOutputSize()...)
+	//Send to all parents
+	for i := 0; i < len(n.inputs); i++ {
+		n.inputs[i] <- props[i]
+	}
+}
+
+func (n *FFNode) backPropagate(gradientErrors []*tensor.Tensor) (props []*tensor.Tensor, err error) {
+	//A panic inside a layer should not kill the whole program
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic during backpropagation: %v", r)
+		}
+	}()
+
+	bpLayer, ok := n.layer.(weight.BPLearnerLayer)
+	if !ok {
+		return nil, errors.New("weight.Layer does not implement weight.BPLearnerLayer interface")
+	}
+
+	outputSize := n.withBatch(n.layer.GetOutputSize(), gradientErrors[0])
+
+	for i := range gradientErrors {
+		if !gradientErrors[i].HasSize(outputSize) {
+			return nil, fmt.Errorf("One of errors has not the correct size. Actual:%v expected:%v", gradientErrors[i].Size, outputSize)
+		}
+	}
+
+	//Create a tensor if it doesn't exist or the size of the batch changed
+	if n.gradientError == nil || !n.gradientError.HasSize(outputSize) {
+		n.gradientError = tensor.NewTensor(outputSize...)
 	} else {
 		n.gradientError.Zero(0)
 	}
 
-	err := n.gradientError.Add(gradientErrors...)
+	err = n.gradientError.Add(gradientErrors...)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	//The undelying layer does the actual computation
-	prop, err := n.layer.(weight.BPLearnerLayer).BackPropagate(n.gradientError)
+	var prop *tensor.Tensor
+	if n.batch {
+		batchLayer, ok := n.layer.(weight.BatchLayer)
+		if !ok || !batchLayer.SupportsBatch() {
+			return nil, errors.New("Layer does not support batches")
+		}
+
+		prop, err = batchLayer.BackPropagateBatch(n.gradientError)
+	} else {
+		prop, err = bpLayer.BackPropagate(n.gradientError)
+	}
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	//Send to all parents
-	for i := 0; i < len(n.inputs); i++ {
-		n.inputs[i] <- prop
+	if len(n.lastInputs) != len(n.inputs) {
+		return nil, errors.New("BackPropagate called before Activate")
+	}
+
+	//Create the tensors if they don't exist or the size of the batch changed
+	if n.merge.Mode != SumMerge && (n.parentGradients == nil || !n.parentGradients[0].HasSize(n.lastInputs[0].Size)) {
+		n.parentGradients = make([]*tensor.Tensor, len(n.inputs))
+		for i := range n.parentGradients {
+			n.parentGradients[i] = tensor.NewTensor(n.lastInputs[i].Size...)
+		}
 	}
+
+	grads := make([]*tensor.Tensor, len(n.inputs))
+	copy(grads, n.parentGradients)
+
+	return n.merge.split(prop, n.lastInputs, grads), nil
+}
+
+//DefaultInput and DefaultOutput are the names of the ports of an FFNet that are not explicitly named: the first layer added with AddLayer and the last layer when no outputs are added with AddOutput
+const (
+	DefaultInput  = "input"
+	DefaultOutput = "output"
+)
+
+//netPort is a named input or output of an FFNet
+type netPort struct {
+	name string
+	node *FFNode
+	c    chan *tensor.Tensor
 }
 
 //FFNet is a generic feedforward network. It can include any number branches, but they cannot form a loop.
+//
+//A network can have several named inputs (see AddInputLayer) and outputs (see AddOutput). Activate and BackPropagate can only be used with networks with one input and one output, use ActivateMulti and BackPropagateMulti for the others.
 type FFNet struct {
 	id string
 
-	input  chan *tensor.Tensor
-	output chan *tensor.Tensor
-
-	startNode *FFNode
-	endNode   *FFNode
+	//Ports in the order they were added. The outputs are sorted by the order of their nodes.
+	inputs  []*netPort
+	outputs []*netPort
 
 	nodes []*FFNode
 
+	//Nodes report their errors here. It has enough buffer for all nodes to fail at the same time.
+	errs chan error
+
 	finished bool
 }
 
@@ -147,82 +311,484 @@ func (n *FFNet) CreateSlave() weight.Layer {
 			parents = append(parents, parentNode.ID())
 		}
 
-		err = ng.AddLayer(enslaver.CreateSlave(), parents...)
+		if port := portByNode(n.inputs, n.nodes[i]); port != nil {
+			err = ng.AddInputLayer(port.name, enslaver.CreateSlave())
+		} else {
+			err = ng.AddMergedLayer(enslaver.CreateSlave(), n.nodes[i].merge, parents...)
+		}
 		if err != nil {
 			panic(err)
 		}
 
 	}
 
+	for _, port := range n.outputs {
+		err := ng.AddOutput(port.name, port.node.ID())
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	ng.End()
 
 	return ng
 }
 
-//Activate takes an input tensor and passes it through all the layers in the netork following the node connections.
+//Spec returns the description of the network and all its nodes. All the layers inside must implement SerializableLayer. See SaveNet.
+func (n *FFNet) Spec() (*LayerSpec, error) {
+	if !n.finished {
+		return nil, errors.New("FFNet is not finished, use End() to finish it before saving it")
+	}
+
+	spec, err := NewLayerSpec("FFNet", n.ID(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range n.nodes {
+		sl, ok := node.layer.(SerializableLayer)
+		if !ok {
+			return nil, fmt.Errorf("weight.Layer %s inside FFNet does not implement SerializableLayer interface", node.ID())
+		}
+
+		ls, err := sl.Spec()
+		if err != nil {
+			return nil, err
+		}
+
+		ns := &NodeSpec{Layer: ls}
+		if node.merge != (Merge{}) {
+			merge := node.merge
+			ns.Merge = &merge
+		}
+		for _, parent := range node.parents {
+			ns.Parents = append(ns.Parents, parent.ID())
+		}
+		if port := portByNode(n.inputs, node); port != nil {
+			ns.Input = port.name
+		}
+		if port := portByNode(n.outputs, node); port != nil {
+			ns.Output = port.name
+		}
+
+		spec.Nodes = append(spec.Nodes, ns)
+	}
+
+	return spec, nil
+}
+
+func loadFFNet(spec *LayerSpec) (weight.Layer, error) {
+	if len(spec.Nodes) == 0 {
+		return nil, fmt.Errorf("FFNet %s has no nodes", spec.ID)
+	}
+
+	net := NewFFNet()
+	net.id = spec.ID
+
+	for _, ns := range spec.Nodes {
+		layer, err := LoadLayer(ns.Layer)
+		if err != nil {
+			return nil, err
+		}
+
+		merge := Merge{}
+		if ns.Merge != nil {
+			merge = *ns.Merge
+		}
+
+		if ns.Input != "" {
+			err = net.AddInputLayer(ns.Input, layer)
+		} else {
+			err = net.AddMergedLayer(layer, merge, ns.Parents...)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//Files without named outputs use the last node
+	for _, ns := range spec.Nodes {
+		if ns.Output != "" {
+			err := net.AddOutput(ns.Output, ns.Layer.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err := net.End()
+	if err != nil {
+		return nil, err
+	}
+
+	return net, nil
+}
+
+//Activate takes an input tensor and passes it through all the layers in the netork following the node connections. The network must have only one input and one output.
 func (n *FFNet) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	err := n.checkSinglePorts()
+	if err != nil {
+		return nil, err
+	}
+
+	outs, err := n.activate([]*tensor.Tensor{input}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return outs[0], nil
+}
+
+func (n *FFNet) BackPropagate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	err := n.checkSinglePorts()
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := n.backPropagate([]*tensor.Tensor{input}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return props[0], nil
+}
+
+//SupportsBatch returns true if all the layers of the network support batches. See weight.BatchLayer.
+func (n *FFNet) SupportsBatch() bool {
+	for _, node := range n.nodes {
+		if !weight.SupportsBatch(node.layer) {
+			return false
+		}
+	}
+
+	return len(n.nodes) > 0
+}
+
+//ActivateBatch is like Activate for a batch of examples. Each layer processes the whole batch at once. See weight.BatchLayer.
+func (n *FFNet) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	err := n.checkSinglePorts()
+	if err != nil {
+		return nil, err
+	}
+
+	outs, err := n.activate([]*tensor.Tensor{input}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return outs[0], nil
+}
+
+//BackPropagateBatch is like BackPropagate for the batch of the last ActivateBatch. See weight.BatchLayer.
+func (n *FFNet) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	e := n.checkSinglePorts()
+	if e != nil {
+		return nil, e
+	}
+
+	props, e := n.backPropagate([]*tensor.Tensor{err}, true)
+	if e != nil {
+		return nil, e
+	}
+
+	return props[0], nil
+}
+
+//Activate32 is like Activate for a float32 input, meant for inference. See weight.Float32Layer. Unlike Activate, the layers are activated one after the other in the order they were added, in the calling goroutine. All of them must implement weight.Float32Layer (batch normalization, dropout, recurrent and attention layers do not), and the network must have a single input and output, as there is no float32 version of ActivateMulti. The inputs of merged layers must have the output size of their parents; a layer that returns a tensor of another size makes it return an error.
+func (n *FFNet) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	err := n.checkSinglePorts()
+	if err != nil {
+		return nil, err
+	}
+
+	//Parents are always added before their children, so they are already activated
+	outs := make(map[*FFNode]*tensor.Tensor32, len(n.nodes))
+	for _, node := range n.nodes {
+		layer, ok := node.layer.(weight.Float32Layer)
+		if !ok {
+			return nil, fmt.Errorf("Layer %s does not implement weight.Float32Layer", node.ID())
+		}
+
+		in := input
+		if len(node.parents) == 1 {
+			in = outs[node.parents[0]]
+		} else if len(node.parents) > 1 {
+			inputs := make([]*tensor.Tensor32, len(node.parents))
+			for i, parent := range node.parents {
+				inputs[i] = outs[parent]
+				if !inputs[i].HasSize(parent.layer.GetOutputSize()) {
+					return nil, fmt.Errorf("Layer %s: input from %s has size %d but should be %d", node.ID(), parent.ID(), inputs[i].Size, parent.layer.GetOutputSize())
+				}
+			}
+
+			if node.input32 == nil {
+				node.input32 = tensor.NewTensor32(node.layer.GetInputSize()...)
+			}
+
+			err := node.merge.apply32(node.input32, inputs)
+			if err != nil {
+				return nil, fmt.Errorf("Layer %s: %s", node.ID(), err.Error())
+			}
+			in = node.input32
+		}
+
+		outs[node], err = layer.Activate32(in)
+		if err != nil {
+			return nil, fmt.Errorf("Layer %s: %s", node.ID(), err.Error())
+		}
+	}
+
+	return outs[n.outputs[0].node], nil
+}
+
+//SyncFloat32 converts the parameters of all the layers to float32 again. See weight.Float32Layer.
+func (n *FFNet) SyncFloat32() {
+	for _, node := range n.nodes {
+		if layer, ok := node.layer.(weight.Float32Layer); ok {
+			layer.SyncFloat32()
+		}
+	}
+}
+
+//ActivateMulti is like Activate for networks with several inputs or outputs. It takes a tensor for each input of the network and returns a tensor for each output, by name.
+func (n *FFNet) ActivateMulti(inputs map[string]*tensor.Tensor) (map[string]*tensor.Tensor, error) {
 	if !n.finished {
 		return nil, errors.New("FFNet is not finished, use End() to finish it before using it")
 	}
 
-	//Call all nodes concurrently
-	for i := range n.nodes {
-		go n.nodes[i].Activate()
+	ins, err := portTensors(n.inputs, inputs, "input")
+	if err != nil {
+		return nil, err
 	}
 
-	//Send the data to the starting node
-	n.input <- input
+	outs, err := n.activate(ins, false)
+	if err != nil {
+		return nil, err
+	}
 
-	//Wait for the result to be available and return it
-	return <-n.output, nil
+	return portMap(n.outputs, outs), nil
 }
 
-func (n *FFNet) BackPropagate(input *tensor.Tensor) (*tensor.Tensor, error) {
+//BackPropagateMulti is like BackPropagate for networks with several inputs or outputs. It takes the gradient of the cost with respect to each output of the network and returns the gradient with respect to each input, by name.
+func (n *FFNet) BackPropagateMulti(errs map[string]*tensor.Tensor) (map[string]*tensor.Tensor, error) {
 	if !n.finished {
 		return nil, errors.New("FFNet is not finished, use End() to finish it before using it")
 	}
 
+	grads, err := portTensors(n.outputs, errs, "output")
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := n.backPropagate(grads, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return portMap(n.inputs, props), nil
+}
+
+func (n *FFNet) checkSinglePorts() error {
+	if !n.finished {
+		return errors.New("FFNet is not finished, use End() to finish it before using it")
+	}
+
+	if len(n.inputs) != 1 || len(n.outputs) != 1 {
+		return fmt.Errorf("FFNet has %d inputs and %d outputs, use ActivateMulti and BackPropagateMulti", len(n.inputs), len(n.outputs))
+	}
+
+	return nil
+}
+
+//activate sends a tensor (or a batch of them) to each input port and returns the tensors of the output ports
+func (n *FFNet) activate(inputs []*tensor.Tensor, batch bool) ([]*tensor.Tensor, error) {
+	//Call all nodes concurrently
+	for i := range n.nodes {
+		n.nodes[i].batch = batch
+		go n.nodes[i].Activate(n.errs)
+	}
+
+	//Send the data to the input nodes
+	for i, port := range n.inputs {
+		port.c <- inputs[i]
+	}
+
+	//Wait for the results to be available and return them
+	return n.collect(n.outputs)
+}
+
+//backPropagate sends a gradient (or a batch of them) to each output port and returns the gradients of the input ports
+func (n *FFNet) backPropagate(grads []*tensor.Tensor, batch bool) ([]*tensor.Tensor, error) {
 	//Call all nodes concurrently
 	for i := range n.nodes {
-		go n.nodes[i].BackPropagate()
+		n.nodes[i].batch = batch
+		go n.nodes[i].BackPropagate(n.errs)
 	}
 
-	//Send the data to the starting node
-	n.output <- input
+	//Send the gradients to the output nodes
+	for i, port := range n.outputs {
+		port.c <- grads[i]
+	}
 
-	//Wait for the result to be available and return it
-	return <-n.input, nil
+	//Wait for the results to be available and return them
+	return n.collect(n.inputs)
 }
 
+//collect waits for a tensor from every port. All of them must be received, even after a failure, so no node is left waiting.
+func (n *FFNet) collect(ports []*netPort) ([]*tensor.Tensor, error) {
+	ts := make([]*tensor.Tensor, len(ports))
+
+	failed := false
+	for i, port := range ports {
+		ts[i] = <-port.c
+		if ts[i] == nil {
+			failed = true
+		}
+	}
+
+	if failed {
+		return nil, n.collectErrors()
+	}
+
+	return ts, nil
+}
+
+//portTensors returns the tensors in the order of the ports. All the ports must have a tensor.
+func portTensors(ports []*netPort, ts map[string]*tensor.Tensor, kind string) ([]*tensor.Tensor, error) {
+	if len(ts) != len(ports) {
+		return nil, fmt.Errorf("FFNet has %d %ss but got %d tensors", len(ports), kind, len(ts))
+	}
+
+	res := make([]*tensor.Tensor, len(ports))
+	for i, port := range ports {
+		res[i] = ts[port.name]
+		if res[i] == nil {
+			return nil, fmt.Errorf("Missing tensor for %s %s", kind, port.name)
+		}
+	}
+
+	return res, nil
+}
+
+func portMap(ports []*netPort, ts []*tensor.Tensor) map[string]*tensor.Tensor {
+	res := make(map[string]*tensor.Tensor, len(ports))
+	for i, port := range ports {
+		res[port.name] = ts[i]
+	}
+	return res
+}
+
+func portByNode(ports []*netPort, node *FFNode) *netPort {
+	for _, port := range ports {
+		if port.node == node {
+			return port
+		}
+	}
+	return nil
+}
+
+func portByName(ports []*netPort, name string) *netPort {
+	for _, port := range ports {
+		if port.name == name {
+			return port
+		}
+	}
+	return nil
+}
+
+//collectErrors returns the errors reported by the nodes in a single error. When the result of the net is nil, all the failed nodes have already reported their errors.
+func (n *FFNet) collectErrors() error {
+	msgs := []string{}
+	for len(n.errs) > 0 {
+		msgs = append(msgs, (<-n.errs).Error())
+	}
+
+	if len(msgs) == 0 {
+		return errors.New("FFNet failed without reporting errors")
+	}
+
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+//AddLayer adds a layer to the network. The first layer added receives the input of the network (named DefaultInput), and the rest must have at least one parent. If a layer has more than one parent it receives the sum of their outputs.
 func (n *FFNet) AddLayer(layer weight.Layer, parents ...string) error {
+	return n.AddMergedLayer(layer, Merge{}, parents...)
+}
 
+//AddInputLayer adds a layer without parents that receives the input of the network with the given name. See ActivateMulti.
+func (n *FFNet) AddInputLayer(name string, layer weight.Layer) error {
 	if n.finished {
 		return errors.New("FFNet finished, cannot add more layers")
 	}
 
-	if n.nodes == nil {
-		n.nodes = []*FFNode{}
+	if portByName(n.inputs, name) != nil {
+		return errors.New("There's already an input in the FFNet with the name " + name)
 	}
 
 	if n.nodeByID(layer.ID()) != nil {
 		return errors.New("There's already a layer in the FFNet with the id " + layer.ID())
 	}
 
-	node := &FFNode{layer: layer}
-	node.outputs = []chan *tensor.Tensor{}
-	node.inputs = []chan *tensor.Tensor{}
+	port := &netPort{name: name, c: make(chan *tensor.Tensor, 1)}
 
-	if len(n.nodes) == 0 {
+	port.node = &FFNode{layer: layer}
+	port.node.outputs = []chan *tensor.Tensor{}
+	port.node.inputs = []chan *tensor.Tensor{port.c}
+
+	n.inputs = append(n.inputs, port)
+	n.nodes = append(n.nodes, port.node)
+
+	return nil
+}
+
+//AddOutput makes the output of the layer with the given id an output of the network with the given name. The layer can still have children. If no outputs are added, the last layer is the output of the network (named DefaultOutput).
+func (n *FFNet) AddOutput(name, id string) error {
+	if n.finished {
+		return errors.New("FFNet finished, cannot add more outputs")
+	}
+
+	if portByName(n.outputs, name) != nil {
+		return errors.New("There's already an output in the FFNet with the name " + name)
+	}
+
+	node := n.nodeByID(id)
+	if node == nil {
+		return errors.New("Could not find output layer " + id)
+	}
+
+	if portByNode(n.outputs, node) != nil {
+		return errors.New("The layer " + id + " is already an output of the FFNet")
+	}
+
+	n.outputs = append(n.outputs, &netPort{name: name, node: node})
+
+	return nil
+}
+
+//AddMergedLayer adds a layer to the network like AddLayer, but combining the outputs of the parents as specified by merge. See Merge.
+func (n *FFNet) AddMergedLayer(layer weight.Layer, merge Merge, parents ...string) error {
+
+	if n.finished {
+		return errors.New("FFNet finished, cannot add more layers")
+	}
+
+	if len(n.nodes) == 0 && len(parents) == 0 {
 		//First node
-		n.input = make(chan *tensor.Tensor, 1)
-		n.startNode = node
-		node.inputs = append(node.inputs, n.input)
-	} else {
-		if len(parents) == 0 {
-			return errors.New("No parent especified")
-		}
+		return n.AddInputLayer(DefaultInput, layer)
 	}
 
+	if len(parents) == 0 {
+		return errors.New("No parent especified")
+	}
+
+	if n.nodeByID(layer.ID()) != nil {
+		return errors.New("There's already a layer in the FFNet with the id " + layer.ID())
+	}
+
+	node := &FFNode{layer: layer, merge: merge}
+	node.outputs = []chan *tensor.Tensor{}
+	node.inputs = []chan *tensor.Tensor{}
+
 	for _, parentID := range parents {
 		parent := n.nodeByID(parentID)
 		if parent == nil {
@@ -268,25 +834,122 @@ func (n *FFNet) setParent(node *FFNode, parent *FFNode) error {
 	return nil
 }
 
-//End closes the network
+//End closes the network. It checks that the network is valid and returns an error describing all the problems found, in which case the network is not finished and can still be modified.
 func (n *FFNet) End() error {
-	last := n.nodes[len(n.nodes)-1]
-	n.output = make(chan *tensor.Tensor, 1)
-	n.endNode = last
+	if n.finished {
+		return errors.New("FFNet already finished")
+	}
+
+	if len(n.nodes) == 0 {
+		return errors.New("FFNet has no layers")
+	}
+
+	outputs := n.outputs
+	if len(outputs) == 0 {
+		outputs = []*netPort{{name: DefaultOutput, node: n.nodes[len(n.nodes)-1]}}
+	}
+
+	err := n.validate(outputs)
+	if err != nil {
+		return err
+	}
 
-	last.outputs = []chan *tensor.Tensor{n.output}
+	//Sort the outputs like the nodes, so a saved network has them in the same order when loaded
+	sorted := make([]*netPort, 0, len(outputs))
+	for _, node := range n.nodes {
+		if port := portByNode(outputs, node); port != nil {
+			port.c = make(chan *tensor.Tensor, 1)
+			node.outputs = append(node.outputs, port.c)
+			sorted = append(sorted, port)
+		}
+	}
+	n.outputs = sorted
+
+	n.errs = make(chan error, len(n.nodes))
 
 	n.finished = true
 
 	return nil
 }
 
+//validate checks that the outputs of the parents of every node can be merged into the input of its layer, and that every node leads to an output (otherwise its result is lost and it never receives a gradient). Every node except the inputs has parents added before it, so all of them are reachable from an input and there can be no loops.
+func (n *FFNet) validate(outputs []*netPort) error {
+	msgs := []string{}
+
+	for _, node := range n.nodes {
+		if portByNode(n.inputs, node) != nil {
+			continue
+		}
+
+		sizes := make([][]int, len(node.parents))
+		for i, parent := range node.parents {
+			sizes[i] = parent.layer.GetOutputSize()
+		}
+
+		size, err := node.merge.Size(sizes)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("Layer %s: %s", node.ID(), err.Error()))
+			continue
+		}
+
+		if !sameSize(size, node.layer.GetInputSize()) {
+			msgs = append(msgs, fmt.Sprintf("Layer %s: input size is %v but its parents give %v", node.ID(), node.layer.GetInputSize(), size))
+		}
+	}
+
+	//Walk back from the outputs. Parents are always before their children, so one pass in reverse order is enough.
+	used := map[*FFNode]bool{}
+	for _, port := range outputs {
+		used[port.node] = true
+	}
+
+	for i := len(n.nodes) - 1; i >= 0; i-- {
+		if used[n.nodes[i]] {
+			for _, parent := range n.nodes[i].parents {
+				used[parent] = true
+			}
+		}
+	}
+
+	for _, node := range n.nodes {
+		if !used[node] {
+			msgs = append(msgs, fmt.Sprintf("Layer %s does not lead to any output", node.ID()))
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New("Invalid FFNet: " + strings.Join(msgs, "; "))
+	}
+
+	return nil
+}
+
+//GetOutputSize returns the size of the first output of the network
 func (n *FFNet) GetOutputSize() []int {
-	return n.endNode.layer.GetOutputSize()
+	return n.outputs[0].node.layer.GetOutputSize()
 }
 
+//GetInputSize returns the size of the first input of the network
 func (n *FFNet) GetInputSize() []int {
-	return n.startNode.layer.GetInputSize()
+	return n.inputs[0].node.layer.GetInputSize()
+}
+
+//GetInputSizes returns the size of each input of the network by name
+func (n *FFNet) GetInputSizes() map[string][]int {
+	sizes := make(map[string][]int, len(n.inputs))
+	for _, port := range n.inputs {
+		sizes[port.name] = port.node.layer.GetInputSize()
+	}
+	return sizes
+}
+
+//GetOutputSizes returns the size of each output of the network by name
+func (n *FFNet) GetOutputSizes() map[string][]int {
+	sizes := make(map[string][]int, len(n.outputs))
+	for _, port := range n.outputs {
+		sizes[port.name] = port.node.layer.GetOutputSize()
+	}
+	return sizes
 }
 
 func (n *FFNet) GetParamGradPointers() ([]*float64, []*float64) {
@@ -306,6 +969,30 @@ func (n *FFNet) GetParamGradPointers() ([]*float64, []*float64) {
 	return params, grads
 }
 
+//GetStatePointers returns the state of all the layers in the network, in the order they were added. See weight.StateLayer.
+func (n *FFNet) GetStatePointers() []*float64 {
+	state := []*float64{}
+	for _, node := range n.nodes {
+		state = append(state, weight.GetStatePointers(node.layer)...)
+	}
+	return state
+}
+
+//SetTraining sets the mode of all the layers in the network. See weight.ModeLayer.
+func (n *FFNet) SetTraining(training bool) {
+	for _, node := range n.nodes {
+		weight.SetTraining(node.layer, training)
+	}
+}
+
+//Seed seeds all the layers of the network that use random numbers, each one with a different seed derived from seed. See weight.RandLayer.
+func (n *FFNet) Seed(seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	for _, node := range n.nodes {
+		weight.Seed(node.layer, rng.Int63())
+	}
+}
+
 func (n *FFNet) GetDebugInfo() []*debug.LayerInfo {
 
 	stats := []*debug.LayerInfo{}
@@ -338,7 +1025,10 @@ func NewSequentialNet(layers ...weight.Layer) (*FFNet, error) {
 		id = layers[i].ID()
 	}
 
-	net.End()
+	err = net.End()
+	if err != nil {
+		return nil, err
+	}
 
 	return net, nil
 }
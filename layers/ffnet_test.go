@@ -1,8 +1,12 @@
 package layers
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/gerardabello/weight/tensor"
 )
 
@@ -46,3 +50,326 @@ func TestGraphActivation(t *testing.T) {
 	}
 
 }
+
+func TestGraphActivationError(t *testing.T) {
+	dl1 := NewDenseLayer([]int{6}, []int{5})
+	dl2 := NewDenseLayer([]int{5}, []int{2})
+
+	g, err := NewSequentialNet(dl1, dl2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = g.Activate(tensor.NewTensor(4))
+	if err == nil {
+		t.Fatal("Activation with wrong input size should return error")
+	}
+
+	if !strings.Contains(err.Error(), dl1.ID()) {
+		t.Errorf("Error should contain the ID of the failing layer. Error: %s", err.Error())
+	}
+
+	//The network should still be usable after an error
+	_, err = g.Activate(tensor.NewTensor(6))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = g.BackPropagate(tensor.NewTensor(3))
+	if err == nil {
+		t.Fatal("Backpropagation with wrong gradient size should return error")
+	}
+
+	_, err = g.BackPropagate(tensor.NewTensor(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+//newMergeNet returns a net with two dense branches from the input [4, 3] merged with the given mode, followed by a dense layer
+func newMergeNet(t *testing.T, merge Merge, sizeA, sizeB, merged []int) *FFNet {
+	net := NewFFNet()
+
+	in := NewIdentityLayer(4, 3)
+	a := NewDenseLayer([]int{4, 3}, sizeA)
+	b := NewDenseLayer([]int{4, 3}, sizeB)
+	out := NewDenseLayer(merged, []int{2})
+
+	for _, err := range []error{
+		net.AddLayer(in),
+		net.AddLayer(a, in.ID()),
+		net.AddLayer(b, in.ID()),
+		net.AddMergedLayer(out, merge, a.ID(), b.ID()),
+		net.End(),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return net
+}
+
+func TestGraphMergeGradients(t *testing.T) {
+	checkGradients(t, newMergeNet(t, Merge{}, []int{3, 2}, []int{3, 2}, []int{3, 2}), randomTensor(4, 3))
+	checkGradients(t, newMergeNet(t, Merge{Mode: AverageMerge}, []int{3, 2}, []int{3, 2}, []int{3, 2}), randomTensor(4, 3))
+	checkGradients(t, newMergeNet(t, Merge{Mode: MultiplyMerge}, []int{3, 2}, []int{3, 2}, []int{3, 2}), randomTensor(4, 3))
+	checkGradients(t, newMergeNet(t, NewConcatMerge(0), []int{2, 2}, []int{3, 2}, []int{5, 2}), randomTensor(4, 3))
+	checkGradients(t, newMergeNet(t, NewConcatMerge(1), []int{3, 1}, []int{3, 2}, []int{3, 3}), randomTensor(4, 3))
+}
+
+func TestMergeConcat(t *testing.T) {
+	merge := NewConcatMerge(1)
+
+	a := &tensor.Tensor{Size: []int{2, 1}, Values: []float64{1, 2}}
+	b := &tensor.Tensor{Size: []int{2, 2}, Values: []float64{3, 4, 5, 6}}
+
+	size, err := merge.Size([][]int{a.Size, b.Size})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameSize(size, []int{2, 3}) {
+		t.Fatalf("Wrong concatenated size %v", size)
+	}
+
+	out := tensor.NewTensor(size...)
+	err = merge.apply(out, []*tensor.Tensor{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range []float64{1, 2, 3, 4, 5, 6} {
+		if out.Values[i] != v {
+			t.Fatalf("Wrong concatenation %v", out.Values)
+		}
+	}
+
+	_, err = NewConcatMerge(0).Size([][]int{a.Size, b.Size})
+	if err == nil {
+		t.Fatal("Concatenating tensors with different sizes in other axes should return error")
+	}
+}
+
+func TestGraphMergeSaveLoad(t *testing.T) {
+	net := newMergeNet(t, NewConcatMerge(0), []int{2, 2}, []int{3, 2}, []int{5, 2})
+
+	input := randomTensor(4, 3)
+	out, err := net.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := out.Copy()
+
+	var b bytes.Buffer
+	err = SaveNet(&b, net)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadNet(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = loaded.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range out.Values {
+		if out.Values[i] != expected.Values[i] {
+			t.Fatalf("Loaded network should produce the same output. Expected %v, got %v", expected.Values, out.Values)
+		}
+	}
+}
+
+//newMultiNet returns a net with inputs "image" [3] and "meta" [2], and outputs "class" (the sum of a dense layer of each input passed through another dense layer) and "aux" (the dense layer of the image)
+func newMultiNet(t *testing.T) (*FFNet, []*DenseLayer) {
+	net := NewFFNet()
+
+	image := NewDenseLayer([]int{3}, []int{2})
+	meta := NewDenseLayer([]int{2}, []int{2})
+	class := NewDenseLayer([]int{2}, []int{1})
+
+	for _, err := range []error{
+		net.AddInputLayer("image", image),
+		net.AddInputLayer("meta", meta),
+		net.AddLayer(class, image.ID(), meta.ID()),
+		net.AddOutput("class", class.ID()),
+		net.AddOutput("aux", image.ID()),
+		net.End(),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return net, []*DenseLayer{image, meta, class}
+}
+
+func TestGraphMultiPorts(t *testing.T) {
+	assert := assert.New(t)
+
+	net, lyrs := newMultiNet(t)
+
+	assert.Equal(map[string][]int{"image": {3}, "meta": {2}}, net.GetInputSizes())
+	assert.Equal(map[string][]int{"class": {1}, "aux": {2}}, net.GetOutputSizes())
+
+	inputs := map[string]*tensor.Tensor{"image": randomTensor(3), "meta": randomTensor(2)}
+
+	outs, err := net.ActivateMulti(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Compute the same outputs layer by layer
+	image, _ := lyrs[0].Activate(inputs["image"])
+	image = image.Copy()
+	meta, _ := lyrs[1].Activate(inputs["meta"])
+	image.Add(meta)
+	class, _ := lyrs[2].Activate(image)
+
+	assert.InDeltaSlice(class.Values, outs["class"].Values, 1e-12)
+	assert.InDeltaSlice(lyrs[0].output.Values, outs["aux"].Values, 1e-12)
+
+	_, err = net.Activate(inputs["image"])
+	assert.Error(err, "Activate should fail with several inputs")
+
+	_, err = net.ActivateMulti(map[string]*tensor.Tensor{"image": inputs["image"]})
+	assert.Error(err, "ActivateMulti should fail with missing inputs")
+
+	_, err = net.ActivateMulti(map[string]*tensor.Tensor{"image": inputs["image"], "other": inputs["meta"]})
+	assert.Error(err, "ActivateMulti should fail with unknown inputs")
+}
+
+func TestGraphMultiGradients(t *testing.T) {
+	net, _ := newMultiNet(t)
+
+	inputs := map[string]*tensor.Tensor{"image": randomTensor(3), "meta": randomTensor(2)}
+	grads := map[string]*tensor.Tensor{"class": randomTensor(1), "aux": randomTensor(2)}
+
+	loss := func() float64 {
+		outs, err := net.ActivateMulti(inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := 0.0
+		for name, g := range grads {
+			for i, v := range outs[name].Values {
+				sum += v * g.Values[i]
+			}
+		}
+		return sum
+	}
+
+	loss()
+	props, err := net.BackPropagateMulti(grads)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const h = 1e-6
+	for name, in := range inputs {
+		for i := range in.Values {
+			v := in.Values[i]
+			in.Values[i] = v + h
+			lp := loss()
+			in.Values[i] = v - h
+			lm := loss()
+			in.Values[i] = v
+
+			assert.InDelta(t, (lp-lm)/(2*h), props[name].Values[i], 1e-5, "Wrong propagation of input %s %d", name, i)
+		}
+	}
+}
+
+func TestGraphMultiSaveLoad(t *testing.T) {
+	net, _ := newMultiNet(t)
+
+	inputs := map[string]*tensor.Tensor{"image": randomTensor(3), "meta": randomTensor(2)}
+	expected, err := net.ActivateMulti(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = SaveNet(&b, net)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadNet(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, l := range []*FFNet{loaded, net.CreateSlave().(*FFNet)} {
+		outs, err := l.ActivateMulti(inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for name := range expected {
+			assert.InDeltaSlice(t, expected[name].Values, outs[name].Values, 1e-12)
+		}
+	}
+}
+
+func TestGraphValidation(t *testing.T) {
+	net := NewFFNet()
+
+	in := NewDenseLayer([]int{4}, []int{3})
+	wrong := NewDenseLayer([]int{2}, []int{2})
+	dangling := NewDenseLayer([]int{3}, []int{2})
+	out := NewDenseLayer([]int{3}, []int{2})
+	concat := NewDenseLayer([]int{5}, []int{1})
+
+	for _, err := range []error{
+		net.AddLayer(in),
+		net.AddLayer(wrong, in.ID()),
+		net.AddLayer(dangling, in.ID()),
+		net.AddLayer(out, in.ID()),
+		net.AddMergedLayer(concat, NewConcatMerge(0), out.ID(), wrong.ID()),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := net.End()
+	if err == nil {
+		t.Fatal("End should fail with an invalid network")
+	}
+
+	//All the problems are reported at once
+	for _, id := range []string{wrong.ID(), dangling.ID(), concat.ID()} {
+		if !strings.Contains(err.Error(), id) {
+			t.Errorf("Error should contain the ID of layer %s. Error: %s", id, err.Error())
+		}
+	}
+	if strings.Contains(err.Error(), out.ID()) {
+		t.Errorf("Error should not contain the ID of the valid layer %s. Error: %s", out.ID(), err.Error())
+	}
+
+	_, err = NewSequentialNet(NewDenseLayer([]int{4}, []int{3}), NewDenseLayer([]int{2}, []int{1}))
+	if err == nil {
+		t.Fatal("NewSequentialNet should fail with wrong sizes")
+	}
+}
+
+func TestGraphWriteDOT(t *testing.T) {
+	net, lyrs := newMultiNet(t)
+
+	var b bytes.Buffer
+	err := net.WriteDOT(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dot := b.String()
+	for _, s := range []string{"digraph", "DenseLayer", lyrs[0].ID(), "params 8", "input:image", "output:aux", "in [3] out [2]"} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("DOT output should contain %q:\n%s", s, dot)
+		}
+	}
+}
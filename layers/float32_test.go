@@ -0,0 +1,168 @@
+package layers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//checkFloat32 compares the output of Activate32 with the one of Activate
+func checkFloat32(t *testing.T, layer weight.Float32Layer) {
+	input := randomTensor(layer.GetInputSize()...)
+
+	out, err := layer.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := out.Copy()
+
+	out32, err := layer.Activate32(input.ToFloat32())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, expected.Size, out32.Size)
+	assert.InDeltaSlice(t, expected.Values, out32.ToFloat64().Values, 1e-4)
+}
+
+func TestFloat32Layers(t *testing.T) {
+	checkFloat32(t, NewDenseLayer([]int{4, 3}, []int{5}))
+	checkFloat32(t, NewConvolutionalLayer(6, 5, 3, 4, 1, 1, 1, 1, 1, 1))
+	checkFloat32(t, NewConvolutionalLayer(7, 7, 2, 3, 1, 2, 2, 1, 0, 1))
+	checkFloat32(t, NewDilatedConvolutionalLayer(7, 7, 2, 3, 1, 1, 1, 1, 2, 2, 2, 2))
+	checkFloat32(t, NewGroupedConvolutionalLayer(5, 5, 4, 6, 1, 1, 1, 1, 1, 1, 2))
+	checkFloat32(t, NewConv1DLayer(9, 3, 4, 1, 2, 1))
+	checkFloat32(t, NewPoolLayer([]int{6, 6, 2}, []int{2, 2, 1}))
+	checkFloat32(t, NewStridedPoolLayer([]int{5, 5, 2}, []int{3, 3, 1}, []int{2, 2, 1}, []int{1, 1, 0}, AvgPool))
+	checkFloat32(t, NewGlobalAvgPoolLayer(3, 3, 4))
+	checkFloat32(t, NewReLULayer(4, 3))
+	checkFloat32(t, NewLeakyReLULayer(4, 3))
+	checkFloat32(t, NewSigmoidLayer(4, 3))
+	checkFloat32(t, NewSoftmaxLayer(6))
+	checkFloat32(t, NewIdentityLayer(4, 3))
+	checkFloat32(t, NewReshaperLayer([]int{4, 3}, []int{12}))
+}
+
+func TestFloat32FFNet(t *testing.T) {
+	checkFloat32(t, newMergeNet(t, Merge{}, []int{3, 2}, []int{3, 2}, []int{3, 2}))
+	checkFloat32(t, newMergeNet(t, Merge{Mode: AverageMerge}, []int{3, 2}, []int{3, 2}, []int{3, 2}))
+	checkFloat32(t, newMergeNet(t, Merge{Mode: MultiplyMerge}, []int{3, 2}, []int{3, 2}, []int{3, 2}))
+	checkFloat32(t, newMergeNet(t, NewConcatMerge(1), []int{3, 1}, []int{3, 2}, []int{3, 3}))
+
+	net, err := NewSequentialNet(NewDenseLayer([]int{3}, []int{3}), NewBatchNormLayer(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = net.Activate32(tensor.NewTensor32(3))
+	assert.Error(t, err, "BatchNorm does not support float32")
+
+	//An unsupported layer in a branch of a merge
+	net = NewFFNet()
+	in := NewIdentityLayer(4, 3)
+	a := NewDenseLayer([]int{4, 3}, []int{4, 2})
+	b := NewDropoutLayer(0.5, 4, 3)
+	out := NewDenseLayer([]int{4, 5}, []int{2})
+	for _, err := range []error{
+		net.AddLayer(in),
+		net.AddLayer(a, in.ID()),
+		net.AddLayer(b, in.ID()),
+		net.AddMergedLayer(out, NewConcatMerge(1), a.ID(), b.ID()),
+		net.End(),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = net.Activate32(tensor.NewTensor32(4, 3))
+	if assert.Error(t, err, "Dropout does not support float32") {
+		assert.Contains(t, err.Error(), b.ID())
+	}
+
+	multi, _ := newMultiNet(t)
+	_, err = multi.Activate32(tensor.NewTensor32(3))
+	assert.Error(t, err, "Networks with several inputs are not supported")
+
+	_, err = NewFFNet().Activate32(tensor.NewTensor32(3))
+	assert.Error(t, err, "The network should be finished")
+
+	//A layer that does not return its output size cannot be merged
+	net = NewFFNet()
+	in = NewIdentityLayer(3, 2)
+	wrong := wrongSize32Layer{NewIdentityLayer(3, 2)}
+	out = NewDenseLayer([]int{3, 2}, []int{2})
+	for _, err := range []error{
+		net.AddLayer(in),
+		net.AddLayer(wrong, in.ID()),
+		net.AddMergedLayer(out, Merge{Mode: MultiplyMerge}, in.ID(), wrong.ID()),
+		net.End(),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = net.Activate32(tensor.NewTensor32(3, 2))
+	assert.Error(t, err, "Inputs of a merge with the wrong size should fail")
+}
+
+//wrongSize32Layer is an IdentityLayer that returns a float32 output with a wrong size
+type wrongSize32Layer struct {
+	*IdentityLayer
+}
+
+func (l wrongSize32Layer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	return tensor.NewTensor32(2), nil
+}
+
+func TestFloat32Sync(t *testing.T) {
+	l := NewDenseLayer([]int{3}, []int{2})
+	input := tensor.NewTensor32(3)
+	input.Zero(1)
+
+	out, err := l.Activate32(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := out.Copy()
+
+	//The float32 parameters are only updated after SyncFloat32
+	l.bias.Values[0] += 1
+
+	out, _ = l.Activate32(input)
+	assert.Equal(t, before.Values, out.Values)
+
+	l.SyncFloat32()
+	out, _ = l.Activate32(input)
+	assert.InDelta(t, before.Values[0]+1, out.Values[0], 1e-5)
+}
+
+func TestSaveLoadNetFloat32(t *testing.T) {
+	net, err := NewSequentialNet(
+		NewConvolutionalLayer(6, 6, 2, 3, 1, 1, 1, 1, 1, 1),
+		NewReLULayer(6, 6, 3),
+		NewDenseLayer([]int{6, 6, 3}, []int{4}),
+		NewSoftmaxLayer(4),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b, b32 bytes.Buffer
+	assert.NoError(t, SaveNet(&b, net))
+	assert.NoError(t, SaveNetFloat32(&b32, net))
+	assert.True(t, b32.Len() < b.Len()*6/10, "Float32 file should be about half the size")
+
+	loaded, err := LoadNet(&b32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ := net.GetParamGradPointers()
+	lp, _ := loaded.GetParamGradPointers()
+	for i := range p {
+		assert.Equal(t, float64(float32(*p[i])), *lp[i])
+	}
+}
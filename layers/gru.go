@@ -0,0 +1,134 @@
+package layers
+
+import (
+	"math"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//GRULayer is a gated recurrent unit layer. Each timestep computes the reset and update gates and the candidate state (in this order in the parameters):
+//
+// n_t = tanh(W x_t + U (r*h_t-1) + b)
+// h_t = (1-u)*n_t + u*h_t-1
+//
+//It takes a sequence [features, timesteps] and returns the last hidden state [hidden], or all of them [hidden, timesteps] if returnSequences is true.
+type GRULayer struct {
+	recurrentLayer
+
+	//Input of the candidate of each timestep: the input and the previous hidden state multiplied by the reset gate
+	zn []float64
+
+	//Gradient of zn
+	dzn []float64
+}
+
+//NewGRULayer creates a new GRULayer
+func NewGRULayer(features, hidden, timesteps int, returnSequences bool) *GRULayer {
+	l := &GRULayer{}
+	l.init(features, hidden, timesteps, 3, returnSequences)
+	l.id = "GRU-" + l.id
+
+	l.zn = make([]float64, timesteps*(features+hidden))
+	l.dzn = make([]float64, features+hidden)
+
+	return l
+}
+
+//CreateSlave creates a slave of the GRULayer. See EnslaverLayer in package weight for more information on layer slaves.
+func (l *GRULayer) CreateSlave() weight.Layer {
+	nl := NewGRULayer(l.features, l.hidden, l.timesteps, l.returnSequences)
+	nl.Truncation = l.Truncation
+
+	nl.weights = l.weights
+	nl.bias = l.bias
+
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *GRULayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("GRU", l.ID(), l.config(), l.weights, l.bias)
+}
+
+func loadGRULayer(spec *LayerSpec) (weight.Layer, error) {
+	c := recurrentConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewGRULayer(c.Features, c.Hidden, c.Timesteps, c.ReturnSequences)
+	l.Truncation = c.Truncation
+	l.id = spec.ID
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+func (l *GRULayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return l.activate(input, l.step)
+}
+
+func (l *GRULayer) step(t int) {
+	z, act, hPrev, h := l.timestep(t)
+	nh := l.hidden
+	nf := l.features
+
+	//Reset and update gates
+	l.affine(0, 2*nh, z, act)
+	for i := 0; i < 2*nh; i++ {
+		act[i] = sigmoid(act[i])
+	}
+
+	zn := l.zn[t*(nf+nh) : (t+1)*(nf+nh)]
+	copy(zn, z[:nf])
+	for i := 0; i < nh; i++ {
+		zn[nf+i] = act[i] * hPrev[i]
+	}
+
+	cand := act[2*nh:]
+	l.affine(2*nh, 3*nh, zn, cand)
+
+	for i := 0; i < nh; i++ {
+		cand[i] = math.Tanh(cand[i])
+		u := act[nh+i]
+		h[i] = (1-u)*cand[i] + u*hPrev[i]
+	}
+}
+
+func (l *GRULayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	return l.backPropagate(err, l.stepBack, func() {})
+}
+
+func (l *GRULayer) stepBack(t int) {
+	z, act, hPrev, _ := l.timestep(t)
+	nh := l.hidden
+	nf := l.features
+
+	zn := l.zn[t*(nf+nh) : (t+1)*(nf+nh)]
+
+	//Candidate
+	for i := 0; i < nh; i++ {
+		u, cand := act[nh+i], act[2*nh+i]
+		l.da[2*nh+i] = l.dh[i] * (1 - u) * (1 - cand*cand)
+		l.da[nh+i] = l.dh[i] * (hPrev[i] - cand) * u * (1 - u)
+	}
+
+	for i := range l.dzn {
+		l.dzn[i] = 0
+	}
+	l.affineBack(2*nh, 3*nh, zn, l.da[2*nh:], l.dzn)
+
+	//The candidate sees the input directly and the previous state through the reset gate
+	copy(l.dz[:nf], l.dzn[:nf])
+	for i := 0; i < nh; i++ {
+		r := act[i]
+		l.da[i] = l.dzn[nf+i] * hPrev[i] * r * (1 - r)
+		l.dz[nf+i] = l.dzn[nf+i]*r + l.dh[i]*act[nh+i]
+	}
+
+	//Reset and update gates
+	l.affineBack(0, 2*nh, z, l.da[:2*nh], l.dz)
+}
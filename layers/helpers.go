@@ -70,3 +70,195 @@ func NewCRPBlocks(inputSize []int, nConv, nKernels, nBlocks int) *FFNet {
 
 	return net
 }
+
+//NewDepthwiseSeparableBlock creates a MobileNet block: a 3x3 depthwise convolution with the given stride followed by a 1x1 convolution with nKernels kernels, each of them followed by batch normalization and ReLU. It needs about 8 times less operations than a normal 3x3 convolution.
+func NewDepthwiseSeparableBlock(inputSize []int, nKernels, stride int) *FFNet {
+
+	if len(inputSize) != 3 {
+		panic("Input size should have 3 dimensions")
+	}
+
+	dw := NewDepthwiseConvolutionalLayer(inputSize[0], inputSize[1], inputSize[2], 1, 1, 1, stride, stride, 1, 1)
+	size := dw.GetOutputSize()
+
+	pw := NewConvolutionalLayer(size[0], size[1], size[2], nKernels, 0, 0, 1, 1, 0, 0)
+
+	net, err := NewSequentialNet(
+		dw,
+		NewBatchNormLayer(size...),
+		NewReLULayer(size...),
+		pw,
+		NewBatchNormLayer(pw.GetOutputSize()...),
+		NewReLULayer(pw.GetOutputSize()...),
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return net
+}
+
+//NewFeedForwardBlock creates the position-wise feed-forward block of a transformer for sequences [features, timesteps]: layer normalization followed by two PositionwiseDenseLayers with a ReLU between them, and a residual connection that adds the input to the result.
+func NewFeedForwardBlock(features, hidden, timesteps int) *FFNet {
+	return newResidualSequence([]int{features, timesteps},
+		NewLayerNormLayer(features, timesteps),
+		NewPositionwiseDenseLayer(features, hidden, timesteps),
+		NewReLULayer(hidden, timesteps),
+		NewPositionwiseDenseLayer(hidden, features, timesteps),
+	)
+}
+
+//NewTransformerBlock creates a (pre-norm) transformer encoder block for sequences [features, timesteps]: a residual self-attention with layer normalization followed by a NewFeedForwardBlock. If causal is true it can be used as a decoder block without cross-attention.
+func NewTransformerBlock(features, hidden, timesteps, heads int, causal bool) *FFNet {
+	attention := newResidualSequence([]int{features, timesteps},
+		NewLayerNormLayer(features, timesteps),
+		NewSelfAttentionLayer(features, timesteps, heads, causal),
+	)
+
+	net, err := NewSequentialNet(
+		attention,
+		NewFeedForwardBlock(features, hidden, timesteps),
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return net
+}
+
+//newResidualSequence returns a net that runs the layers in sequence and adds the input to their output. The output size of the last layer must be the input size.
+func newResidualSequence(size []int, lyrs ...weight.Layer) *FFNet {
+	net := NewFFNet()
+
+	in := NewIdentityLayer(size...)
+	err := net.AddLayer(in)
+	if err != nil {
+		panic(err)
+	}
+
+	parent := in.ID()
+	for _, l := range lyrs {
+		err = net.AddLayer(l, parent)
+		if err != nil {
+			panic(err)
+		}
+		parent = l.ID()
+	}
+
+	//The last node has two parents, so it gets the sum of the input and the output of the layers
+	err = net.AddLayer(NewIdentityLayer(size...), in.ID(), parent)
+	if err != nil {
+		panic(err)
+	}
+
+	err = net.End()
+	if err != nil {
+		panic(err)
+	}
+
+	return net
+}
+
+//NewResidualBlock creates the basic block of a ResNet: two 3x3 convolutions with nKernels kernels, each of them followed by batch normalization, whose result is added to the input (the shortcut) before a final ReLU. The first convolution has the given stride.
+//
+//If the output has a different size than the input (because of the stride or the number of kernels), the shortcut is a 1x1 convolution with batch normalization that projects the input to the output size.
+func NewResidualBlock(inputSize []int, nKernels, stride int) *FFNet {
+
+	if len(inputSize) != 3 {
+		panic("Input size should have 3 dimensions")
+	}
+
+	cl1 := NewConvolutionalLayer(inputSize[0], inputSize[1], inputSize[2], nKernels, 1, 1, stride, stride, 1, 1)
+	size := cl1.GetOutputSize()
+	cl2 := NewConvolutionalLayer(size[0], size[1], size[2], nKernels, 1, 1, 1, 1, 1, 1)
+
+	return newShortcutBlock(inputSize, size, stride,
+		cl1,
+		NewBatchNormLayer(size...),
+		NewReLULayer(size...),
+		cl2,
+		NewBatchNormLayer(size...),
+	)
+}
+
+//NewBottleneckBlock creates the bottleneck block of the deeper ResNets: a 1x1 convolution that reduces the depth to nKernels, a 3x3 convolution with the given stride and a 1x1 convolution that expands the depth to 4*nKernels, each of them followed by batch normalization. The shortcut is the same as in NewResidualBlock.
+func NewBottleneckBlock(inputSize []int, nKernels, stride int) *FFNet {
+
+	if len(inputSize) != 3 {
+		panic("Input size should have 3 dimensions")
+	}
+
+	reduce := NewConvolutionalLayer(inputSize[0], inputSize[1], inputSize[2], nKernels, 0, 0, 1, 1, 0, 0)
+	size := reduce.GetOutputSize()
+	conv := NewConvolutionalLayer(size[0], size[1], size[2], nKernels, 1, 1, stride, stride, 1, 1)
+	convSize := conv.GetOutputSize()
+	expand := NewConvolutionalLayer(convSize[0], convSize[1], convSize[2], 4*nKernels, 0, 0, 1, 1, 0, 0)
+	outputSize := expand.GetOutputSize()
+
+	return newShortcutBlock(inputSize, outputSize, stride,
+		reduce,
+		NewBatchNormLayer(size...),
+		NewReLULayer(size...),
+		conv,
+		NewBatchNormLayer(convSize...),
+		NewReLULayer(convSize...),
+		expand,
+		NewBatchNormLayer(outputSize...),
+	)
+}
+
+//newShortcutBlock returns a net that runs the layers in sequence, adds the shortcut (the input, or its projection if the sizes are different) to their output, and applies a ReLU
+func newShortcutBlock(inputSize, outputSize []int, stride int, lyrs ...weight.Layer) *FFNet {
+	net := NewFFNet()
+
+	in := NewIdentityLayer(inputSize...)
+	err := net.AddLayer(in)
+	if err != nil {
+		panic(err)
+	}
+
+	parent := in.ID()
+	for _, l := range lyrs {
+		err = net.AddLayer(l, parent)
+		if err != nil {
+			panic(err)
+		}
+		parent = l.ID()
+	}
+
+	shortcut := in.ID()
+	if !sameSize(inputSize, outputSize) {
+		proj := NewConvolutionalLayer(inputSize[0], inputSize[1], inputSize[2], outputSize[2], 0, 0, stride, stride, 0, 0)
+		if !sameSize(proj.GetOutputSize(), outputSize) {
+			panic("Shortcut projection does not match the output size of the block")
+		}
+
+		bn := NewBatchNormLayer(outputSize...)
+
+		err = net.AddLayer(proj, in.ID())
+		if err != nil {
+			panic(err)
+		}
+
+		err = net.AddLayer(bn, proj.ID())
+		if err != nil {
+			panic(err)
+		}
+
+		shortcut = bn.ID()
+	}
+
+	err = net.AddLayer(NewReLULayer(outputSize...), parent, shortcut)
+	if err != nil {
+		panic(err)
+	}
+
+	err = net.End()
+	if err != nil {
+		panic(err)
+	}
+
+	return net
+}
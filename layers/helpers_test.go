@@ -0,0 +1,40 @@
+package layers
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight"
+)
+
+func TestResidualBlockSize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]int{8, 8, 4}, NewResidualBlock([]int{8, 8, 4}, 4, 1).GetOutputSize())
+	assert.Equal([]int{4, 4, 6}, NewResidualBlock([]int{8, 8, 4}, 6, 2).GetOutputSize())
+	assert.Equal([]int{8, 8, 8}, NewBottleneckBlock([]int{8, 8, 8}, 2, 1).GetOutputSize())
+	assert.Equal([]int{4, 4, 12}, NewBottleneckBlock([]int{8, 8, 4}, 3, 2).GetOutputSize())
+}
+
+//randomizeParams sets all the parameters of the layer to random values. With the biases at 0, a convolution that only sees values cut by a ReLU outputs exactly 0, and the numerical gradient of the next ReLU is wrong.
+func randomizeParams(layer weight.BPLearnerLayer) weight.BPLearnerLayer {
+	params, _ := layer.GetParamGradPointers()
+	for _, p := range params {
+		*p = rand.NormFloat64()
+	}
+	return layer
+}
+
+func TestResidualBlockGradients(t *testing.T) {
+	checkGradients(t, randomizeParams(NewResidualBlock([]int{4, 4, 2}, 2, 1)), randomTensor(4, 4, 2))
+	checkGradients(t, randomizeParams(NewResidualBlock([]int{6, 6, 2}, 3, 2)), randomTensor(6, 6, 2))
+	checkGradients(t, randomizeParams(NewBottleneckBlock([]int{6, 6, 3}, 2, 2)), randomTensor(6, 6, 3))
+
+	//In training mode the batch normalization layers use the statistics of the batch
+	block := NewResidualBlock([]int{6, 6, 2}, 3, 2)
+	randomizeParams(block)
+	block.SetTraining(true)
+	checkBatchGradients(t, block, randomTensor(6, 6, 2, 3))
+}
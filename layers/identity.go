@@ -0,0 +1,122 @@
+package layers
+
+import (
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//IdentityLayer returns its input unchanged. It is useful as the first node of an FFNet that needs to use the input in more than one branch (for example, in a residual connection), or as the last node to sum several branches.
+type IdentityLayer struct {
+	BaseLayer
+}
+
+//NewIdentityLayer creates a new IdentityLayer
+func NewIdentityLayer(size ...int) *IdentityLayer {
+	layer := &IdentityLayer{}
+	layer.BaseLayer.Init(size, size)
+	layer.id = "Identity-" + layer.id
+	return layer
+}
+
+func (l *IdentityLayer) CreateSlave() weight.Layer {
+	nl := NewIdentityLayer(l.GetInputSize()...)
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *IdentityLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("Identity", l.ID(), sizeConfig{InputSize: l.GetInputSize()})
+}
+
+func loadIdentityLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := sizeConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewIdentityLayer(c.InputSize...)
+	l.id = spec.ID
+
+	return l, nil
+}
+
+func (l *IdentityLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	copy(l.output.Values, input.Values)
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+func (l *IdentityLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	copy(l.propagation.Values, err.Values)
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *IdentityLayer) SupportsBatch() bool {
+	return true
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer.
+func (l *IdentityLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	copy(l.batchOutput.Values, input.Values)
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *IdentityLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	copy(l.batchPropagation.Values, err.Values)
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
+
+//Activate32 is like Activate for a float32 input. See weight.Float32Layer.
+func (l *IdentityLayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	copy(l.output32.Values, input.Values)
+
+	l.mutex.Unlock()
+	return &l.output32, nil
+}
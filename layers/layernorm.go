@@ -0,0 +1,163 @@
+package layers
+
+import (
+	"math"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//LayerNormLayer normalizes the features of each position to zero mean and unit variance, and then applies a learnable scale (gamma) and shift (beta) per feature. The features are the first dimension of the input, so a sequence [features, timesteps] is normalized independently at each timestep.
+//
+//Unlike BatchNormLayer, the statistics are computed on each input, so the layer behaves the same during training and inference.
+type LayerNormLayer struct {
+	BaseLayer
+
+	features int
+
+	//Epsilon is added to the variance to avoid dividing by 0
+	Epsilon float64
+
+	//Normalized input and inverse of the standard deviation of each position in the last activation, needed to backpropagate
+	xhat   []float64
+	invStd []float64
+}
+
+type layerNormConfig struct {
+	InputSize []int
+	Epsilon   float64
+}
+
+//NewLayerNormLayer creates a new LayerNormLayer with gamma set to 1 and beta to 0
+func NewLayerNormLayer(size ...int) *LayerNormLayer {
+	layer := &LayerNormLayer{}
+	layer.BaseLayer.Init(size, size)
+	layer.id = "LayerNorm-" + layer.id
+
+	layer.Epsilon = 1e-5
+	layer.features = size[0]
+
+	layer.weights = tensor.NewTensor(layer.features)
+	layer.weightsGrad = tensor.NewTensor(layer.features)
+	layer.weights.Zero(1)
+
+	layer.bias = tensor.NewTensor(layer.features)
+	layer.biasGrad = tensor.NewTensor(layer.features)
+
+	layer.xhat = make([]float64, tensor.SizeLength(size))
+	layer.invStd = make([]float64, tensor.SizeLength(size)/layer.features)
+
+	return layer
+}
+
+//CreateSlave creates a slave of the LayerNormLayer. See EnslaverLayer in package weight for more information on layer slaves.
+func (l *LayerNormLayer) CreateSlave() weight.Layer {
+	nl := NewLayerNormLayer(l.GetInputSize()...)
+
+	nl.weights = l.weights
+	nl.bias = l.bias
+	nl.Epsilon = l.Epsilon
+
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *LayerNormLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("LayerNorm", l.ID(), layerNormConfig{l.GetInputSize(), l.Epsilon}, l.weights, l.bias)
+}
+
+func loadLayerNormLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := layerNormConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewLayerNormLayer(c.InputSize...)
+	l.id = spec.ID
+	l.Epsilon = c.Epsilon
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+func (l *LayerNormLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	n := l.features
+	gamma := l.weights.Values
+	beta := l.bias.Values
+
+	for p := range l.invStd {
+		in := input.Values[p*n : (p+1)*n]
+		xhat := l.xhat[p*n : (p+1)*n]
+		out := l.output.Values[p*n : (p+1)*n]
+
+		mean := 0.0
+		for _, v := range in {
+			mean += v
+		}
+		mean /= float64(n)
+
+		variance := 0.0
+		for _, v := range in {
+			variance += (v - mean) * (v - mean)
+		}
+		variance /= float64(n)
+
+		l.invStd[p] = 1 / math.Sqrt(variance+l.Epsilon)
+
+		for i, v := range in {
+			xhat[i] = (v - mean) * l.invStd[p]
+			out[i] = gamma[i]*xhat[i] + beta[i]
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+func (l *LayerNormLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	n := l.features
+	gamma := l.weights.Values
+
+	for p := range l.invStd {
+		errs := err.Values[p*n : (p+1)*n]
+		xhat := l.xhat[p*n : (p+1)*n]
+		prop := l.propagation.Values[p*n : (p+1)*n]
+
+		//Gradient of the normalized values, and its mean and its mean weighted by the normalized values
+		meanDxhat := 0.0
+		meanDxhatXhat := 0.0
+		for i, v := range errs {
+			l.weightsGrad.Values[i] += v * xhat[i]
+			l.biasGrad.Values[i] += v
+
+			dxhat := v * gamma[i]
+			meanDxhat += dxhat
+			meanDxhatXhat += dxhat * xhat[i]
+		}
+		meanDxhat /= float64(n)
+		meanDxhatXhat /= float64(n)
+
+		for i, v := range errs {
+			prop[i] = l.invStd[p] * (v*gamma[i] - meanDxhat - xhat[i]*meanDxhatXhat)
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
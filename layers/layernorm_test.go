@@ -0,0 +1,38 @@
+package layers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestLayerNormActivation(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewLayerNormLayer(2, 2)
+	layer.Epsilon = 0
+	layer.weights.Values = []float64{1, 2}
+	layer.bias.Values = []float64{0, 1}
+
+	out, err := layer.Activate(&tensor.Tensor{Size: []int{2, 2}, Values: []float64{1, 3, -5, -1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Each timestep is normalized to [-1, 1] before the scale and shift
+	assert.InDeltaSlice([]float64{-1, 3, -1, 3}, out.Values, 1e-12)
+}
+
+func TestLayerNormGradients(t *testing.T) {
+	layer := NewLayerNormLayer(5, 3)
+	for i := range layer.weights.Values {
+		layer.weights.Values[i] = 1 + 0.1*float64(i)
+		layer.bias.Values[i] = math.Sin(float64(i))
+	}
+
+	checkGradients(t, layer, randomTensor(5, 3))
+	checkGradients(t, NewLayerNormLayer(4), randomTensor(4))
+}
@@ -23,23 +23,33 @@ func (l *LeakyReLULayer) CreateSlave() weight.Layer {
 	return nl
 }
 
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *LeakyReLULayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("LeakyReLU", l.ID(), sizeConfig{InputSize: l.GetInputSize()})
+}
+
+func loadLeakyReLULayer(spec *LayerSpec) (weight.Layer, error) {
+	c := sizeConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewLeakyReLULayer(c.InputSize...)
+	l.id = spec.ID
+
+	return l, nil
+}
+
 func (l *LeakyReLULayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	err := l.BaseLayer.Activate(input)
 	if err != nil {
+		l.mutex.Unlock()
 		return nil, err
 	}
 
-	//New a slice with length equal to the number of neurons
-	inputs := input.Values
-
-	for i := range l.output.Values {
-		if inputs[i] > 0 {
-			l.output.Values[i] = inputs[i]
-		} else {
-			l.output.Values[i] = 0.01 * inputs[i]
-		}
-	}
+	leakyReLU(l.output.Values, input.Values)
 
 	l.mutex.Unlock()
 	return &l.output, nil
@@ -49,19 +59,88 @@ func (l *LeakyReLULayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, erro
 	l.mutex.Lock()
 	e := l.BaseLayer.BackPropagate(err)
 	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	leakyReLUBack(l.propagation.Values, err.Values, l.lastInput.Values)
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *LeakyReLULayer) SupportsBatch() bool {
+	return true
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer.
+func (l *LeakyReLULayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	leakyReLU(l.batchOutput.Values, input.Values)
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *LeakyReLULayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
 		return nil, e
 	}
 
-	errs := err.Values
-	inputs := l.lastInput.Values
+	leakyReLUBack(l.batchPropagation.Values, err.Values, l.lastBatch.Values)
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
+
+func leakyReLU(out, inputs []float64) {
+	for i := range out {
+		if inputs[i] > 0 {
+			out[i] = inputs[i]
+		} else {
+			out[i] = 0.01 * inputs[i]
+		}
+	}
+}
 
-	for i := range l.propagation.Values {
+func leakyReLUBack(prop, errs, inputs []float64) {
+	for i := range prop {
 		if inputs[i] > 0 {
-			l.propagation.Values[i] = errs[i]
+			prop[i] = errs[i]
+		} else {
+			prop[i] = 0.01 * errs[i]
+		}
+	}
+}
+
+//Activate32 is like Activate for a float32 input. See weight.Float32Layer.
+func (l *LeakyReLULayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	for i, v := range input.Values {
+		if v > 0 {
+			l.output32.Values[i] = v
 		} else {
-			l.propagation.Values[i] = 0.01 * errs[i]
+			l.output32.Values[i] = 0.01 * v
 		}
 	}
+
 	l.mutex.Unlock()
-	return &l.propagation, nil
+	return &l.output32, nil
 }
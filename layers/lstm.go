@@ -0,0 +1,134 @@
+package layers
+
+import (
+	"math"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//LSTMLayer is a long short-term memory layer. Each timestep computes the input, forget and output gates and the candidate cell (in this order in the parameters):
+//
+// c_t = f*c_t-1 + i*g
+// h_t = o*tanh(c_t)
+//
+//It takes a sequence [features, timesteps] and returns the last hidden state [hidden], or all of them [hidden, timesteps] if returnSequences is true.
+type LSTMLayer struct {
+	recurrentLayer
+
+	//Cell state of each timestep (the first one is the initial state) and its tanh
+	c     []float64
+	tanhC []float64
+
+	//Gradient of the cell state flowing to the previous timestep
+	dc []float64
+}
+
+//NewLSTMLayer creates a new LSTMLayer. The bias of the forget gate is initialized to 1, so the layer remembers by default.
+func NewLSTMLayer(features, hidden, timesteps int, returnSequences bool) *LSTMLayer {
+	l := &LSTMLayer{}
+	l.init(features, hidden, timesteps, 4, returnSequences)
+	l.id = "LSTM-" + l.id
+
+	for i := hidden; i < 2*hidden; i++ {
+		l.bias.Values[i] = 1
+	}
+
+	l.c = make([]float64, (timesteps+1)*hidden)
+	l.tanhC = make([]float64, (timesteps+1)*hidden)
+	l.dc = make([]float64, hidden)
+
+	return l
+}
+
+//CreateSlave creates a slave of the LSTMLayer. See EnslaverLayer in package weight for more information on layer slaves.
+func (l *LSTMLayer) CreateSlave() weight.Layer {
+	nl := NewLSTMLayer(l.features, l.hidden, l.timesteps, l.returnSequences)
+	nl.Truncation = l.Truncation
+
+	nl.weights = l.weights
+	nl.bias = l.bias
+
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *LSTMLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("LSTM", l.ID(), l.config(), l.weights, l.bias)
+}
+
+func loadLSTMLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := recurrentConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewLSTMLayer(c.Features, c.Hidden, c.Timesteps, c.ReturnSequences)
+	l.Truncation = c.Truncation
+	l.id = spec.ID
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+func (l *LSTMLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return l.activate(input, l.step)
+}
+
+func (l *LSTMLayer) step(t int) {
+	z, act, _, h := l.timestep(t)
+	nh := l.hidden
+
+	l.affine(0, 4*nh, z, act)
+
+	cPrev := l.c[t*nh : (t+1)*nh]
+	c := l.c[(t+1)*nh : (t+2)*nh]
+	tanhC := l.tanhC[(t+1)*nh : (t+2)*nh]
+
+	for i := 0; i < nh; i++ {
+		in := sigmoid(act[i])
+		forget := sigmoid(act[nh+i])
+		out := sigmoid(act[2*nh+i])
+		cand := math.Tanh(act[3*nh+i])
+
+		act[i], act[nh+i], act[2*nh+i], act[3*nh+i] = in, forget, out, cand
+
+		c[i] = forget*cPrev[i] + in*cand
+		tanhC[i] = math.Tanh(c[i])
+		h[i] = out * tanhC[i]
+	}
+}
+
+func (l *LSTMLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	return l.backPropagate(err, l.stepBack, func() {
+		for i := range l.dc {
+			l.dc[i] = 0
+		}
+	})
+}
+
+func (l *LSTMLayer) stepBack(t int) {
+	z, act, _, _ := l.timestep(t)
+	nh := l.hidden
+
+	cPrev := l.c[t*nh : (t+1)*nh]
+	tanhC := l.tanhC[(t+1)*nh : (t+2)*nh]
+
+	for i := 0; i < nh; i++ {
+		in, forget, out, cand := act[i], act[nh+i], act[2*nh+i], act[3*nh+i]
+
+		dOut := l.dh[i] * tanhC[i]
+		dc := l.dh[i]*out*(1-tanhC[i]*tanhC[i]) + l.dc[i]
+
+		l.da[i] = dc * cand * in * (1 - in)
+		l.da[nh+i] = dc * cPrev[i] * forget * (1 - forget)
+		l.da[2*nh+i] = dOut * out * (1 - out)
+		l.da[3*nh+i] = dc * in * (1 - cand*cand)
+
+		l.dc[i] = dc * forget
+	}
+
+	l.affineBack(0, 4*nh, z, l.da, l.dz)
+}
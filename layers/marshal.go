@@ -0,0 +1,336 @@
+package layers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//ModelFormatVersion is the version of the model file format written by SaveNet. LoadNet refuses files with a newer version. It is increased every time the format changes (new layer types, new fields in the specs or new tensor encodings), so older readers reject newer files instead of loading them wrong.
+//
+//	1: first version
+//	2: pooling mode, stride and padding, and GlobalAvgPool
+//	3: TransposedConv and Upsampling layers
+//	4: convolution dilation and groups
+//	5: Conv1D layer
+//	6: RNN, LSTM and GRU layers
+//	7: LayerNorm, Identity, SelfAttention, PositionalEncoding and PositionwiseDense layers
+//	8: merge modes of FFNet nodes
+//	9: named input and output ports of FFNet
+//	10: parameters stored as float32 (SaveNetFloat32)
+const ModelFormatVersion = 10
+
+var modelMagic = [4]byte{'W', 'G', 'H', 'T'}
+
+//maxDescriptionLen is the maximum size of the JSON description of a model file. It prevents a corrupted header from allocating an arbitrary amount of memory.
+const maxDescriptionLen = 64 << 20
+
+//LayerSpec describes a layer: its type, ID, the hyperparameters needed to create it again and the tensors that hold its parameters.
+type LayerSpec struct {
+	Type string
+	ID   string
+
+	//Config holds the constructor arguments of the layer. Each layer type defines its own structure.
+	Config json.RawMessage `json:",omitempty"`
+
+	//NumTensors is the number of tensors stored after the description. They are not part of the JSON description.
+	NumTensors int `json:",omitempty"`
+
+	//Nodes is only used by FFNet
+	Nodes []*NodeSpec `json:",omitempty"`
+
+	Tensors []*tensor.Tensor `json:"-"`
+}
+
+//NodeSpec describes a node inside an FFNet
+type NodeSpec struct {
+	Layer   *LayerSpec
+	Parents []string `json:",omitempty"`
+	Merge   *Merge   `json:",omitempty"`
+
+	//Names of the input and output ports of the network the node is connected to, if any
+	Input  string `json:",omitempty"`
+	Output string `json:",omitempty"`
+}
+
+//SerializableLayer is a layer that can describe itself so it can be saved with SaveNet and rebuilt with LoadNet
+type SerializableLayer interface {
+	weight.Layer
+
+	//Spec returns a description of the layer. The tensors in the spec may point to the internal parameters of the layer, so they should not be modified.
+	Spec() (*LayerSpec, error)
+}
+
+//LayerLoader creates a layer from its spec
+type LayerLoader func(spec *LayerSpec) (weight.Layer, error)
+
+var layerLoaders map[string]LayerLoader
+
+func init() {
+	layerLoaders = map[string]LayerLoader{
+		"FFNet":              loadFFNet,
+		"Dense":              loadDenseLayer,
+		"Conv":               loadConvolutionalLayer,
+		"Pool":               loadPoolLayer,
+		"ReLU":               loadReLULayer,
+		"LeakyReLU":          loadLeakyReLULayer,
+		"Sigmoid":            loadSigmoidLayer,
+		"Softmax":            loadSoftmaxLayer,
+		"Reshaper":           loadReshaperLayer,
+		"BatchNorm":          loadBatchNormLayer,
+		"Dropout":            loadDropoutLayer,
+		"GlobalAvgPool":      loadGlobalAvgPoolLayer,
+		"TransposedConv":     loadTransposedConvolutionalLayer,
+		"Upsampling":         loadUpsamplingLayer,
+		"Conv1D":             loadConv1DLayer,
+		"RNN":                loadRNNLayer,
+		"LSTM":               loadLSTMLayer,
+		"GRU":                loadGRULayer,
+		"LayerNorm":          loadLayerNormLayer,
+		"Identity":           loadIdentityLayer,
+		"SelfAttention":      loadSelfAttentionLayer,
+		"PositionalEncoding": loadPositionalEncodingLayer,
+		"PositionwiseDense":  loadPositionwiseDenseLayer,
+	}
+}
+
+//RegisterLayer makes a layer type known to LoadNet. Use it to be able to load networks that contain your own layer implementations.
+func RegisterLayer(layerType string, loader LayerLoader) {
+	layerLoaders[layerType] = loader
+}
+
+//SaveNet writes the whole network (structure, hyperparameters and parameters) to w. Every layer in the network must implement SerializableLayer.
+func SaveNet(w io.Writer, net *FFNet) error {
+	return saveNet(w, net, false)
+}
+
+//SaveNetFloat32 is like SaveNet but stores the parameters as float32, so the file is half the size. It is meant for networks used for inference, where the precision of float32 is enough. LoadNet reads it like any other file.
+func SaveNetFloat32(w io.Writer, net *FFNet) error {
+	return saveNet(w, net, true)
+}
+
+func saveNet(w io.Writer, net *FFNet, asFloat32 bool) error {
+	spec, err := net.Spec()
+	if err != nil {
+		return err
+	}
+
+	desc, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	header := struct {
+		Magic   [4]byte
+		Version uint32
+		DescLen uint32
+	}{modelMagic, ModelFormatVersion, uint32(len(desc))}
+
+	err = binary.Write(bw, binary.BigEndian, header)
+	if err != nil {
+		return err
+	}
+
+	_, err = bw.Write(desc)
+	if err != nil {
+		return err
+	}
+
+	err = writeSpecTensors(bw, spec, asFloat32)
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+//LoadNet reads a network written with SaveNet and returns it ready to be activated. A corrupted or truncated file returns an error.
+func LoadNet(r io.Reader) (net *FFNet, err error) {
+	//The layer constructors panic with invalid hyperparameters, which a corrupted file can contain
+	defer func() {
+		if rec := recover(); rec != nil {
+			net = nil
+			err = fmt.Errorf("Invalid model file: %v", rec)
+		}
+	}()
+
+	br := bufio.NewReader(r)
+
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		DescLen uint32
+	}
+
+	err = binary.Read(br, binary.BigEndian, &header)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Magic != modelMagic {
+		return nil, errors.New("Not a weight model file")
+	}
+
+	if header.Version > ModelFormatVersion {
+		return nil, fmt.Errorf("Model file version %d is newer than the supported version %d", header.Version, ModelFormatVersion)
+	}
+
+	if header.DescLen > maxDescriptionLen {
+		return nil, fmt.Errorf("Model description of %d bytes is bigger than the maximum of %d", header.DescLen, maxDescriptionLen)
+	}
+
+	desc := make([]byte, header.DescLen)
+	_, err = io.ReadFull(br, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &LayerSpec{}
+	err = json.NewDecoder(bytes.NewReader(desc)).Decode(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	err = readSpecTensors(br, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err := LoadLayer(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	net, ok := layer.(*FFNet)
+	if !ok {
+		return nil, fmt.Errorf("Model file contains a %s, not an FFNet", spec.Type)
+	}
+
+	return net, nil
+}
+
+//LoadLayer creates a layer from its spec using the loader registered for its type. If the loader panics (for example because a constructor gets invalid hyperparameters), the panic is returned as an error.
+func LoadLayer(spec *LayerSpec) (layer weight.Layer, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			layer = nil
+			err = fmt.Errorf("Could not create layer %s of type %s: %v", spec.ID, spec.Type, rec)
+		}
+	}()
+
+	loader, ok := layerLoaders[spec.Type]
+	if !ok {
+		return nil, fmt.Errorf("Unknown layer type %s. Use RegisterLayer to add it", spec.Type)
+	}
+
+	if len(spec.Tensors) != spec.NumTensors {
+		return nil, fmt.Errorf("Layer %s expects %d tensors but has %d", spec.ID, spec.NumTensors, len(spec.Tensors))
+	}
+
+	return loader(spec)
+}
+
+//NewLayerSpec creates a spec with the config encoded as JSON and the given tensors
+func NewLayerSpec(layerType, id string, config interface{}, tensors ...*tensor.Tensor) (*LayerSpec, error) {
+	spec := &LayerSpec{Type: layerType, ID: id, Tensors: tensors, NumTensors: len(tensors)}
+
+	if config != nil {
+		c, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		spec.Config = c
+	}
+
+	return spec, nil
+}
+
+//DecodeConfig decodes the config of the spec into v
+func (s *LayerSpec) DecodeConfig(v interface{}) error {
+	if len(s.Config) == 0 {
+		return fmt.Errorf("Layer %s has no config", s.ID)
+	}
+	return json.Unmarshal(s.Config, v)
+}
+
+//CopyTensors copies the values of the spec tensors into dst, in order. Sizes must match.
+func (s *LayerSpec) CopyTensors(dst ...*tensor.Tensor) error {
+	if len(dst) != len(s.Tensors) {
+		return fmt.Errorf("Layer %s expects %d tensors but has %d", s.ID, len(dst), len(s.Tensors))
+	}
+
+	for i := range dst {
+		if !s.Tensors[i].HasSize(dst[i].Size) {
+			return fmt.Errorf("Tensor %d of layer %s has size %v but %v was expected", i, s.ID, s.Tensors[i].Size, dst[i].Size)
+		}
+		copy(dst[i].Values, s.Tensors[i].Values)
+	}
+
+	return nil
+}
+
+//Tensors are written depth first, after the description
+func writeSpecTensors(w io.Writer, spec *LayerSpec, asFloat32 bool) error {
+	for _, t := range spec.Tensors {
+		var err error
+		if asFloat32 {
+			err = t.MarshalFloat32(w)
+		} else {
+			err = t.Marshal(w)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, n := range spec.Nodes {
+		err := writeSpecTensors(w, n.Layer, asFloat32)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readSpecTensors(r io.Reader, spec *LayerSpec) error {
+	if spec.NumTensors < 0 {
+		return fmt.Errorf("Layer %s has a negative number of tensors", spec.ID)
+	}
+
+	spec.Tensors = make([]*tensor.Tensor, spec.NumTensors)
+	for i := range spec.Tensors {
+		t, err := tensor.Unmarshal(r)
+		if err != nil {
+			return fmt.Errorf("Could not read tensor %d of layer %s: %s", i, spec.ID, err.Error())
+		}
+		spec.Tensors[i] = t
+	}
+
+	for _, n := range spec.Nodes {
+		if n.Layer == nil {
+			return fmt.Errorf("Node without layer in %s", spec.ID)
+		}
+		err := readSpecTensors(r, n.Layer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//sizeConfig is used by layers that only need their input and output size to be created
+type sizeConfig struct {
+	InputSize  []int
+	OutputSize []int
+}
@@ -0,0 +1,115 @@
+package layers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestSaveLoadNet(t *testing.T) {
+	assert := assert.New(t)
+
+	crp := NewCRPBlocks([]int{8, 8, 3}, 1, 4, 2)
+
+	net, err := NewSequentialNet(
+		crp,
+		NewLeakyReLULayer(crp.GetOutputSize()...),
+		NewReshaperLayer(crp.GetOutputSize(), []int{2 * 2 * 4}),
+		NewDenseLayer([]int{2 * 2 * 4}, []int{5}),
+		NewSigmoidLayer(5),
+		NewDenseLayer([]int{5}, []int{3}),
+		NewSoftmaxLayer(3),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := tensor.NewTensor(8, 8, 3)
+	for i := range input.Values {
+		input.Values[i] = rand.Float64()
+	}
+
+	out, err := net.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := out.Copy()
+
+	var b bytes.Buffer
+	err = SaveNet(&b, net)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadNet(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(net.ID(), loaded.ID(), "Loaded network should keep its ID")
+	assert.Equal(net.GetInputSize(), loaded.GetInputSize(), "Loaded network should have the same input size")
+	assert.Equal(net.GetOutputSize(), loaded.GetOutputSize(), "Loaded network should have the same output size")
+
+	out, err = loaded.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.InDeltaSlice(expected.Values, out.Values, 1e-12, "Loaded network should produce the same output")
+}
+
+func TestLoadNetBadFile(t *testing.T) {
+	_, err := LoadNet(bytes.NewReader([]byte("this is not a model file")))
+	if err == nil {
+		t.Errorf("Loading an invalid file should return error")
+	}
+}
+
+func TestLoadNetCorruptedFile(t *testing.T) {
+	net, err := NewSequentialNet(
+		NewPoolLayer([]int{4, 4, 1}, []int{2, 2, 1}),
+		NewReshaperLayer([]int{2, 2, 1}, []int{4}),
+		NewDenseLayer([]int{4}, []int{2}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = SaveNet(&b, net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := b.Bytes()
+
+	//Every truncation of the file should return an error
+	for n := 0; n < len(file); n++ {
+		_, err := LoadNet(bytes.NewReader(file[:n]))
+		if err == nil {
+			t.Fatalf("Loading a file truncated to %d of %d bytes should return error", n, len(file))
+		}
+	}
+
+	//A config that makes the constructor panic
+	bad := bytes.Replace(file, []byte(`"KernelSize":[2,2,1]`), []byte(`"KernelSize":[0,2,1]`), 1)
+	if bytes.Equal(bad, file) {
+		t.Fatal("The pool config was not found in the file")
+	}
+	_, err = LoadNet(bytes.NewReader(bad))
+	if err == nil {
+		t.Errorf("Loading a file with an invalid layer config should return error")
+	}
+
+	//A description length bigger than the limit
+	huge := append([]byte{}, file...)
+	binary.BigEndian.PutUint32(huge[8:12], maxDescriptionLen+1)
+	_, err = LoadNet(bytes.NewReader(huge))
+	if err == nil {
+		t.Errorf("Loading a file with a huge description should return error")
+	}
+}
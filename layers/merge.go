@@ -0,0 +1,254 @@
+package layers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+//MergeMode is the operation used by an FFNode to combine the outputs of its parents
+type MergeMode int
+
+const (
+	//SumMerge adds the outputs of the parents. It is the default.
+	SumMerge MergeMode = iota
+	//ConcatMerge concatenates the outputs of the parents along an axis, in the order the parents were given. The other dimensions must be the same.
+	ConcatMerge
+	//MultiplyMerge multiplies the outputs of the parents elementwise
+	MultiplyMerge
+	//AverageMerge computes the elementwise mean of the outputs of the parents
+	AverageMerge
+)
+
+//Merge describes how an FFNode combines the outputs of its parents into the input of its layer
+type Merge struct {
+	Mode MergeMode
+
+	//Axis is the dimension along which the outputs are concatenated with ConcatMerge
+	Axis int `json:",omitempty"`
+}
+
+//NewConcatMerge returns a Merge that concatenates the outputs of the parents along the given axis
+func NewConcatMerge(axis int) Merge {
+	return Merge{Mode: ConcatMerge, Axis: axis}
+}
+
+//String returns a short description of the merge, like "concat(1)"
+func (m Merge) String() string {
+	switch m.Mode {
+	case SumMerge:
+		return "sum"
+	case ConcatMerge:
+		return fmt.Sprintf("concat(%d)", m.Axis)
+	case MultiplyMerge:
+		return "multiply"
+	case AverageMerge:
+		return "average"
+	}
+
+	return fmt.Sprintf("MergeMode(%d)", m.Mode)
+}
+
+//Size returns the size of the merged tensor given the sizes of the outputs of the parents
+func (m Merge) Size(sizes [][]int) ([]int, error) {
+	if len(sizes) == 0 {
+		return nil, errors.New("Nothing to merge")
+	}
+
+	if m.Mode != ConcatMerge {
+		for i := 1; i < len(sizes); i++ {
+			if !sameSize(sizes[0], sizes[i]) {
+				return nil, fmt.Errorf("Cannot merge tensors of sizes %v and %v", sizes[0], sizes[i])
+			}
+		}
+
+		return sizes[0], nil
+	}
+
+	if m.Axis < 0 || m.Axis >= len(sizes[0]) {
+		return nil, fmt.Errorf("Cannot concatenate tensors of size %v along axis %d", sizes[0], m.Axis)
+	}
+
+	size := make([]int, len(sizes[0]))
+	copy(size, sizes[0])
+	size[m.Axis] = 0
+
+	for _, s := range sizes {
+		if len(s) != len(size) {
+			return nil, fmt.Errorf("Cannot concatenate tensors of sizes %v and %v", sizes[0], s)
+		}
+
+		for d := range s {
+			if d != m.Axis && s[d] != size[d] {
+				return nil, fmt.Errorf("Cannot concatenate tensors of sizes %v and %v along axis %d", sizes[0], s, m.Axis)
+			}
+		}
+
+		size[m.Axis] += s[m.Axis]
+	}
+
+	return size, nil
+}
+
+//apply merges the inputs into out, which must have the merged size
+func (m Merge) apply(out *tensor.Tensor, inputs []*tensor.Tensor) error {
+	switch m.Mode {
+	case SumMerge, AverageMerge:
+		out.Zero(0)
+		err := out.Add(inputs...)
+		if err != nil {
+			return err
+		}
+
+		if m.Mode == AverageMerge {
+			out.Mul(1 / float64(len(inputs)))
+		}
+
+	case MultiplyMerge:
+		out.Zero(1)
+		for _, in := range inputs {
+			for i, v := range in.Values {
+				out.Values[i] *= v
+			}
+		}
+
+	case ConcatMerge:
+		outer, blocks := m.blocks(tensorSizes(inputs))
+
+		off := 0
+		for o := 0; o < outer; o++ {
+			for i, in := range inputs {
+				off += copy(out.Values[off:], in.Values[o*blocks[i]:(o+1)*blocks[i]])
+			}
+		}
+
+	default:
+		return fmt.Errorf("Unknown merge mode %d", m.Mode)
+	}
+
+	return nil
+}
+
+//split computes the gradient of each of the inputs given the gradient of the merged tensor. The result is stored in grads, which must have the size of the inputs. With SumMerge all the inputs share the same gradient, so grads is not used.
+func (m Merge) split(grad *tensor.Tensor, inputs []*tensor.Tensor, grads []*tensor.Tensor) []*tensor.Tensor {
+	switch m.Mode {
+	case SumMerge:
+		for i := range grads {
+			grads[i] = grad
+		}
+
+	case AverageMerge:
+		for i := range grads {
+			copy(grads[i].Values, grad.Values)
+			grads[i].Mul(1 / float64(len(grads)))
+		}
+
+	case MultiplyMerge:
+		//The gradient of each input is the gradient times the product of all the other inputs
+		for i := range grads {
+			copy(grads[i].Values, grad.Values)
+			for j, in := range inputs {
+				if j == i {
+					continue
+				}
+				for k, v := range in.Values {
+					grads[i].Values[k] *= v
+				}
+			}
+		}
+
+	case ConcatMerge:
+		outer, blocks := m.blocks(tensorSizes(inputs))
+
+		off := 0
+		for o := 0; o < outer; o++ {
+			for i := range grads {
+				off += copy(grads[i].Values[o*blocks[i]:(o+1)*blocks[i]], grad.Values[off:])
+			}
+		}
+	}
+
+	return grads
+}
+
+//apply32 is apply for float32 tensors
+func (m Merge) apply32(out *tensor.Tensor32, inputs []*tensor.Tensor32) error {
+	switch m.Mode {
+	case SumMerge, AverageMerge, MultiplyMerge:
+		copy(out.Values, inputs[0].Values)
+		for _, in := range inputs[1:] {
+			for i, v := range in.Values {
+				if m.Mode == MultiplyMerge {
+					out.Values[i] *= v
+				} else {
+					out.Values[i] += v
+				}
+			}
+		}
+
+		if m.Mode == AverageMerge {
+			for i := range out.Values {
+				out.Values[i] /= float32(len(inputs))
+			}
+		}
+
+	case ConcatMerge:
+		sizes := make([][]int, len(inputs))
+		for i := range inputs {
+			sizes[i] = inputs[i].Size
+		}
+		outer, blocks := m.blocks(sizes)
+
+		off := 0
+		for o := 0; o < outer; o++ {
+			for i, in := range inputs {
+				off += copy(out.Values[off:], in.Values[o*blocks[i]:(o+1)*blocks[i]])
+			}
+		}
+
+	default:
+		return fmt.Errorf("Unknown merge mode %d", m.Mode)
+	}
+
+	return nil
+}
+
+//blocks returns the number of contiguous blocks that are concatenated (the product of the dimensions after the axis) and the length of the blocks of each input, given their sizes
+func (m Merge) blocks(sizes [][]int) (int, []int) {
+	size := sizes[0]
+
+	outer := 1
+	for d := m.Axis + 1; d < len(size); d++ {
+		outer *= size[d]
+	}
+
+	blocks := make([]int, len(sizes))
+	for i := range sizes {
+		blocks[i] = tensor.SizeLength(sizes[i]) / outer
+	}
+
+	return outer, blocks
+}
+
+func tensorSizes(ts []*tensor.Tensor) [][]int {
+	sizes := make([][]int, len(ts))
+	for i := range ts {
+		sizes[i] = ts[i].Size
+	}
+	return sizes
+}
+
+func sameSize(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -9,141 +9,490 @@ import (
 	"github.com/gerardabello/weight/tensor"
 )
 
+//PoolMode is the operation used to reduce each window of a PoolLayer
+type PoolMode int
+
+const (
+	//MaxPool takes the maximum value of the window
+	MaxPool PoolMode = iota
+	//AvgPool takes the mean of the window. Positions in the padding are not counted.
+	AvgPool
+)
+
+//PoolLayer reduces each window of the input to a single value. Windows have size kernelSize and are placed every stride values, so they can overlap. The input can be padded with padding values at both sides of each dimension.
 type PoolLayer struct {
 	BaseLayer
 
 	kernelSize []int
+	stride     []int
+	padding    []int
+	mode       PoolMode
+
+	windows [][]int //for each output value, the flat indexes of the input values in its window (padding excluded)
 
-	lastMax [][]int //an array of length equal to output.GetNumberOfValues(). Each value has an array of coortinates to the input position that set the max
+	lastMax  []int //for each output value, the flat index of the input value that set the max
+	batchMax []int //lastMax for each example of the last batch
 }
 
+//NewPoolLayer creates a max pooling layer with non-overlapping windows (the stride is equal to the kernel size). If the input is not divisible by the kernel, the remaining values at the end of each dimension are ignored.
 func NewPoolLayer(inputSize, kernelSize []int) *PoolLayer {
+	return NewStridedPoolLayer(inputSize, kernelSize, kernelSize, make([]int, len(kernelSize)), MaxPool)
+}
+
+//NewAvgPoolLayer creates an average pooling layer with non-overlapping windows
+func NewAvgPoolLayer(inputSize, kernelSize []int) *PoolLayer {
+	return NewStridedPoolLayer(inputSize, kernelSize, kernelSize, make([]int, len(kernelSize)), AvgPool)
+}
+
+//NewStridedPoolLayer creates a new PoolLayer. The output size in each dimension is (input + 2*padding - kernel)/stride + 1, rounded down. For example, a kernel of 3, stride 2 and padding 1 halves the size of the input rounding up.
+func NewStridedPoolLayer(inputSize, kernelSize, stride, padding []int, mode PoolMode) *PoolLayer {
 	if len(inputSize) == 0 {
 		panic("Cannot create dimensionless layer")
 	}
 
-	if len(inputSize) != len(kernelSize) {
-		panic("The kernel should have the same number of dimensions as the input")
+	if len(inputSize) != len(kernelSize) || len(inputSize) != len(stride) || len(inputSize) != len(padding) {
+		panic("The kernel, stride and padding should have the same number of dimensions as the input")
 	}
 
-	for i := 0; i < len(inputSize); i++ {
-		if inputSize[i]%kernelSize[i] != 0 {
-			fmt.Println(inputSize)
-			fmt.Println(kernelSize)
-			panic(fmt.Sprintf("Input size in dim %d is not divisible by kernel", i))
-		}
+	if mode != MaxPool && mode != AvgPool {
+		panic(fmt.Sprintf("Unknown pool mode %d", mode))
 	}
 
-	pl := &PoolLayer{kernelSize: kernelSize}
-
 	//calculate output size
 	outputSize := make([]int, len(inputSize))
 	for i := 0; i < len(outputSize); i++ {
-		//We assume the division has modulus 0 (it should be checked in the creation)
-		outputSize[i] = inputSize[i] / kernelSize[i]
+		if kernelSize[i] <= 0 || stride[i] <= 0 || padding[i] < 0 {
+			panic(fmt.Sprintf("Invalid kernel, stride or padding in dim %d", i))
+		}
+
+		//A window made only of padding would have no value to pool
+		if padding[i] >= kernelSize[i] {
+			panic(fmt.Sprintf("Padding in dim %d should be smaller than the kernel", i))
+		}
+
+		if inputSize[i]+2*padding[i] < kernelSize[i] {
+			panic(fmt.Sprintf("Kernel in dim %d is bigger than the padded input", i))
+		}
+
+		outputSize[i] = (inputSize[i]+2*padding[i]-kernelSize[i])/stride[i] + 1
+	}
+
+	pl := &PoolLayer{
+		kernelSize: kernelSize,
+		stride:     stride,
+		padding:    padding,
+		mode:       mode,
 	}
 
 	pl.BaseLayer.Init(inputSize, outputSize)
 
 	pl.id = "Pool-" + pl.id
 
-	//initialize lastMax
-	pl.lastMax = make([][]int, pl.output.GetNumberOfValues())
-	for i := 0; i < len(pl.lastMax); i++ {
-		pl.lastMax[i] = make([]int, len(pl.GetInputSize()))
-	}
+	pl.windows = poolWindows(inputSize, outputSize, kernelSize, stride, padding)
+	pl.lastMax = make([]int, pl.output.GetNumberOfValues())
 
 	return pl
 }
 
+//poolWindows returns the flat indexes of the input values in each window
+func poolWindows(inputSize, outputSize, kernelSize, stride, padding []int) [][]int {
+	dims := len(inputSize)
+
+	windows := make([][]int, tensor.SizeLength(outputSize))
+
+	outPos := make([]int, dims)
+	kPos := make([]int, dims)
+	for o := range windows {
+		//Position of the output value. The first dimension is the fastest.
+		rem := o
+		for d := 0; d < dims; d++ {
+			outPos[d] = rem % outputSize[d]
+			rem /= outputSize[d]
+		}
+
+		for k := 0; k < tensor.SizeLength(kernelSize); k++ {
+			rem := k
+			for d := 0; d < dims; d++ {
+				kPos[d] = rem % kernelSize[d]
+				rem /= kernelSize[d]
+			}
+
+			flat := 0
+			mult := 1
+			inside := true
+			for d := 0; d < dims; d++ {
+				p := outPos[d]*stride[d] - padding[d] + kPos[d]
+				if p < 0 || p >= inputSize[d] {
+					inside = false
+					break
+				}
+				flat += p * mult
+				mult *= inputSize[d]
+			}
+
+			if inside {
+				windows[o] = append(windows[o], flat)
+			}
+		}
+	}
+
+	return windows
+}
+
 func (l *PoolLayer) CreateSlave() weight.Layer {
-	nl := NewPoolLayer(l.GetInputSize(), l.kernelSize)
+	nl := NewStridedPoolLayer(l.GetInputSize(), l.kernelSize, l.stride, l.padding, l.mode)
 	nl.id = l.ID()
 
 	return nl
 }
 
-//Recursion in the wild
-//pooling divides the input matrix into an integer number of kernels (l.kernelSize), finds the maximum of every kernel and stores the maximums in l.output
-func (l *PoolLayer) pooling(input *tensor.Tensor, dim int, pos []int, outpos []int) error {
-	if input.Size[dim]%l.kernelSize[dim] != 0 {
-		return fmt.Errorf("Kernel size in dim %d is not the same as input", dim)
+type poolConfig struct {
+	InputSize  []int
+	KernelSize []int
+	Stride     []int `json:",omitempty"`
+	Padding    []int `json:",omitempty"`
+	Mode       PoolMode
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *PoolLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("Pool", l.ID(), poolConfig{l.GetInputSize(), l.kernelSize, l.stride, l.padding, l.mode})
+}
+
+func loadPoolLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := poolConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
 	}
 
-	//We use pos and outpos but we could just store pos and calculate pos/kernelSize, maybe an improvement
-	p := make([]int, len(pos))
-	op := make([]int, len(outpos))
-	copy(op, outpos)
-	copy(p, pos)
+	//Files saved before strides and padding were added only have the kernel
+	if c.Stride == nil {
+		c.Stride = c.KernelSize
+	}
+	if c.Padding == nil {
+		c.Padding = make([]int, len(c.KernelSize))
+	}
 
-	n := input.Size[dim]
-	for i := 0; i < n; i++ {
-		p[dim] = i
-		op[dim] = i / l.kernelSize[dim]
+	l := NewStridedPoolLayer(c.InputSize, c.KernelSize, c.Stride, c.Padding, c.Mode)
+	l.id = spec.ID
 
-		if dim == input.GetDims()-1 {
-			//If last dimension, calculate max and return
-			curVal := l.output.GetVal(op...)
-			inpVal := input.GetVal(p...)
-			if inpVal > curVal {
-				l.output.SetVal(inpVal, op...)
+	return l, nil
+}
 
-				//Store the index of the value that sets the max
-				flatpos := l.output.DimToFlat(op...)
+func (l *PoolLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	if !input.HasSize(l.GetInputSize()) {
+		l.mutex.Unlock()
+		return nil, errors.New("Input has wrong size")
+	}
+
+	l.lastInput = input
+
+	l.pool(l.output.Values, input.Values, l.lastMax)
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+func (l *PoolLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
 
-				copy(l.lastMax[flatpos], p)
+	l.unpool(l.propagation.Values, err.Values, l.lastMax)
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+//pool reduces each window of the inputs into out, storing in max the index of the maximum of each window with MaxPool
+func (l *PoolLayer) pool(out, inputs []float64, max []int) {
+	for o, window := range l.windows {
+		switch l.mode {
+		case MaxPool:
+			m := -math.MaxFloat64
+			maxIndex := window[0]
+			for _, i := range window {
+				if inputs[i] > m {
+					m = inputs[i]
+					maxIndex = i
+				}
 			}
-		} else {
-			err := l.pooling(input, dim+1, p, op)
-			if err != nil {
-				return err
+			out[o] = m
+			max[o] = maxIndex
+
+		case AvgPool:
+			sum := 0.0
+			for _, i := range window {
+				sum += inputs[i]
+			}
+			out[o] = sum / float64(len(window))
+		}
+	}
+}
+
+//unpool accumulates in prop the gradient of the inputs given the gradient of the outputs and the indexes stored by pool
+func (l *PoolLayer) unpool(prop, errs []float64, max []int) {
+	//Windows can overlap, so the gradients are accumulated
+	for o, window := range l.windows {
+		switch l.mode {
+		case MaxPool:
+			prop[max[o]] += errs[o]
+
+		case AvgPool:
+			g := errs[o] / float64(len(window))
+			for _, i := range window {
+				prop[i] += g
 			}
 		}
 	}
+}
 
-	return nil
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *PoolLayer) SupportsBatch() bool {
+	return true
 }
 
-func (l *PoolLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer.
+func (l *PoolLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
-	if !input.HasSize(l.GetInputSize()) {
-		return nil, errors.New("Input has wrong size")
+	n, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	ni := l.propagation.GetNumberOfValues()
+	no := l.output.GetNumberOfValues()
+
+	if len(l.batchMax) != n*no {
+		l.batchMax = make([]int, n*no)
+	}
+
+	for b := 0; b < n; b++ {
+		l.pool(l.batchOutput.Values[b*no:(b+1)*no], input.Values[b*ni:(b+1)*ni], l.batchMax[b*no:(b+1)*no])
+	}
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *PoolLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
 	}
 
-	l.output.Zero(-math.MaxFloat64)
+	ni := l.propagation.GetNumberOfValues()
+	no := l.output.GetNumberOfValues()
 
-	pos := make([]int, input.GetDims())
-	outpos := make([]int, input.GetDims())
-	err := l.pooling(input, 0, pos, outpos)
+	for b := 0; b < n; b++ {
+		l.unpool(l.batchPropagation.Values[b*ni:(b+1)*ni], err.Values[b*no:(b+1)*no], l.batchMax[b*no:(b+1)*no])
+	}
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
+
+func (l *PoolLayer) GetParamGradPointers() ([]*float64, []*float64) {
+	return []*float64{}, []*float64{}
+}
+
+//GlobalAvgPoolLayer computes the mean of each channel. The channel is the last dimension of the input, so an input [w,h,c] gives an output [c].
+type GlobalAvgPoolLayer struct {
+	BaseLayer
+
+	channels int
+	spatial  int
+}
+
+//NewGlobalAvgPoolLayer creates a new GlobalAvgPoolLayer
+func NewGlobalAvgPoolLayer(inputSize ...int) *GlobalAvgPoolLayer {
+	if len(inputSize) < 2 {
+		panic("Global pooling input should have at least 2 dimensions")
+	}
+
+	channels := inputSize[len(inputSize)-1]
+
+	layer := &GlobalAvgPoolLayer{
+		channels: channels,
+		spatial:  tensor.SizeLength(inputSize) / channels,
+	}
+	layer.BaseLayer.Init(inputSize, []int{channels})
+	layer.id = "GlobalAvgPool-" + layer.id
+
+	return layer
+}
+
+func (l *GlobalAvgPoolLayer) CreateSlave() weight.Layer {
+	nl := NewGlobalAvgPoolLayer(l.GetInputSize()...)
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *GlobalAvgPoolLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("GlobalAvgPool", l.ID(), sizeConfig{InputSize: l.GetInputSize()})
+}
+
+func loadGlobalAvgPoolLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := sizeConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewGlobalAvgPoolLayer(c.InputSize...)
+	l.id = spec.ID
+
+	return l, nil
+}
+
+func (l *GlobalAvgPoolLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
 	if err != nil {
+		l.mutex.Unlock()
 		return nil, err
 	}
 
+	for c := 0; c < l.channels; c++ {
+		sum := 0.0
+		for _, v := range input.Values[c*l.spatial : (c+1)*l.spatial] {
+			sum += v
+		}
+		l.output.Values[c] = sum / float64(l.spatial)
+	}
+
 	l.mutex.Unlock()
 	return &l.output, nil
 }
 
-func (l *PoolLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+func (l *GlobalAvgPoolLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	e := l.BaseLayer.BackPropagate(err)
 	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	for c := 0; c < l.channels; c++ {
+		g := err.Values[c] / float64(l.spatial)
+		prop := l.propagation.Values[c*l.spatial : (c+1)*l.spatial]
+		for i := range prop {
+			prop[i] = g
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *GlobalAvgPoolLayer) SupportsBatch() bool {
+	return true
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer.
+func (l *GlobalAvgPoolLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	for i := 0; i < n*l.channels; i++ {
+		sum := 0.0
+		for _, v := range input.Values[i*l.spatial : (i+1)*l.spatial] {
+			sum += v
+		}
+		l.batchOutput.Values[i] = sum / float64(l.spatial)
+	}
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *GlobalAvgPoolLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	n, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
 		return nil, e
 	}
 
-	errs := err.Values
+	for i := 0; i < n*l.channels; i++ {
+		g := err.Values[i] / float64(l.spatial)
+		prop := l.batchPropagation.Values[i*l.spatial : (i+1)*l.spatial]
+		for j := range prop {
+			prop[j] = g
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
 
-	for i := 0; i < len(l.propagation.Values); i++ {
-		l.propagation.Values[i] = 0
+//Activate32 is like Activate for a float32 input. See weight.Float32Layer.
+func (l *PoolLayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
 	}
 
-	for i := 0; i < len(errs); i++ {
-		l.propagation.SetVal(errs[i], l.lastMax[i]...)
+	for o, window := range l.windows {
+		switch l.mode {
+		case MaxPool:
+			m := input.Values[window[0]]
+			for _, i := range window {
+				if input.Values[i] > m {
+					m = input.Values[i]
+				}
+			}
+			l.output32.Values[o] = m
+
+		case AvgPool:
+			sum := float32(0)
+			for _, i := range window {
+				sum += input.Values[i]
+			}
+			l.output32.Values[o] = sum / float32(len(window))
+		}
 	}
 
 	l.mutex.Unlock()
-	return &l.propagation, nil
+	return &l.output32, nil
 }
 
-func (l *PoolLayer) GetParamGradPointers() ([]*float64, []*float64) {
-	return []*float64{}, []*float64{}
+//Activate32 is like Activate for a float32 input. See weight.Float32Layer.
+func (l *GlobalAvgPoolLayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	for c := 0; c < l.channels; c++ {
+		sum := float32(0)
+		for _, v := range input.Values[c*l.spatial : (c+1)*l.spatial] {
+			sum += v
+		}
+		l.output32.Values[c] = sum / float32(l.spatial)
+	}
+
+	l.mutex.Unlock()
+	return &l.output32, nil
 }
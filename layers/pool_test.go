@@ -118,3 +118,91 @@ func TestPoolPropagation(t *testing.T) {
 		bpout.Values, 1e-3, "Expected backpropagation of neurons")
 
 }
+
+func TestAvgPoolActivation(t *testing.T) {
+
+	assert := assert.New(t)
+
+	layer := NewAvgPoolLayer([]int{4, 2}, []int{2, 2})
+
+	data := &tensor.Tensor{Size: []int{4, 2},
+		Values: []float64{
+			1, 2, 3, 4,
+			5, 6, 7, 8,
+		}}
+
+	out, err := layer.Activate(data)
+	if err != nil {
+		t.Fatalf("Error while activating layer: %s", err.Error())
+	}
+
+	assert.InDeltaSlice([]float64{3.5, 5.5}, out.Values, 1e-12, "Expected activation of neurons")
+
+	bpout, err := layer.BackPropagate(&tensor.Tensor{Size: []int{2, 1}, Values: []float64{4, 8}})
+	if err != nil {
+		t.Fatalf("Error while backpropagating layer: %s", err.Error())
+	}
+
+	assert.InDeltaSlice([]float64{1, 1, 2, 2, 1, 1, 2, 2}, bpout.Values, 1e-12, "Expected backpropagation of neurons")
+}
+
+func TestStridedPool(t *testing.T) {
+
+	assert := assert.New(t)
+
+	//3x3 kernel, stride 2 and padding 1 on an odd input
+	layer := NewStridedPoolLayer([]int{5, 5, 1}, []int{3, 3, 1}, []int{2, 2, 1}, []int{1, 1, 0}, MaxPool)
+
+	assert.Equal([]int{3, 3, 1}, layer.GetOutputSize(), "Output size should be rounded up half of the input")
+
+	data := &tensor.Tensor{Size: []int{5, 5, 1}, Values: make([]float64, 25)}
+	for i := range data.Values {
+		data.Values[i] = float64(i)
+	}
+
+	out, err := layer.Activate(data)
+	if err != nil {
+		t.Fatalf("Error while activating layer: %s", err.Error())
+	}
+
+	assert.InDeltaSlice([]float64{6, 8, 9, 16, 18, 19, 21, 23, 24}, out.Values, 1e-12, "Expected activation of neurons")
+
+	//The value at (1,1) is in the four windows of the top left corner, so its gradient is accumulated
+	data.Values[6] = 100
+	_, err = layer.Activate(data)
+	if err != nil {
+		t.Fatalf("Error while activating layer: %s", err.Error())
+	}
+
+	errGrad := &tensor.Tensor{Size: []int{3, 3, 1}, Values: []float64{1, 1, 1, 1, 1, 1, 1, 1, 1}}
+	bpout, err := layer.BackPropagate(errGrad)
+	if err != nil {
+		t.Fatalf("Error while backpropagating layer: %s", err.Error())
+	}
+
+	assert.InDelta(4, bpout.Values[6], 1e-12, "Gradient of overlapping windows should be accumulated")
+}
+
+func TestGlobalAvgPool(t *testing.T) {
+
+	assert := assert.New(t)
+
+	layer := NewGlobalAvgPoolLayer(2, 2, 2)
+
+	data := &tensor.Tensor{Size: []int{2, 2, 2}, Values: []float64{1, 2, 3, 4, 10, 20, 30, 40}}
+
+	out, err := layer.Activate(data)
+	if err != nil {
+		t.Fatalf("Error while activating layer: %s", err.Error())
+	}
+
+	assert.Equal([]int{2}, out.Size)
+	assert.InDeltaSlice([]float64{2.5, 25}, out.Values, 1e-12, "Expected activation of neurons")
+
+	bpout, err := layer.BackPropagate(&tensor.Tensor{Size: []int{2}, Values: []float64{4, 8}})
+	if err != nil {
+		t.Fatalf("Error while backpropagating layer: %s", err.Error())
+	}
+
+	assert.InDeltaSlice([]float64{1, 1, 1, 1, 2, 2, 2, 2}, bpout.Values, 1e-12, "Expected backpropagation of neurons")
+}
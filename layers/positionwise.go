@@ -0,0 +1,135 @@
+package layers
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas64"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//PositionwiseDenseLayer applies the same dense layer to each timestep of a sequence [features, timesteps], returning a sequence [outFeatures, timesteps]. The parameters are stored like in a DenseLayer with input [features] and output [outFeatures].
+type PositionwiseDenseLayer struct {
+	BaseLayer
+
+	features, outFeatures, timesteps int
+}
+
+type positionwiseDenseConfig struct {
+	Features, OutFeatures, Timesteps int
+}
+
+//NewPositionwiseDenseLayer creates a new PositionwiseDenseLayer
+func NewPositionwiseDenseLayer(features, outFeatures, timesteps int) *PositionwiseDenseLayer {
+	l := &PositionwiseDenseLayer{}
+	l.BaseLayer.Init([]int{features, timesteps}, []int{outFeatures, timesteps})
+	l.id = "PositionwiseDense-" + l.id
+
+	l.features = features
+	l.outFeatures = outFeatures
+	l.timesteps = timesteps
+
+	l.weights = tensor.NewTensor(features, outFeatures)
+	l.weightsGrad = tensor.NewTensor(features, outFeatures)
+
+	//initialize for relus
+	stdev := math.Sqrt(2.0 / float64(features))
+	for i := range l.weights.Values {
+		l.weights.Values[i] = rand.NormFloat64() * stdev
+	}
+
+	l.bias = tensor.NewTensor(outFeatures)
+	l.biasGrad = tensor.NewTensor(outFeatures)
+
+	return l
+}
+
+//CreateSlave creates a slave of the PositionwiseDenseLayer. See EnslaverLayer in package weight for more information on layer slaves.
+func (l *PositionwiseDenseLayer) CreateSlave() weight.Layer {
+	nl := NewPositionwiseDenseLayer(l.features, l.outFeatures, l.timesteps)
+
+	nl.weights = l.weights
+	nl.bias = l.bias
+
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *PositionwiseDenseLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("PositionwiseDense", l.ID(), positionwiseDenseConfig{l.features, l.outFeatures, l.timesteps}, l.weights, l.bias)
+}
+
+func loadPositionwiseDenseLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := positionwiseDenseConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewPositionwiseDenseLayer(c.Features, c.OutFeatures, c.Timesteps)
+	l.id = spec.ID
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+//MACs returns the number of multiply-adds needed to activate one example. See MACLayer.
+func (l *PositionwiseDenseLayer) MACs() int {
+	return l.timesteps * len(l.weights.Values)
+}
+
+//matrices returns the input, output and weights as matrices with a row for each timestep (or output feature in the weights)
+func (l *PositionwiseDenseLayer) matrices(in, out, w []float64) (blas64.General, blas64.General, blas64.General) {
+	return blas64.General{Rows: l.timesteps, Cols: l.features, Stride: l.features, Data: in},
+		blas64.General{Rows: l.timesteps, Cols: l.outFeatures, Stride: l.outFeatures, Data: out},
+		blas64.General{Rows: l.outFeatures, Cols: l.features, Stride: l.features, Data: w}
+}
+
+func (l *PositionwiseDenseLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	in, out, w := l.matrices(input.Values, l.output.Values, l.weights.Values)
+	blas64.Gemm(blas.NoTrans, blas.Trans, 1, in, w, 0, out)
+
+	for t := 0; t < l.timesteps; t++ {
+		row := l.output.Values[t*l.outFeatures : (t+1)*l.outFeatures]
+		for i := range row {
+			row[i] += l.bias.Values[i]
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+func (l *PositionwiseDenseLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	in, errs, w := l.matrices(l.lastInput.Values, err.Values, l.weights.Values)
+	_, _, wg := l.matrices(nil, nil, l.weightsGrad.Values)
+	prop, _, _ := l.matrices(l.propagation.Values, nil, nil)
+
+	blas64.Gemm(blas.Trans, blas.NoTrans, 1, errs, in, 1, wg)
+	blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, errs, w, 0, prop)
+
+	for i, v := range err.Values {
+		l.biasGrad.Values[i%l.outFeatures] += v
+	}
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
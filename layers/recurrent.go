@@ -0,0 +1,211 @@
+package layers
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+//recurrentLayer has the common parts of the recurrent layers. They take a sequence [features, timesteps], so each timestep is contiguous in memory, and run a cell once per timestep. The output is the hidden state of the last timestep [hidden], or the hidden states of all timesteps [hidden, timesteps] if returnSequences is true.
+//
+//The parameters are stored like in a DenseLayer with input [features+hidden] and output [gates*hidden]: each gate of each hidden unit has a row of weights, first for the input of the timestep and then for the previous hidden state.
+type recurrentLayer struct {
+	BaseLayer
+
+	features, hidden, timesteps, gates int
+
+	returnSequences bool
+
+	//Truncation is the number of timesteps the gradient flows back through the hidden state. The sequence is split in chunks of Truncation timesteps and the gradient of the hidden state is cut at their boundaries (the error of every timestep is still used). 0 means full backpropagation through time.
+	Truncation int
+
+	//Values of each timestep stored in the forward pass: concatenated input and previous hidden state, gate activations and hidden states (the first one is the initial state, always 0)
+	z   []float64
+	act []float64
+	h   []float64
+
+	//Temp slices for the backward pass
+	dz []float64
+	da []float64
+	dh []float64
+}
+
+type recurrentConfig struct {
+	Features, Hidden, Timesteps int
+	ReturnSequences            bool
+	Truncation                 int
+}
+
+func (l *recurrentLayer) init(features, hidden, timesteps, gates int, returnSequences bool) {
+	if features <= 0 || hidden <= 0 || timesteps <= 0 {
+		panic("Recurrent layer sizes must be bigger than 0")
+	}
+
+	outputSize := []int{hidden}
+	if returnSequences {
+		outputSize = []int{hidden, timesteps}
+	}
+
+	l.BaseLayer.Init([]int{features, timesteps}, outputSize)
+
+	l.features = features
+	l.hidden = hidden
+	l.timesteps = timesteps
+	l.gates = gates
+	l.returnSequences = returnSequences
+
+	ni := features + hidden
+	l.weights = tensor.NewTensor(ni, gates*hidden)
+	l.weightsGrad = tensor.NewTensor(ni, gates*hidden)
+
+	//The gates use tanh and sigmoid, so scale the weights for a unit variance with ni inputs
+	stdev := math.Sqrt(1.0 / float64(ni))
+	for i := range l.weights.Values {
+		l.weights.Values[i] = rand.NormFloat64() * stdev
+	}
+
+	l.bias = tensor.NewTensor(gates * hidden)
+	l.biasGrad = tensor.NewTensor(gates * hidden)
+
+	l.z = make([]float64, timesteps*ni)
+	l.act = make([]float64, timesteps*gates*hidden)
+	l.h = make([]float64, (timesteps+1)*hidden)
+
+	l.dz = make([]float64, ni)
+	l.da = make([]float64, gates*hidden)
+	l.dh = make([]float64, hidden)
+}
+
+//MACs returns the number of multiply-adds needed to activate one example. See MACLayer.
+func (l *recurrentLayer) MACs() int {
+	return l.timesteps * len(l.weights.Values)
+}
+
+func (l *recurrentLayer) config() recurrentConfig {
+	return recurrentConfig{l.features, l.hidden, l.timesteps, l.returnSequences, l.Truncation}
+}
+
+//affine computes the weighted sum plus bias of z for the rows [from, to) and stores it in out
+func (l *recurrentLayer) affine(from, to int, z, out []float64) {
+	ni := len(z)
+	for r := from; r < to; r++ {
+		w := l.weights.Values[r*ni : (r+1)*ni]
+		a := l.bias.Values[r]
+		for j, v := range z {
+			a += v * w[j]
+		}
+		out[r-from] = a
+	}
+}
+
+//affineBack accumulates the gradients of the rows [from, to) given the gradient da of their sums, and adds the gradient of z to dz
+func (l *recurrentLayer) affineBack(from, to int, z, da, dz []float64) {
+	ni := len(z)
+	for r := from; r < to; r++ {
+		e := da[r-from]
+		if e == 0 {
+			continue
+		}
+		l.biasGrad.Values[r] += e
+
+		w := l.weights.Values[r*ni : (r+1)*ni]
+		wg := l.weightsGrad.Values[r*ni : (r+1)*ni]
+		for j, v := range z {
+			wg[j] += e * v
+			dz[j] += e * w[j]
+		}
+	}
+}
+
+//timestep returns the slices of the stored values of timestep t
+func (l *recurrentLayer) timestep(t int) (z, act, hPrev, h []float64) {
+	ni := l.features + l.hidden
+	ng := l.gates * l.hidden
+	return l.z[t*ni : (t+1)*ni], l.act[t*ng : (t+1)*ng], l.h[t*l.hidden : (t+1)*l.hidden], l.h[(t+1)*l.hidden : (t+2)*l.hidden]
+}
+
+//activate runs step for every timestep, after copying the input and the previous hidden state into z
+func (l *recurrentLayer) activate(input *tensor.Tensor, step func(t int)) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	for t := 0; t < l.timesteps; t++ {
+		z, _, hPrev, _ := l.timestep(t)
+		copy(z, input.Values[t*l.features:(t+1)*l.features])
+		copy(z[l.features:], hPrev)
+
+		step(t)
+	}
+
+	if l.returnSequences {
+		copy(l.output.Values, l.h[l.hidden:])
+	} else {
+		copy(l.output.Values, l.h[l.timesteps*l.hidden:])
+	}
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+//backPropagate runs stepBack from the last timestep to the first. stepBack receives the gradient of the hidden state in l.dh and must leave in l.dz the gradient of z. cut is called when the gradient of the state must not flow to the previous timestep.
+func (l *recurrentLayer) backPropagate(err *tensor.Tensor, stepBack func(t int), cut func()) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	if !err.HasSize(l.GetOutputSize()) {
+		l.mutex.Unlock()
+		return nil, fmt.Errorf("Error gradient has size %v but layer output is %v", err.Size, l.GetOutputSize())
+	}
+
+	for i := range l.dh {
+		l.dh[i] = 0
+	}
+	cut()
+
+	for t := l.timesteps - 1; t >= 0; t-- {
+		//Error of the output of this timestep
+		if l.returnSequences {
+			for i, v := range err.Values[t*l.hidden : (t+1)*l.hidden] {
+				l.dh[i] += v
+			}
+		} else if t == l.timesteps-1 {
+			for i, v := range err.Values {
+				l.dh[i] += v
+			}
+		}
+
+		for i := range l.dz {
+			l.dz[i] = 0
+		}
+
+		stepBack(t)
+
+		copy(l.propagation.Values[t*l.features:(t+1)*l.features], l.dz[:l.features])
+
+		//Gradient of the previous hidden state
+		copy(l.dh, l.dz[l.features:])
+		if l.Truncation > 0 && t%l.Truncation == 0 {
+			for i := range l.dh {
+				l.dh[i] = 0
+			}
+			cut()
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
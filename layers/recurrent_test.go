@@ -0,0 +1,71 @@
+package layers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestRNNActivation(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewRNNLayer(1, 1, 3, true)
+
+	assert.Equal([]int{1, 3}, layer.GetInputSize())
+	assert.Equal([]int{1, 3}, layer.GetOutputSize())
+
+	//Input weight, recurrent weight
+	layer.weights.Values = []float64{0.5, 2}
+	layer.bias.Values = []float64{0.1}
+
+	out, err := layer.Activate(&tensor.Tensor{Size: []int{1, 3}, Values: []float64{1, -1, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h0 := math.Tanh(0.5 + 0.1)
+	h1 := math.Tanh(-0.5 + 2*h0 + 0.1)
+	h2 := math.Tanh(2*h1 + 0.1)
+
+	assert.InDeltaSlice([]float64{h0, h1, h2}, out.Values, 1e-12)
+}
+
+func TestRecurrentGradients(t *testing.T) {
+	checkGradients(t, NewRNNLayer(3, 4, 5, true), randomTensor(3, 5))
+	checkGradients(t, NewRNNLayer(3, 4, 5, false), randomTensor(3, 5))
+
+	checkGradients(t, NewLSTMLayer(3, 4, 5, true), randomTensor(3, 5))
+	checkGradients(t, NewLSTMLayer(2, 3, 4, false), randomTensor(2, 4))
+
+	checkGradients(t, NewGRULayer(3, 4, 5, true), randomTensor(3, 5))
+	checkGradients(t, NewGRULayer(2, 3, 4, false), randomTensor(2, 4))
+}
+
+func TestRecurrentTruncation(t *testing.T) {
+	layer := NewLSTMLayer(2, 3, 6, false)
+	layer.Truncation = 2
+
+	input := randomTensor(2, 6)
+	_, err := layer.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grad := randomTensor(3)
+	prop, err := layer.BackPropagate(grad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Only the last output has error, so with a truncation of 2 only the last 2 timesteps receive gradient
+	for i, v := range prop.Values {
+		if i < 4*2 {
+			assert.Equal(t, 0.0, v, "Input %d should not receive gradient", i)
+		} else {
+			assert.NotEqual(t, 0.0, v, "Input %d should receive gradient", i)
+		}
+	}
+}
@@ -25,19 +25,33 @@ func (l *ReLULayer) CreateSlave() weight.Layer {
 	return nl
 }
 
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *ReLULayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("ReLU", l.ID(), sizeConfig{InputSize: l.GetInputSize()})
+}
+
+func loadReLULayer(spec *LayerSpec) (weight.Layer, error) {
+	c := sizeConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewReLULayer(c.InputSize...)
+	l.id = spec.ID
+
+	return l, nil
+}
+
 func (l *ReLULayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	err := l.BaseLayer.Activate(input)
 	if err != nil {
+		l.mutex.Unlock()
 		return nil, err
 	}
 
-	//New a slice with length equal to the number of neurons
-	inputs := input.Values
-
-	for i := range l.output.Values {
-		l.output.Values[i] = math.Max(0, inputs[i])
-	}
+	relu(l.output.Values, input.Values)
 
 	l.mutex.Unlock()
 	return &l.output, nil
@@ -47,20 +61,84 @@ func (l *ReLULayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	e := l.BaseLayer.BackPropagate(err)
 	if e != nil {
+		l.mutex.Unlock()
 		return nil, e
 	}
 
-	errs := err.Values
-	inputs := l.lastInput.Values
+	reluBack(l.propagation.Values, err.Values, l.lastInput.Values)
 
-	for i := range l.propagation.Values {
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *ReLULayer) SupportsBatch() bool {
+	return true
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer.
+func (l *ReLULayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	relu(l.batchOutput.Values, input.Values)
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *ReLULayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	reluBack(l.batchPropagation.Values, err.Values, l.lastBatch.Values)
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
+
+func relu(out, inputs []float64) {
+	for i := range out {
+		out[i] = math.Max(0, inputs[i])
+	}
+}
+
+func reluBack(prop, errs, inputs []float64) {
+	for i := range prop {
 		if inputs[i] > 0 {
-			l.propagation.Values[i] = errs[i]
+			prop[i] = errs[i]
 		} else {
-			l.propagation.Values[i] = 0
+			prop[i] = 0
 		}
+	}
+}
+
+//Activate32 is like Activate for a float32 input. See weight.Float32Layer.
+func (l *ReLULayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
 
+	for i, v := range input.Values {
+		if v > 0 {
+			l.output32.Values[i] = v
+		} else {
+			l.output32.Values[i] = 0
+		}
 	}
+
 	l.mutex.Unlock()
-	return &l.propagation, nil
+	return &l.output32, nil
 }
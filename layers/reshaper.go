@@ -31,6 +31,24 @@ func (l *ReshaperLayer) CreateSlave() weight.Layer {
 	return nl
 }
 
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *ReshaperLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("Reshaper", l.ID(), sizeConfig{l.GetInputSize(), l.GetOutputSize()})
+}
+
+func loadReshaperLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := sizeConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewReshaperLayer(c.InputSize, c.OutputSize)
+	l.id = spec.ID
+
+	return l, nil
+}
+
 func (l *ReshaperLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	l.output.Values = input.Values
@@ -53,3 +71,53 @@ func (l *ReshaperLayer) GetDebugInfo() []map[string]interface{} {
 	return []map[string]interface{}{}
 }
 */
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *ReshaperLayer) SupportsBatch() bool {
+	return true
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer.
+func (l *ReshaperLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	copy(l.batchOutput.Values, input.Values)
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *ReshaperLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	copy(l.batchPropagation.Values, err.Values)
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
+
+//Activate32 is like Activate for a float32 input. See weight.Float32Layer.
+func (l *ReshaperLayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	copy(l.output32.Values, input.Values)
+
+	l.mutex.Unlock()
+	return &l.output32, nil
+}
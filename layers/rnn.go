@@ -0,0 +1,81 @@
+package layers
+
+import (
+	"math"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//RNNLayer is a simple (Elman) recurrent layer: h_t = tanh(W x_t + U h_t-1 + b). It takes a sequence [features, timesteps] and returns the last hidden state [hidden], or all of them [hidden, timesteps] if returnSequences is true.
+type RNNLayer struct {
+	recurrentLayer
+}
+
+//NewRNNLayer creates a new RNNLayer
+func NewRNNLayer(features, hidden, timesteps int, returnSequences bool) *RNNLayer {
+	l := &RNNLayer{}
+	l.init(features, hidden, timesteps, 1, returnSequences)
+	l.id = "RNN-" + l.id
+
+	return l
+}
+
+//CreateSlave creates a slave of the RNNLayer. See EnslaverLayer in package weight for more information on layer slaves.
+func (l *RNNLayer) CreateSlave() weight.Layer {
+	nl := NewRNNLayer(l.features, l.hidden, l.timesteps, l.returnSequences)
+	nl.Truncation = l.Truncation
+
+	nl.weights = l.weights
+	nl.bias = l.bias
+
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *RNNLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("RNN", l.ID(), l.config(), l.weights, l.bias)
+}
+
+func loadRNNLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := recurrentConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewRNNLayer(c.Features, c.Hidden, c.Timesteps, c.ReturnSequences)
+	l.Truncation = c.Truncation
+	l.id = spec.ID
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+func (l *RNNLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return l.activate(input, l.step)
+}
+
+func (l *RNNLayer) step(t int) {
+	z, _, _, h := l.timestep(t)
+
+	l.affine(0, l.hidden, z, h)
+	for i := range h {
+		h[i] = math.Tanh(h[i])
+	}
+}
+
+func (l *RNNLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	return l.backPropagate(err, l.stepBack, func() {})
+}
+
+func (l *RNNLayer) stepBack(t int) {
+	z, _, _, h := l.timestep(t)
+
+	for i := range l.da {
+		l.da[i] = l.dh[i] * (1 - h[i]*h[i])
+	}
+
+	l.affineBack(0, l.hidden, z, l.da, l.dz)
+}
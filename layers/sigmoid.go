@@ -24,18 +24,33 @@ func (l *SigmoidLayer) CreateSlave() weight.Layer {
 	return nl
 }
 
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *SigmoidLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("Sigmoid", l.ID(), sizeConfig{InputSize: l.GetInputSize()})
+}
+
+func loadSigmoidLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := sizeConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewSigmoidLayer(c.InputSize...)
+	l.id = spec.ID
+
+	return l, nil
+}
+
 func (l *SigmoidLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	err := l.BaseLayer.Activate(input)
 	if err != nil {
+		l.mutex.Unlock()
 		return nil, err
 	}
 
-	inputs := input.Values
-
-	for i := range l.output.Values {
-		l.output.Values[i] = 1 / (1 + math.Exp(-inputs[i]))
-	}
+	sigmoidAll(l.output.Values, input.Values)
 
 	l.mutex.Unlock()
 	return &l.output, nil
@@ -45,16 +60,76 @@ func (l *SigmoidLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error)
 	l.mutex.Lock()
 	e := l.BaseLayer.BackPropagate(err)
 	if e != nil {
+		l.mutex.Unlock()
 		return nil, e
 	}
 
-	errs := err.Values
-	inputs := l.lastInput.Values
+	sigmoidBack(l.propagation.Values, err.Values, l.lastInput.Values)
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
 
-	for i := range l.propagation.Values {
-		l.propagation.Values[i] = math.Exp(-inputs[i]) / math.Pow(1+math.Exp(-inputs[i]), 2) * errs[i]
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *SigmoidLayer) SupportsBatch() bool {
+	return true
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer.
+func (l *SigmoidLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
 	}
 
+	sigmoidAll(l.batchOutput.Values, input.Values)
+
 	l.mutex.Unlock()
-	return &l.propagation, nil
+	return &l.batchOutput, nil
+}
+
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *SigmoidLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	sigmoidBack(l.batchPropagation.Values, err.Values, l.lastBatch.Values)
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
+
+func sigmoidAll(out, inputs []float64) {
+	for i := range out {
+		out[i] = 1 / (1 + math.Exp(-inputs[i]))
+	}
+}
+
+func sigmoidBack(prop, errs, inputs []float64) {
+	for i := range prop {
+		prop[i] = math.Exp(-inputs[i]) / math.Pow(1+math.Exp(-inputs[i]), 2) * errs[i]
+	}
+}
+
+//Activate32 is like Activate for a float32 input. See weight.Float32Layer.
+func (l *SigmoidLayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	for i, v := range input.Values {
+		l.output32.Values[i] = float32(1 / (1 + math.Exp(-float64(v))))
+	}
+
+	l.mutex.Unlock()
+	return &l.output32, nil
 }
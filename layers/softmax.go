@@ -24,10 +24,29 @@ func (l *SoftmaxLayer) CreateSlave() weight.Layer {
 	return nl
 }
 
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *SoftmaxLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("Softmax", l.ID(), sizeConfig{InputSize: l.GetInputSize()})
+}
+
+func loadSoftmaxLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := sizeConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewSoftmaxLayer(c.InputSize...)
+	l.id = spec.ID
+
+	return l, nil
+}
+
 func (l *SoftmaxLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
 	l.mutex.Lock()
 	err := l.BaseLayer.Activate(input)
 	if err != nil {
+		l.mutex.Unlock()
 		return nil, err
 	}
 
@@ -96,22 +115,97 @@ func (l *SoftmaxLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error)
 	l.mutex.Lock()
 	e := l.BaseLayer.BackPropagate(err)
 	if e != nil {
+		l.mutex.Unlock()
 		return nil, e
 	}
 
-	errs := err.Values
-	outs := l.output.Values
+	softmaxBack(l.propagation.Values, err.Values, l.output.Values)
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+//SupportsBatch returns true, see weight.BatchLayer
+func (l *SoftmaxLayer) SupportsBatch() bool {
+	return true
+}
+
+//ActivateBatch is like Activate for a batch of examples. See weight.BatchLayer.
+func (l *SoftmaxLayer) ActivateBatch(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, err := l.BaseLayer.ActivateBatch(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	n := l.output.GetNumberOfValues()
+	for i := 0; i < len(input.Values); i += n {
+		copy(l.batchOutput.Values[i:i+n], SoftMaxLog(input.Values[i:i+n]))
+	}
+
+	l.mutex.Unlock()
+	return &l.batchOutput, nil
+}
 
-	for i := range l.propagation.Values {
-		for j := range l.propagation.Values {
+//BackPropagateBatch is like BackPropagate for a batch of examples. See weight.BatchLayer.
+func (l *SoftmaxLayer) BackPropagateBatch(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	_, e := l.BaseLayer.BackPropagateBatch(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	n := l.output.GetNumberOfValues()
+	for i := 0; i < len(err.Values); i += n {
+		softmaxBack(l.batchPropagation.Values[i:i+n], err.Values[i:i+n], l.batchOutput.Values[i:i+n])
+	}
+
+	l.mutex.Unlock()
+	return &l.batchPropagation, nil
+}
+
+//softmaxBack accumulates in prop the gradient of the softmax given the gradient of its outputs
+func softmaxBack(prop, errs, outs []float64) {
+	for i := range prop {
+		for j := range prop {
 			if i == j {
-				l.propagation.Values[i] += outs[i] * (1 - outs[i]) * errs[j]
+				prop[i] += outs[i] * (1 - outs[i]) * errs[j]
 			} else {
-				l.propagation.Values[i] += -outs[i] * outs[j] * errs[j]
+				prop[i] += -outs[i] * outs[j] * errs[j]
 			}
 		}
 	}
+}
+
+//Activate32 is like Activate for a float32 input. See weight.Float32Layer.
+func (l *SoftmaxLayer) Activate32(input *tensor.Tensor32) (*tensor.Tensor32, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate32(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	max := input.Values[0]
+	for _, v := range input.Values {
+		if v > max {
+			max = v
+		}
+	}
+
+	z := 0.0
+	for i, v := range input.Values {
+		e := math.Exp(float64(v - max))
+		l.output32.Values[i] = float32(e)
+		z += e
+	}
+
+	for i := range l.output32.Values {
+		l.output32.Values[i] /= float32(z)
+	}
 
 	l.mutex.Unlock()
-	return &l.propagation, nil
+	return &l.output32, nil
 }
@@ -0,0 +1,140 @@
+package layers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//MACLayer is a layer that can estimate the number of multiply-adds it needs to activate one example. Layers that do not implement it (activations, pooling, normalization...) count as 0 in a Summary, as they are cheap compared to the ones that do.
+type MACLayer interface {
+	weight.Layer
+
+	MACs() int
+}
+
+//LayerSummary describes one layer in a Summary
+type LayerSummary struct {
+	ID   string
+	Type string
+
+	//Depth is the number of FFNets the layer is inside of, 0 for the summarized layer itself
+	Depth int
+
+	InputSize  []int
+	OutputSize []int
+
+	//Params is the number of trainable parameters
+	Params int
+
+	//Memory is the size in bytes of the output of the layer for one example
+	Memory int
+
+	//MACs is the estimated number of multiply-adds to activate one example. See MACLayer.
+	MACs int
+}
+
+//Summary describes all the layers of a network, with the totals of their parameters, memory and multiply-adds. Print it to get a table.
+type Summary struct {
+	Layers []LayerSummary
+
+	Params int
+	Memory int
+	MACs   int
+}
+
+//Summarize returns the summary of a layer. FFNets are expanded, so there is a row for each layer inside them (recursively).
+func Summarize(layer weight.Layer) *Summary {
+	s := &Summary{}
+	s.add(layer, 0)
+	return s
+}
+
+//Summary returns the summary of the network. See Summarize.
+func (n *FFNet) Summary() *Summary {
+	return Summarize(n)
+}
+
+func (s *Summary) add(layer weight.Layer, depth int) {
+	if net, ok := layer.(*FFNet); ok {
+		for _, node := range net.nodes {
+			s.add(node.layer, depth+1)
+		}
+		return
+	}
+
+	ls := LayerSummary{
+		ID:         layer.ID(),
+		Type:       layerType(layer),
+		Depth:      depth,
+		InputSize:  layer.GetInputSize(),
+		OutputSize: layer.GetOutputSize(),
+		Params:     paramCount(layer),
+		Memory:     tensor.SizeLength(layer.GetOutputSize()) * 8,
+	}
+
+	if ml, ok := layer.(MACLayer); ok {
+		ls.MACs = ml.MACs()
+	}
+
+	s.Layers = append(s.Layers, ls)
+
+	s.Params += ls.Params
+	s.Memory += ls.Memory
+	s.MACs += ls.MACs
+}
+
+//Write prints the summary as a table with a row for each layer and the totals
+func (s *Summary) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "Layer\tType\tInput\tOutput\tParams\tMemory\tMACs\t")
+
+	for _, l := range s.Layers {
+		indent := ""
+		if l.Depth > 1 {
+			indent = strings.Repeat("  ", l.Depth-1)
+		}
+
+		fmt.Fprintf(tw, "%s%s\t%s\t%v\t%v\t%d\t%s\t%s\t\n", indent, l.ID, l.Type, l.InputSize, l.OutputSize, l.Params, formatBytes(l.Memory), formatCount(l.MACs))
+	}
+
+	fmt.Fprintf(tw, "Total\t\t\t\t%d\t%s\t%s\t\n", s.Params, formatBytes(s.Memory), formatCount(s.MACs))
+
+	return tw.Flush()
+}
+
+func (s *Summary) String() string {
+	var b bytes.Buffer
+	s.Write(&b)
+	return b.String()
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+func formatCount(n int) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.2fG", float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.2fM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.2fK", float64(n)/1e3)
+	}
+	return fmt.Sprintf("%d", n)
+}
@@ -0,0 +1,50 @@
+package layers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	conv := NewConvolutionalLayer(8, 8, 3, 4, 1, 1, 1, 1, 1, 1)
+	block, err := NewSequentialNet(
+		NewReLULayer(8, 8, 4),
+		NewPoolLayer([]int{8, 8, 4}, []int{2, 2, 1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dense := NewDenseLayer([]int{4, 4, 4}, []int{10})
+
+	net, err := NewSequentialNet(conv, block, dense, NewSoftmaxLayer(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := net.Summary()
+
+	assert.Len(s.Layers, 5, "Nested networks should be expanded")
+	assert.Equal(2, s.Layers[1].Depth)
+
+	assert.Equal(3*3*3*4+4, s.Layers[0].Params)
+	assert.Equal(8*8*4*3*3*3, s.Layers[0].MACs)
+	assert.Equal(8*8*4*8, s.Layers[0].Memory)
+
+	assert.Equal(64*10+10, s.Layers[3].Params)
+	assert.Equal(64*10, s.Layers[3].MACs)
+
+	assert.Equal(3*3*3*4+4+64*10+10, s.Params)
+	assert.Equal(8*8*4*3*3*3+64*10, s.MACs)
+	assert.Equal((8*8*4*2+4*4*4+10*2)*8, s.Memory)
+
+	table := s.String()
+	for _, str := range []string{conv.ID(), "ConvolutionalLayer", "[8 8 3]", "Total", "6.91K"} {
+		assert.True(strings.Contains(table, str), "Table should contain %q:\n%s", str, table)
+	}
+
+	assert.Equal(64*10, Summarize(dense).MACs, "Summarize should work with any layer")
+}
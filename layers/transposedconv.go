@@ -0,0 +1,235 @@
+package layers
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas64"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//TransposedConvolutionalLayer computes the transposed operation of a ConvolutionalLayer (sometimes called deconvolution or fractionally strided convolution). Each input value is multiplied by the kernel and added to the output, moving stride values in the output for each input value, so the output is bigger than the input.
+//
+//The forward pass is the backpropagation of a convolution: the input is multiplied by the transposed kernel matrix and the columns are accumulated in the output with col2im. The backpropagation is a normal convolution computed with im2col.
+type TransposedConvolutionalLayer struct {
+	BaseLayer
+
+	inputWidth, inputHeight, inputDepth int
+	kernelWidth, kernelHeight           int
+	strideX, strideY                    int
+	padX, padY                          int
+
+	colTmp *tensor.Tensor
+}
+
+type transposedConvConfig struct {
+	InputWidth, InputHeight, InputDepth int
+	NKernels                            int
+	KernelWidth, KernelHeight           int
+	StrideX, StrideY                    int
+	PadX, PadY                          int
+}
+
+//NewTransposedConvolutionalLayer creates a new TransposedConvolutionalLayer
+//
+// inputWidth: input width
+// inputHeight: input height
+// inputDepth: input depth
+// nKernels: number of kernels (output depth)
+// kernelWidth: kernel width
+// kernelHeight: kernel height
+// strideX: stride in x
+// strideY: stride in y
+// padX: padding in x, removed from both sides of the output
+// padY: padding in y, removed from both sides of the output
+//
+// The output width is (inputWidth-1)*strideX - 2*padX + kernelWidth, and the same for the height. To double the size of the input use a kernel of 4, stride 2 and padding 1, or a kernel of 2, stride 2 and no padding.
+func NewTransposedConvolutionalLayer(inputWidth, inputHeight, inputDepth, nKernels, kernelWidth, kernelHeight, strideX, strideY, padX, padY int) *TransposedConvolutionalLayer {
+	if inputHeight <= 0 || inputWidth <= 0 || inputDepth <= 0 {
+		panic("Input sizes must be bigger than 0")
+	}
+
+	if nKernels <= 0 {
+		panic("Number of kernels must be bigger than 0")
+	}
+
+	if kernelWidth <= 0 || kernelHeight <= 0 {
+		panic("Kernel sizes must be bigger than 0")
+	}
+
+	if strideX <= 0 || strideY <= 0 {
+		panic("Strides must be bigger than 0")
+	}
+
+	if padX < 0 || padY < 0 || 2*padX >= kernelWidth || 2*padY >= kernelHeight {
+		panic("Padding must be positive and smaller than half the kernel")
+	}
+
+	l := &TransposedConvolutionalLayer{}
+
+	l.inputWidth = inputWidth
+	l.inputHeight = inputHeight
+	l.inputDepth = inputDepth
+	l.kernelWidth = kernelWidth
+	l.kernelHeight = kernelHeight
+	l.strideX = strideX
+	l.strideY = strideY
+	l.padX = padX
+	l.padY = padY
+
+	outputWidth := (inputWidth-1)*strideX - 2*padX + kernelWidth
+	outputHeight := (inputHeight-1)*strideY - 2*padY + kernelHeight
+
+	//initialize bias and kernel. The kernel is stored as a matrix with one row for each input channel, so it can be used directly by Gemm.
+	l.bias = tensor.NewTensor(nKernels)
+	l.biasGrad = tensor.NewTensor(l.bias.Size...)
+
+	l.weights = tensor.NewTensor(kernelWidth, kernelHeight, nKernels, inputDepth)
+	l.weightsGrad = tensor.NewTensor(l.weights.Size...)
+
+	//Each output receives contributions from about kernelWidth*kernelHeight/(strideX*strideY) input positions
+	fanIn := math.Max(1, float64(kernelWidth*kernelHeight*inputDepth)/float64(strideX*strideY))
+	stdev := math.Sqrt(2.0 / fanIn)
+	for i := range l.weights.Values {
+		l.weights.Values[i] = rand.NormFloat64() * stdev
+	}
+
+	l.BaseLayer.Init([]int{inputWidth, inputHeight, inputDepth}, []int{outputWidth, outputHeight, nKernels})
+	l.id = "TransposedConv-" + l.id
+
+	return l
+}
+
+//CreateSlave creates a slave of the TransposedConvolutionalLayer. See EnslaverLayer in package weight for more information on layer slaves.
+func (l *TransposedConvolutionalLayer) CreateSlave() weight.Layer {
+	nl := NewTransposedConvolutionalLayer(l.inputWidth, l.inputHeight, l.inputDepth, l.GetOutputSize()[2], l.kernelWidth, l.kernelHeight, l.strideX, l.strideY, l.padX, l.padY)
+
+	nl.id = l.ID()
+
+	nl.weights = l.weights
+	nl.bias = l.bias
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *TransposedConvolutionalLayer) Spec() (*LayerSpec, error) {
+	c := transposedConvConfig{
+		InputWidth:   l.inputWidth,
+		InputHeight:  l.inputHeight,
+		InputDepth:   l.inputDepth,
+		NKernels:     l.GetOutputSize()[2],
+		KernelWidth:  l.kernelWidth,
+		KernelHeight: l.kernelHeight,
+		StrideX:      l.strideX,
+		StrideY:      l.strideY,
+		PadX:         l.padX,
+		PadY:         l.padY,
+	}
+	return NewLayerSpec("TransposedConv", l.ID(), c, l.weights, l.bias)
+}
+
+func loadTransposedConvolutionalLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := transposedConvConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewTransposedConvolutionalLayer(c.InputWidth, c.InputHeight, c.InputDepth, c.NKernels, c.KernelWidth, c.KernelHeight, c.StrideX, c.StrideY, c.PadX, c.PadY)
+	l.id = spec.ID
+
+	return l, spec.CopyTensors(l.weights, l.bias)
+}
+
+//MACs returns the number of multiply-adds needed to activate one example. See MACLayer.
+func (l *TransposedConvolutionalLayer) MACs() int {
+	//Each input value is multiplied by the weights of its channel in all the kernels
+	return l.inputWidth * l.inputHeight * len(l.weights.Values)
+}
+
+//matrices returns the kernel, its gradient and the columns as blas matrices
+func (l *TransposedConvolutionalLayer) matrices() (ker, kerGrad, col blas64.General) {
+	if l.colTmp == nil {
+		l.colTmp = tensor.NewTensor(l.inputWidth*l.inputHeight, l.kernelWidth*l.kernelHeight*l.GetOutputSize()[2])
+	}
+
+	ckk := l.kernelWidth * l.kernelHeight * l.GetOutputSize()[2]
+	ker = blas64.General{Rows: l.inputDepth, Cols: ckk, Stride: ckk, Data: l.weights.Values}
+	kerGrad = blas64.General{Rows: l.inputDepth, Cols: ckk, Stride: ckk, Data: l.weightsGrad.Values}
+
+	n := l.inputWidth * l.inputHeight
+	col = blas64.General{Rows: ckk, Cols: n, Stride: n, Data: l.colTmp.Values}
+
+	return
+}
+
+//Activate computes the transposed convolution of the input
+func (l *TransposedConvolutionalLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	ker, _, col := l.matrices()
+
+	n := l.inputWidth * l.inputHeight
+	matIn := blas64.General{Rows: l.inputDepth, Cols: n, Stride: n, Data: input.Values}
+
+	//Each column has the contribution of one input position to its output window
+	blas64.Gemm(blas.Trans, blas.NoTrans, 1, ker, matIn, 0, col)
+
+	out := l.output.Size
+	col2im(col.Data, out[2], out[1], out[0], l.kernelHeight, l.kernelWidth, l.padY, l.padX, l.strideY, l.strideX, l.output.Values)
+
+	dStride := out[0] * out[1]
+	for i := range l.output.Values {
+		l.output.Values[i] += l.bias.Values[i/dStride]
+	}
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+func (l *TransposedConvolutionalLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	if !err.HasSize(l.GetOutputSize()) {
+		l.mutex.Unlock()
+		return nil, fmt.Errorf("Error gradient has size %v but layer output is %v", err.Size, l.GetOutputSize())
+	}
+
+	ker, kerGrad, col := l.matrices()
+
+	out := l.output.Size
+	im2col(err.Values, col.Data, out[0], out[1], out[2], l.kernelWidth, l.kernelHeight, l.padX, l.padY, l.strideX, l.strideY)
+
+	n := l.inputWidth * l.inputHeight
+	matIn := blas64.General{Rows: l.inputDepth, Cols: n, Stride: n, Data: l.lastInput.Values}
+	matProp := blas64.General{Rows: l.inputDepth, Cols: n, Stride: n, Data: l.propagation.Values}
+
+	//The propagation is the convolution of the error with the kernel
+	blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, ker, col, 0, matProp)
+
+	//Kernel gradient
+	blas64.Gemm(blas.NoTrans, blas.Trans, 1, matIn, col, 1, kerGrad)
+
+	dStride := out[0] * out[1]
+	for i, v := range err.Values {
+		l.biasGrad.Values[i/dStride] += v
+	}
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
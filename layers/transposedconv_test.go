@@ -0,0 +1,83 @@
+package layers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestTransposedConvolutionalActivation(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewTransposedConvolutionalLayer(2, 2, 1, 1, 2, 2, 2, 2, 0, 0)
+
+	assert.Equal([]int{4, 4, 1}, layer.GetOutputSize())
+
+	layer.weights.Values = []float64{1, 2, 3, 4}
+	layer.bias.Values = []float64{0.5}
+
+	data := &tensor.Tensor{Size: []int{2, 2, 1}, Values: []float64{1, 10, 100, 1000}}
+
+	out, err := layer.Activate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//With kernel 2 and stride 2 each input value scales the kernel into its own block
+	assert.InDeltaSlice([]float64{
+		1.5, 2.5, 10.5, 20.5,
+		3.5, 4.5, 30.5, 40.5,
+		100.5, 200.5, 1000.5, 2000.5,
+		300.5, 400.5, 3000.5, 4000.5,
+	}, out.Values, 1e-9)
+}
+
+func TestTransposedConvolutionalOverlap(t *testing.T) {
+	//Kernel 3, stride 2 and padding 1: windows overlap and the borders are cropped
+	layer := NewTransposedConvolutionalLayer(3, 2, 2, 3, 3, 3, 2, 2, 1, 1)
+
+	assert.Equal(t, []int{5, 3, 3}, layer.GetOutputSize())
+
+	input := randomTensor(3, 2, 2)
+	out, err := layer.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Compute the transposed convolution directly: every input value adds the scaled kernel to the output
+	expected := tensor.NewTensor(5, 3, 3)
+	for co := 0; co < 3; co++ {
+		for i := 0; i < 15; i++ {
+			expected.Values[co*15+i] = layer.bias.Values[co]
+		}
+	}
+	for ci := 0; ci < 2; ci++ {
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 3; x++ {
+				v := input.GetVal(x, y, ci)
+				for co := 0; co < 3; co++ {
+					for ky := 0; ky < 3; ky++ {
+						for kx := 0; kx < 3; kx++ {
+							ox := x*2 - 1 + kx
+							oy := y*2 - 1 + ky
+							if ox < 0 || ox >= 5 || oy < 0 || oy >= 3 {
+								continue
+							}
+							w := layer.weights.GetVal(kx, ky, co, ci)
+							expected.SetVal(expected.GetVal(ox, oy, co)+v*w, ox, oy, co)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	assert.InDeltaSlice(t, expected.Values, out.Values, 1e-9)
+}
+
+func TestTransposedConvolutionalGradients(t *testing.T) {
+	layer := NewTransposedConvolutionalLayer(3, 2, 2, 3, 3, 3, 2, 2, 1, 1)
+	checkGradients(t, layer, randomTensor(3, 2, 2))
+}
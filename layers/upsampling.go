@@ -0,0 +1,188 @@
+package layers
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//UpsampleMode is the interpolation used by an UpsamplingLayer
+type UpsampleMode int
+
+const (
+	//NearestUpsample copies each input value to a block of scaleX*scaleY output values
+	NearestUpsample UpsampleMode = iota
+	//BilinearUpsample interpolates linearly between the four nearest input values. Input and output values are aligned by their centers, not their corners.
+	BilinearUpsample
+)
+
+//UpsamplingLayer increases the width and height of a 3D input [w,h,c] by an integer factor. It has no parameters.
+type UpsamplingLayer struct {
+	BaseLayer
+
+	scaleX, scaleY int
+	mode           UpsampleMode
+
+	//For each output column (row), the two input columns (rows) that are interpolated and the weight of the second one
+	x0, x1, y0, y1 []int
+	fx, fy         []float64
+}
+
+type upsamplingConfig struct {
+	InputSize      []int
+	ScaleX, ScaleY int
+	Mode           UpsampleMode
+}
+
+//NewUpsamplingLayer creates a new UpsamplingLayer. The output size is [w*scaleX, h*scaleY, c].
+func NewUpsamplingLayer(inputSize []int, scaleX, scaleY int, mode UpsampleMode) *UpsamplingLayer {
+	if len(inputSize) != 3 {
+		panic("Upsampling input should have 3 dimensions")
+	}
+
+	if scaleX <= 0 || scaleY <= 0 {
+		panic("Upsampling scales must be bigger than 0")
+	}
+
+	if mode != NearestUpsample && mode != BilinearUpsample {
+		panic(fmt.Sprintf("Unknown upsample mode %d", mode))
+	}
+
+	l := &UpsamplingLayer{scaleX: scaleX, scaleY: scaleY, mode: mode}
+	l.BaseLayer.Init(inputSize, []int{inputSize[0] * scaleX, inputSize[1] * scaleY, inputSize[2]})
+	l.id = "Upsampling-" + l.id
+
+	l.x0, l.x1, l.fx = upsampleCoords(inputSize[0], scaleX, mode)
+	l.y0, l.y1, l.fy = upsampleCoords(inputSize[1], scaleY, mode)
+
+	return l
+}
+
+//upsampleCoords returns, for each output position in one dimension, the input positions it depends on and the weight of the second one
+func upsampleCoords(size, scale int, mode UpsampleMode) (p0, p1 []int, f []float64) {
+	n := size * scale
+	p0 = make([]int, n)
+	p1 = make([]int, n)
+	f = make([]float64, n)
+
+	for o := 0; o < n; o++ {
+		if mode == NearestUpsample {
+			p0[o] = o / scale
+			p1[o] = p0[o]
+			continue
+		}
+
+		//Position of the center of the output value in input coordinates
+		src := (float64(o)+0.5)/float64(scale) - 0.5
+		src = math.Max(0, math.Min(src, float64(size-1)))
+
+		p0[o] = int(math.Floor(src))
+		p1[o] = p0[o] + 1
+		if p1[o] >= size {
+			p1[o] = size - 1
+		}
+		f[o] = src - float64(p0[o])
+	}
+
+	return
+}
+
+func (l *UpsamplingLayer) CreateSlave() weight.Layer {
+	nl := NewUpsamplingLayer(l.GetInputSize(), l.scaleX, l.scaleY, l.mode)
+	nl.id = l.ID()
+
+	return nl
+}
+
+//Spec returns the description of the layer used to save it. See SerializableLayer.
+func (l *UpsamplingLayer) Spec() (*LayerSpec, error) {
+	return NewLayerSpec("Upsampling", l.ID(), upsamplingConfig{l.GetInputSize(), l.scaleX, l.scaleY, l.mode})
+}
+
+func loadUpsamplingLayer(spec *LayerSpec) (weight.Layer, error) {
+	c := upsamplingConfig{}
+	err := spec.DecodeConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewUpsamplingLayer(c.InputSize, c.ScaleX, c.ScaleY, c.Mode)
+	l.id = spec.ID
+
+	return l, nil
+}
+
+func (l *UpsamplingLayer) Activate(input *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	err := l.BaseLayer.Activate(input)
+	if err != nil {
+		l.mutex.Unlock()
+		return nil, err
+	}
+
+	iw, ih := input.Size[0], input.Size[1]
+	ow, oh := l.output.Size[0], l.output.Size[1]
+
+	for c := 0; c < l.output.Size[2]; c++ {
+		in := input.Values[c*iw*ih : (c+1)*iw*ih]
+		out := l.output.Values[c*ow*oh : (c+1)*ow*oh]
+
+		for y := 0; y < oh; y++ {
+			r0 := in[l.y0[y]*iw : (l.y0[y]+1)*iw]
+			r1 := in[l.y1[y]*iw : (l.y1[y]+1)*iw]
+			fy := l.fy[y]
+
+			for x := 0; x < ow; x++ {
+				fx := l.fx[x]
+				top := r0[l.x0[x]]*(1-fx) + r0[l.x1[x]]*fx
+				bottom := r1[l.x0[x]]*(1-fx) + r1[l.x1[x]]*fx
+				out[y*ow+x] = top*(1-fy) + bottom*fy
+			}
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.output, nil
+}
+
+func (l *UpsamplingLayer) BackPropagate(err *tensor.Tensor) (*tensor.Tensor, error) {
+	l.mutex.Lock()
+	e := l.BaseLayer.BackPropagate(err)
+	if e != nil {
+		l.mutex.Unlock()
+		return nil, e
+	}
+
+	iw, ih := l.propagation.Size[0], l.propagation.Size[1]
+	ow, oh := l.output.Size[0], l.output.Size[1]
+
+	for c := 0; c < l.output.Size[2]; c++ {
+		prop := l.propagation.Values[c*iw*ih : (c+1)*iw*ih]
+		errs := err.Values[c*ow*oh : (c+1)*ow*oh]
+
+		for y := 0; y < oh; y++ {
+			r0 := prop[l.y0[y]*iw : (l.y0[y]+1)*iw]
+			r1 := prop[l.y1[y]*iw : (l.y1[y]+1)*iw]
+			fy := l.fy[y]
+
+			for x := 0; x < ow; x++ {
+				fx := l.fx[x]
+				g := errs[y*ow+x]
+
+				r0[l.x0[x]] += g * (1 - fx) * (1 - fy)
+				r0[l.x1[x]] += g * fx * (1 - fy)
+				r1[l.x0[x]] += g * (1 - fx) * fy
+				r1[l.x1[x]] += g * fx * fy
+			}
+		}
+	}
+
+	l.mutex.Unlock()
+	return &l.propagation, nil
+}
+
+func (l *UpsamplingLayer) GetParamGradPointers() ([]*float64, []*float64) {
+	return []*float64{}, []*float64{}
+}
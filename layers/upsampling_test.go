@@ -0,0 +1,59 @@
+package layers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestUpsamplingNearest(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewUpsamplingLayer([]int{2, 1, 2}, 2, 2, NearestUpsample)
+
+	data := &tensor.Tensor{Size: []int{2, 1, 2}, Values: []float64{1, 2, 3, 4}}
+
+	out, err := layer.Activate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal([]int{4, 2, 2}, out.Size)
+	assert.InDeltaSlice([]float64{
+		1, 1, 2, 2,
+		1, 1, 2, 2,
+
+		3, 3, 4, 4,
+		3, 3, 4, 4,
+	}, out.Values, 1e-12)
+
+	prop, err := layer.BackPropagate(&tensor.Tensor{Size: []int{4, 2, 2}, Values: []float64{
+		1, 2, 3, 4,
+		5, 6, 7, 8,
+
+		1, 1, 1, 1,
+		1, 1, 1, 1,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.InDeltaSlice([]float64{14, 22, 4, 4}, prop.Values, 1e-12)
+}
+
+func TestUpsamplingBilinear(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := NewUpsamplingLayer([]int{2, 1, 1}, 2, 1, BilinearUpsample)
+
+	out, err := layer.Activate(&tensor.Tensor{Size: []int{2, 1, 1}, Values: []float64{0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.InDeltaSlice([]float64{0, 0.25, 0.75, 1}, out.Values, 1e-12)
+
+	checkGradients(t, NewUpsamplingLayer([]int{3, 4, 2}, 2, 3, BilinearUpsample), randomTensor(3, 4, 2))
+}
@@ -0,0 +1,106 @@
+package layers
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//checkGradients compares the gradients computed by BackPropagate with numerical gradients of the loss sum(output*g), being g a random tensor
+func checkGradients(t *testing.T, layer weight.BPLearnerLayer, input *tensor.Tensor) {
+	const h = 1e-6
+	const tolerance = 1e-5
+
+	g := tensor.NewTensor(layer.GetOutputSize()...)
+	for i := range g.Values {
+		g.Values[i] = rand.NormFloat64()
+	}
+
+	loss := func() float64 {
+		out, err := layer.Activate(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := 0.0
+		for i, v := range out.Values {
+			sum += v * g.Values[i]
+		}
+		return sum
+	}
+
+	params, grads := layer.GetParamGradPointers()
+	for _, gr := range grads {
+		*gr = 0
+	}
+
+	loss()
+	prop, err := layer.BackPropagate(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	propagation := prop.Copy()
+
+	for i := range input.Values {
+		v := input.Values[i]
+		input.Values[i] = v + h
+		lp := loss()
+		input.Values[i] = v - h
+		lm := loss()
+		input.Values[i] = v
+
+		assert.InDelta(t, (lp-lm)/(2*h), propagation.Values[i], tolerance, "Wrong propagation of input %d", i)
+	}
+
+	for i := range params {
+		v := *params[i]
+		*params[i] = v + h
+		lp := loss()
+		*params[i] = v - h
+		lm := loss()
+		*params[i] = v
+
+		assert.InDelta(t, (lp-lm)/(2*h), *grads[i], tolerance, "Wrong gradient of parameter %d", i)
+	}
+}
+
+func randomTensor(size ...int) *tensor.Tensor {
+	t := tensor.NewTensor(size...)
+	for i := range t.Values {
+		t.Values[i] = rand.NormFloat64()
+	}
+	return t
+}
+
+func TestBackPropagateBeforeActivate(t *testing.T) {
+	lyrs := []weight.BPLearnerLayer{
+		NewDenseLayer([]int{4}, []int{3}),
+		NewConvolutionalLayer(5, 5, 2, 3, 1, 1, 1, 1, 1, 1),
+		NewTransposedConvolutionalLayer(3, 3, 2, 2, 3, 3, 2, 2, 1, 1),
+		NewUpsamplingLayer([]int{3, 3, 2}, 2, 2, NearestUpsample),
+		NewPoolLayer([]int{4, 4, 2}, []int{2, 2, 1}),
+		NewGlobalAvgPoolLayer(3, 3, 2),
+		NewReLULayer(4),
+		NewLeakyReLULayer(4),
+		NewSigmoidLayer(4),
+		NewSoftmaxLayer(4),
+		NewBatchNormLayer(4),
+		NewLayerNormLayer(4),
+		NewDropoutLayer(0.5, 4),
+		NewIdentityLayer(4),
+		NewRNNLayer(3, 4, 5, true),
+		NewLSTMLayer(3, 4, 5, false),
+		NewGRULayer(3, 4, 5, true),
+		NewSelfAttentionLayer(4, 5, 2, false),
+		NewPositionalEncodingLayer(4, 5),
+		NewPositionwiseDenseLayer(4, 3, 5),
+	}
+
+	for _, l := range lyrs {
+		_, err := l.BackPropagate(tensor.NewTensor(l.GetOutputSize()...))
+		assert.Error(t, err, "%s should not propagate before Activate", l.ID())
+	}
+}
@@ -12,6 +12,7 @@ func (m *CIFAR) GetNextRow(row interface{}) error {
 
 	currentOffset, err := file.Seek(0, 1) //moves 0 bytes relative to the current position (= no movement) and returns the current position
 	if err != nil {
+		m.mutex.Unlock()
 		return err
 	}
 
@@ -30,6 +31,7 @@ func (m *CIFAR) GetNextRow(row interface{}) error {
 	if currentRow == int64(m.batchRows[m.currentBatch]) {
 		m.currentBatch++
 		if m.currentBatch >= len(m.batches) {
+			m.mutex.Unlock()
 			return errors.New("No new set available")
 		}
 		m.batches[m.currentBatch].Seek(0, 0) //set net batch to start
@@ -41,6 +43,7 @@ func (m *CIFAR) GetNextRow(row interface{}) error {
 
 	err = binary.Read(file, binary.LittleEndian, row)
 	if err != nil {
+		m.mutex.Unlock()
 		return err
 	}
 
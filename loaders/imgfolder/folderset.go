@@ -2,7 +2,6 @@ package imgfolder
 
 import (
 	"bufio"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -25,6 +24,9 @@ type img struct {
 type FolderSet struct {
 	imgs []img
 
+	//Images in the order of the folders, used to shuffle them again with Seed
+	sorted []img
+
 	nlabels int
 	imgsize [2]int
 
@@ -51,15 +53,25 @@ func (m *FolderSet) Reset() {
 	m.mutex.Unlock()
 }
 
+//Seed shuffles the images again with the given seed. The new order only depends on the seed, not on the previous order. See weight.RandDataSet.
+func (m *FolderSet) Seed(seed int64) {
+	m.mutex.Lock()
+	copy(m.imgs, m.sorted)
+	shuffle(m.imgs, rand.New(rand.NewSource(seed)))
+	m.mutex.Unlock()
+}
+
 func (m *FolderSet) GetNextSet() (*tensor.Tensor, *tensor.Tensor, error) {
 	m.mutex.Lock()
 	im, err := utils.LoadImage(m.imgs[m.pointer].path, utils.RGB)
 	if err != nil {
-		return nil, nil, err
+		m.mutex.Unlock()
+		return nil, nil, fmt.Errorf("Could not load %s: %s", m.imgs[m.pointer].path, err.Error())
 	}
 
 	if im.Size[0] != m.imgsize[0] || im.Size[1] != m.imgsize[1] {
-		return nil, nil, errors.New("Image file does not have expected dimensions")
+		m.mutex.Unlock()
+		return nil, nil, fmt.Errorf("Image file %s does not have expected dimensions", m.imgs[m.pointer].path)
 	}
 
 	lbl := tensor.NewTensor(m.nlabels)
@@ -121,7 +133,9 @@ func NewSet(path string) (*FolderSet, error) {
 		index++
 	}
 
-	shuffle(set.imgs)
+	set.sorted = make([]img, len(set.imgs))
+	copy(set.sorted, set.imgs)
+	shuffle(set.imgs, rand.New(rand.NewSource(rand.Int63())))
 
 	set.nlabels = index + 1
 	set.pointer = 0
@@ -147,11 +161,11 @@ func openLabelFolder(path string, lbl int) ([]img, error) {
 	return imgs, nil
 }
 
-func shuffle(slc []img) {
+func shuffle(slc []img, rng *rand.Rand) {
 	N := len(slc)
 	for i := 0; i < N; i++ {
 		// choose index uniformly in [i, N-1]
-		r := i + rand.Intn(N-i)
+		r := i + rng.Intn(N-i)
 		slc[r], slc[i] = slc[i], slc[r]
 	}
 }
@@ -38,6 +38,7 @@ func (m *TensorSet) Reset() {
 func (m *TensorSet) GetNextSet() (*tensor.Tensor, *tensor.Tensor, error) {
 	m.mutex.Lock()
 	if m.pointer >= len(m.data) {
+		m.mutex.Unlock()
 		return nil, nil, fmt.Errorf("No next set. %d >= %d", m.pointer, len(m.data))
 	}
 
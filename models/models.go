@@ -0,0 +1,130 @@
+package models
+
+import (
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/layers"
+)
+
+//NewLeNet creates a LeNet-5 style network for small grayscale images like MNIST ([28, 28, 1]): two 5x5 convolutions with ReLU and 2x2 max pooling, followed by three dense layers and a softmax over the classes
+func NewLeNet(inputSize []int, classes int) *layers.FFNet {
+
+	if len(inputSize) != 3 {
+		panic("Input size should have 3 dimensions")
+	}
+
+	cl1 := layers.NewConvolutionalLayer(inputSize[0], inputSize[1], inputSize[2], 6, 2, 2, 1, 1, 2, 2)
+	pl1 := layers.NewPoolLayer(cl1.GetOutputSize(), []int{2, 2, 1})
+
+	size := pl1.GetOutputSize()
+	cl2 := layers.NewConvolutionalLayer(size[0], size[1], size[2], 16, 2, 2, 1, 1, 0, 0)
+	pl2 := layers.NewPoolLayer(cl2.GetOutputSize(), []int{2, 2, 1})
+
+	return sequential(
+		cl1,
+		layers.NewReLULayer(cl1.GetOutputSize()...),
+		pl1,
+		cl2,
+		layers.NewReLULayer(cl2.GetOutputSize()...),
+		pl2,
+		layers.NewDenseLayer(pl2.GetOutputSize(), []int{120}),
+		layers.NewReLULayer(120),
+		layers.NewDenseLayer([]int{120}, []int{84}),
+		layers.NewReLULayer(84),
+		layers.NewDenseLayer([]int{84}, []int{classes}),
+		layers.NewSoftmaxLayer(classes),
+	)
+}
+
+//NewResNet20 creates the 20 layer ResNet for CIFAR-10 ([32, 32, 3]) from the original ResNet paper: a 3x3 convolution with 16 kernels, three stages of 3 residual blocks with 16, 32 and 64 kernels (the last two halve the width and height), global average pooling and a dense layer with softmax over the classes
+func NewResNet20(inputSize []int, classes int) *layers.FFNet {
+	return NewResNet(inputSize, 3, classes)
+}
+
+//NewResNet creates a CIFAR ResNet like NewResNet20 with n residual blocks per stage, so it has 6n+2 layers (20, 32, 44, 56...)
+func NewResNet(inputSize []int, n, classes int) *layers.FFNet {
+
+	if len(inputSize) != 3 {
+		panic("Input size should have 3 dimensions")
+	}
+
+	cl := layers.NewConvolutionalLayer(inputSize[0], inputSize[1], inputSize[2], 16, 1, 1, 1, 1, 1, 1)
+	size := cl.GetOutputSize()
+
+	lyrs := []weight.Layer{
+		cl,
+		layers.NewBatchNormLayer(size...),
+		layers.NewReLULayer(size...),
+	}
+
+	for stage, nKernels := range []int{16, 32, 64} {
+		for i := 0; i < n; i++ {
+			stride := 1
+			if stage > 0 && i == 0 {
+				stride = 2
+			}
+
+			block := layers.NewResidualBlock(size, nKernels, stride)
+			lyrs = append(lyrs, block)
+			size = block.GetOutputSize()
+		}
+	}
+
+	lyrs = append(lyrs,
+		layers.NewGlobalAvgPoolLayer(size...),
+		layers.NewDenseLayer([]int{size[2]}, []int{classes}),
+		layers.NewSoftmaxLayer(classes),
+	)
+
+	return sequential(lyrs...)
+}
+
+//NewVGG creates a small VGG-like network for images like CIFAR-10 ([32, 32, 3]): three stages of two 3x3 convolutions with batch normalization and ReLU followed by 2x2 max pooling, with 64, 128 and 256 kernels, and a classifier with a hidden dense layer of 256 units with dropout. The width and height of the input must be divisible by 8.
+func NewVGG(inputSize []int, classes int) *layers.FFNet {
+
+	if len(inputSize) != 3 {
+		panic("Input size should have 3 dimensions")
+	}
+
+	if inputSize[0]%8 != 0 || inputSize[1]%8 != 0 {
+		panic("Input size in VGG should be divisible by 8")
+	}
+
+	lyrs := []weight.Layer{}
+
+	size := inputSize
+	for _, nKernels := range []int{64, 128, 256} {
+		for i := 0; i < 2; i++ {
+			cl := layers.NewConvolutionalLayer(size[0], size[1], size[2], nKernels, 1, 1, 1, 1, 1, 1)
+			size = cl.GetOutputSize()
+
+			lyrs = append(lyrs,
+				cl,
+				layers.NewBatchNormLayer(size...),
+				layers.NewReLULayer(size...),
+			)
+		}
+
+		pl := layers.NewPoolLayer(size, []int{2, 2, 1})
+		lyrs = append(lyrs, pl)
+		size = pl.GetOutputSize()
+	}
+
+	lyrs = append(lyrs,
+		layers.NewDenseLayer(size, []int{256}),
+		layers.NewReLULayer(256),
+		layers.NewDropoutLayer(0.5, 256),
+		layers.NewDenseLayer([]int{256}, []int{classes}),
+		layers.NewSoftmaxLayer(classes),
+	)
+
+	return sequential(lyrs...)
+}
+
+func sequential(lyrs ...weight.Layer) *layers.FFNet {
+	net, err := layers.NewSequentialNet(lyrs...)
+	if err != nil {
+		panic(err)
+	}
+
+	return net
+}
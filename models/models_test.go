@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/layers"
+	"github.com/gerardabello/weight/tensor"
+)
+
+func TestModels(t *testing.T) {
+	assert := assert.New(t)
+
+	nets := []*layers.FFNet{
+		NewLeNet([]int{28, 28, 1}, 10),
+		NewResNet20([]int{32, 32, 3}, 10),
+		NewVGG([]int{32, 32, 3}, 10),
+	}
+
+	for _, net := range nets {
+		out, err := net.Activate(tensor.NewTensor(net.GetInputSize()...))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal([]int{10}, out.Size)
+		assert.InDelta(1, out.Values[0]+out.Values[1]+out.Values[2]+out.Values[3]+out.Values[4]+out.Values[5]+out.Values[6]+out.Values[7]+out.Values[8]+out.Values[9], 1e-9, "Output should be a probability distribution")
+	}
+}
@@ -1,21 +1,39 @@
 package tensor
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/gerardabello/weight/loaders/utils/idx"
 )
 
 func (t *Tensor) Marshal(w io.Writer) error {
-	var size []int32
-	for _, s := range t.Size {
-		size = append(size, int32(s))
-	}
-	iw := idx.NewWriter(w, idx.Float64DataType, size)
+	iw := idx.NewWriter(w, idx.Float64DataType, dimensions(t.Size))
 
 	return iw.WriteFloat64(t.Values)
 }
 
+//MarshalFloat32 is like Marshal but writes the values as float32 (idx.Float32DataType), so the data is half the size. Unmarshal reads it back converting the values to float64.
+func (t *Tensor) MarshalFloat32(w io.Writer) error {
+	return t.ToFloat32().Marshal(w)
+}
+
+//Marshal writes the tensor in IDX format with the idx.Float32DataType type
+func (t *Tensor32) Marshal(w io.Writer) error {
+	iw := idx.NewWriter(w, idx.Float32DataType, dimensions(t.Size))
+
+	return iw.WriteFloat32(t.Values)
+}
+
+func dimensions(size []int) []int32 {
+	var dims []int32
+	for _, s := range size {
+		dims = append(dims, int32(s))
+	}
+	return dims
+}
+
+//Unmarshal reads a tensor written with Marshal or MarshalFloat32
 func Unmarshal(r io.Reader) (*Tensor, error) {
 	rd, err := idx.NewReader(r)
 
@@ -24,9 +42,57 @@ func Unmarshal(r io.Reader) (*Tensor, error) {
 	}
 
 	t := &Tensor{}
-	t.Allocate(rd.Dimensions...)
+	err = t.Allocate(rd.Dimensions...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rd.Header.DataType {
+	case idx.Float64DataType:
+		err = rd.ReadFloat64(t.Values)
+
+	case idx.Float32DataType:
+		values := make([]float32, len(t.Values))
+		err = rd.ReadFloat32(values)
+		CopyToFloat64(t.Values, values)
+
+	default:
+		err = fmt.Errorf("Cannot read tensor of IDX data type 0x%02x", rd.Header.DataType)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+//Unmarshal32 reads a Tensor32 written with Marshal or MarshalFloat32. Values written as float64 lose precision.
+func Unmarshal32(r io.Reader) (*Tensor32, error) {
+	rd, err := idx.NewReader(r)
+
+	if err != nil {
+		return nil, err
+	}
 
-	err = rd.ReadFloat64(t.Values)
+	t := &Tensor32{}
+	err = t.Allocate(rd.Dimensions...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rd.Header.DataType {
+	case idx.Float32DataType:
+		err = rd.ReadFloat32(t.Values)
+
+	case idx.Float64DataType:
+		values := make([]float64, len(t.Values))
+		err = rd.ReadFloat64(values)
+		CopyToFloat32(t.Values, values)
+
+	default:
+		err = fmt.Errorf("Cannot read tensor of IDX data type 0x%02x", rd.Header.DataType)
+	}
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,179 @@
+package tensor
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas64"
+)
+
+//The functions in this file return new tensors and do not modify their arguments. Binary operations broadcast their arguments like NumPy, but with the dimensions aligned at the first one (the fastest in memory) instead of the last: a missing dimension at the end or a dimension of size 1 is repeated to match the other tensor. For example, [3] broadcasts to [3, 4] (the same values for each of the 4 columns) and [1, 4] broadcasts to [3, 4]. To add a value per channel to an image [w, h, c], use a tensor of size [1, 1, c]. A tensor of size [1] works as a scalar.
+
+//Scalar returns a tensor of size [1] with the value v, which can be broadcast to any size
+func Scalar(v float64) *Tensor {
+	return &Tensor{Values: []float64{v}, Size: []int{1}}
+}
+
+//BroadcastSize returns the size of the result of a binary operation between tensors of sizes a and b
+func BroadcastSize(a, b []int) ([]int, error) {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+
+	size := make([]int, n)
+	for d := range size {
+		sa, sb := 1, 1
+		if d < len(a) {
+			sa = a[d]
+		}
+		if d < len(b) {
+			sb = b[d]
+		}
+
+		switch {
+		case sa == sb || sb == 1:
+			size[d] = sa
+		case sa == 1:
+			size[d] = sb
+		default:
+			return nil, fmt.Errorf("Cannot broadcast tensors of sizes %v and %v", a, b)
+		}
+	}
+
+	return size, nil
+}
+
+//Broadcast returns a view of the tensor repeated to the given size, without copying the values. See BroadcastSize.
+func (t *Tensor) Broadcast(size ...int) (*View, error) {
+	if len(t.Size) > len(size) {
+		return nil, fmt.Errorf("Cannot broadcast a tensor of size %v to %v", t.Size, size)
+	}
+
+	v := t.View()
+	for d := range t.Size {
+		if t.Size[d] != size[d] {
+			if t.Size[d] != 1 {
+				return nil, fmt.Errorf("Cannot broadcast a tensor of size %v to %v", t.Size, size)
+			}
+			v.Strides[d] = 0
+		}
+	}
+
+	//The repeated dimensions do not move in the values
+	for d := len(t.Size); d < len(size); d++ {
+		v.Strides = append(v.Strides, 0)
+	}
+	v.Size = append([]int{}, size...)
+
+	return v, nil
+}
+
+//broadcastValues returns the values of the tensor broadcast to size, copying them only if needed
+func broadcastValues(t *Tensor, size []int) ([]float64, error) {
+	if t.HasSize(size) {
+		return t.Values, nil
+	}
+
+	v, err := t.Broadcast(size...)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Contiguous().Values, nil
+}
+
+//Binary returns a new tensor with f applied to each pair of values of a and b after broadcasting them
+func Binary(a, b *Tensor, f func(x, y float64) float64) (*Tensor, error) {
+	size, err := BroadcastSize(a.Size, b.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	av, err := broadcastValues(a, size)
+	if err != nil {
+		return nil, err
+	}
+	bv, err := broadcastValues(b, size)
+	if err != nil {
+		return nil, err
+	}
+
+	out := NewTensor(size...)
+	for i := range out.Values {
+		out.Values[i] = f(av[i], bv[i])
+	}
+
+	return out, nil
+}
+
+//Add returns a + b
+func Add(a, b *Tensor) (*Tensor, error) {
+	return Binary(a, b, func(x, y float64) float64 { return x + y })
+}
+
+//Sub returns a - b
+func Sub(a, b *Tensor) (*Tensor, error) {
+	return Binary(a, b, func(x, y float64) float64 { return x - y })
+}
+
+//Mul returns the elementwise product of a and b
+func Mul(a, b *Tensor) (*Tensor, error) {
+	return Binary(a, b, func(x, y float64) float64 { return x * y })
+}
+
+//Div returns the elementwise division of a by b
+func Div(a, b *Tensor) (*Tensor, error) {
+	return Binary(a, b, func(x, y float64) float64 { return x / y })
+}
+
+//Pow returns each value of a to the power of the value of b
+func Pow(a, b *Tensor) (*Tensor, error) {
+	return Binary(a, b, math.Pow)
+}
+
+//Apply returns a new tensor with f applied to each value of t
+func Apply(t *Tensor, f func(float64) float64) *Tensor {
+	out := NewTensor(t.Size...)
+	for i, v := range t.Values {
+		out.Values[i] = f(v)
+	}
+	return out
+}
+
+//Exp returns the exponential of each value
+func Exp(t *Tensor) *Tensor {
+	return Apply(t, math.Exp)
+}
+
+//Log returns the natural logarithm of each value
+func Log(t *Tensor) *Tensor {
+	return Apply(t, math.Log)
+}
+
+//Clamp returns the values limited to the range [min, max]
+func Clamp(t *Tensor, min, max float64) *Tensor {
+	return Apply(t, func(v float64) float64 {
+		return math.Max(min, math.Min(max, v))
+	})
+}
+
+//MatMul returns the matrix product of a [n, k] and b [k, m], a new tensor [n, m]. The first index of a matrix is the row, so the element (i, j) is GetVal(i, j).
+func MatMul(a, b *Tensor) (*Tensor, error) {
+	if len(a.Size) != 2 || len(b.Size) != 2 || a.Size[1] != b.Size[0] {
+		return nil, fmt.Errorf("Cannot multiply matrices of sizes %v and %v", a.Size, b.Size)
+	}
+
+	n, k, m := a.Size[0], a.Size[1], b.Size[1]
+	out := NewTensor(n, m)
+
+	//The first dimension is the fastest, so the values of a [n, k] matrix are the values of its transpose in the row-major order used by blas. The product is computed as (a*b)^T = b^T * a^T.
+	at := blas64.General{Rows: k, Cols: n, Stride: n, Data: a.Values}
+	bt := blas64.General{Rows: m, Cols: k, Stride: k, Data: b.Values}
+	ot := blas64.General{Rows: m, Cols: n, Stride: n, Data: out.Values}
+
+	blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, bt, at, 0, ot)
+
+	return out, nil
+}
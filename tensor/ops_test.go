@@ -0,0 +1,106 @@
+package tensor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcastSize(t *testing.T) {
+	assert := assert.New(t)
+
+	size, err := BroadcastSize([]int{3, 4}, []int{3})
+	assert.NoError(err)
+	assert.Equal([]int{3, 4}, size)
+
+	size, err = BroadcastSize([]int{1, 4}, []int{3, 1, 2})
+	assert.NoError(err)
+	assert.Equal([]int{3, 4, 2}, size)
+
+	_, err = BroadcastSize([]int{3, 4}, []int{4})
+	assert.Error(err)
+}
+
+func TestBinaryOps(t *testing.T) {
+	assert := assert.New(t)
+
+	a := &Tensor{Values: []float64{1, 2, 3, 4, 5, 6}, Size: []int{3, 2}}
+
+	//One value for each column
+	out, err := Add(a, &Tensor{Values: []float64{10, 20}, Size: []int{1, 2}})
+	assert.NoError(err)
+	assert.Equal([]int{3, 2}, out.Size)
+	assert.Equal([]float64{11, 12, 13, 24, 25, 26}, out.Values)
+
+	//One value for each row
+	out, err = Sub(a, &Tensor{Values: []float64{1, 2, 3}, Size: []int{3}})
+	assert.NoError(err)
+	assert.Equal([]float64{0, 0, 0, 3, 3, 3}, out.Values)
+
+	out, err = Mul(a, Scalar(2))
+	assert.NoError(err)
+	assert.Equal([]float64{2, 4, 6, 8, 10, 12}, out.Values)
+
+	out, err = Div(Scalar(12), a)
+	assert.NoError(err)
+	assert.Equal([]float64{12, 6, 4, 3, 2.4, 2}, out.Values)
+
+	out, err = Pow(a, Scalar(2))
+	assert.NoError(err)
+	assert.Equal([]float64{1, 4, 9, 16, 25, 36}, out.Values)
+
+	//Both arguments are broadcast
+	out, err = Add(&Tensor{Values: []float64{1, 2}, Size: []int{2}}, &Tensor{Values: []float64{10, 20, 30}, Size: []int{1, 3}})
+	assert.NoError(err)
+	assert.Equal([]int{2, 3}, out.Size)
+	assert.Equal([]float64{11, 12, 21, 22, 31, 32}, out.Values)
+
+	_, err = Add(a, &Tensor{Values: []float64{1, 2}, Size: []int{2}})
+	assert.Error(err)
+
+	//The arguments are not modified
+	assert.Equal([]float64{1, 2, 3, 4, 5, 6}, a.Values)
+}
+
+func TestUnaryOps(t *testing.T) {
+	assert := assert.New(t)
+
+	a := &Tensor{Values: []float64{-1, 0, 1, 2}, Size: []int{2, 2}}
+
+	assert.InDeltaSlice([]float64{math.Exp(-1), 1, math.E, math.Exp(2)}, Exp(a).Values, 1e-12)
+	assert.InDeltaSlice([]float64{0, 1, 2}, Log(&Tensor{Values: []float64{1, math.E, math.E * math.E}, Size: []int{3}}).Values, 1e-12)
+	assert.Equal([]float64{-0.5, 0, 1, 1}, Clamp(a, -0.5, 1).Values)
+	assert.Equal([]float64{1, 0, 1, 4}, Apply(a, func(v float64) float64 { return v * v }).Values)
+	assert.Equal([]int{2, 2}, Apply(a, math.Abs).Size)
+}
+
+func TestMatMul(t *testing.T) {
+	assert := assert.New(t)
+
+	a := NewTensor(2, 3)
+	b := NewTensor(3, 4)
+	for i := range a.Values {
+		a.Values[i] = float64(i) - 2
+	}
+	for i := range b.Values {
+		b.Values[i] = float64(i%5) * 0.5
+	}
+
+	out, err := MatMul(a, b)
+	assert.NoError(err)
+	assert.Equal([]int{2, 4}, out.Size)
+
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 4; j++ {
+			sum := 0.0
+			for k := 0; k < 3; k++ {
+				sum += a.GetVal(i, k) * b.GetVal(k, j)
+			}
+			assert.InDelta(sum, out.GetVal(i, j), 1e-12)
+		}
+	}
+
+	_, err = MatMul(a, a)
+	assert.Error(err)
+}
@@ -0,0 +1,110 @@
+package tensor
+
+import (
+	"fmt"
+	"math"
+)
+
+//The reductions in this file return a new tensor with the same number of dimensions, with size 1 in the reduced axes, so the result can be broadcast back to the original tensor. For example, the values of an image [w, h, c] minus MeanAlong(0, 1) have zero mean in each channel.
+
+//reducedSize returns the size of t with the given axes set to 1. Without axes, all of them are reduced.
+func reducedSize(size []int, axes []int) []int {
+	reduced := append([]int{}, size...)
+
+	if len(axes) == 0 {
+		for d := range reduced {
+			reduced[d] = 1
+		}
+		return reduced
+	}
+
+	for _, a := range axes {
+		if a < 0 || a >= len(size) {
+			panic(fmt.Sprintf("Axis %d out of range for a tensor with %d dimensions", a, len(size)))
+		}
+		reduced[a] = 1
+	}
+
+	return reduced
+}
+
+//reduce returns a tensor of the reduced size, which starts with init in all values and is updated with f for each value of t that falls in it
+func (t *Tensor) reduce(axes []int, init float64, f func(acc, v float64) float64) *Tensor {
+	out := NewTensor(reducedSize(t.Size, axes)...)
+	out.Zero(init)
+
+	//Iterating the output broadcast to the size of t gives the position of the output of each value of t
+	view, err := out.Broadcast(t.Size...)
+	if err != nil {
+		panic(err)
+	}
+
+	i := 0
+	view.each(func(pos int) {
+		out.Values[pos] = f(out.Values[pos], t.Values[i])
+		i++
+	})
+
+	return out
+}
+
+//SumAlong returns the sum of the values along the given axes, or of all of them if no axis is given
+func (t *Tensor) SumAlong(axes ...int) *Tensor {
+	return t.reduce(axes, 0, func(acc, v float64) float64 { return acc + v })
+}
+
+//MeanAlong returns the mean of the values along the given axes, or of all of them if no axis is given
+func (t *Tensor) MeanAlong(axes ...int) *Tensor {
+	sum := t.SumAlong(axes...)
+	sum.Mul(float64(sum.GetNumberOfValues()) / float64(t.GetNumberOfValues()))
+	return sum
+}
+
+//MaxAlong returns the maximum of the values along the given axes, or of all of them if no axis is given
+func (t *Tensor) MaxAlong(axes ...int) *Tensor {
+	return t.reduce(axes, math.Inf(-1), math.Max)
+}
+
+//VarianceAlong returns the variance of the values along the given axes, or of all of them if no axis is given. It is the population variance (the mean of the squared differences to the mean), the one used to normalize values.
+func (t *Tensor) VarianceAlong(axes ...int) *Tensor {
+	mean := t.MeanAlong(axes...)
+
+	diff, err := Sub(t, mean)
+	if err != nil {
+		panic(err)
+	}
+
+	for i, v := range diff.Values {
+		diff.Values[i] = v * v
+	}
+
+	return diff.MeanAlong(axes...)
+}
+
+//ArgMaxAlong returns the position of the maximum value along the axis, stored as a float64. If the maximum is repeated, the first position is returned.
+func (t *Tensor) ArgMaxAlong(axis int) *Tensor {
+	max := t.MaxAlong(axis)
+	arg := NewTensor(max.Size...)
+	arg.Zero(-1)
+
+	view, err := arg.Broadcast(t.Size...)
+	if err != nil {
+		panic(err)
+	}
+
+	//Position in the axis of each value of t
+	stride := 1
+	for d := 0; d < axis; d++ {
+		stride *= t.Size[d]
+	}
+
+	i := 0
+	view.each(func(pos int) {
+		if arg.Values[pos] < 0 && t.Values[i] == max.Values[pos] {
+			arg.Values[pos] = float64((i / stride) % t.Size[axis])
+		}
+		i++
+	})
+
+	return arg
+}
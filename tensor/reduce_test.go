@@ -0,0 +1,64 @@
+package tensor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReductions(t *testing.T) {
+	assert := assert.New(t)
+
+	//Values 0..5 with size [3, 2]: the rows are (0, 3), (1, 4) and (2, 5)
+	tt := newSequenceTensor(3, 2)
+
+	sum := tt.SumAlong(0)
+	assert.Equal([]int{1, 2}, sum.Size)
+	assert.Equal([]float64{3, 12}, sum.Values)
+
+	sum = tt.SumAlong(1)
+	assert.Equal([]int{3, 1}, sum.Size)
+	assert.Equal([]float64{3, 5, 7}, sum.Values)
+
+	sum = tt.SumAlong()
+	assert.Equal([]int{1, 1}, sum.Size)
+	assert.Equal([]float64{15}, sum.Values)
+	assert.Equal(sum.Values, tt.SumAlong(0, 1).Values)
+
+	assert.Equal([]float64{1, 4}, tt.MeanAlong(0).Values)
+	assert.Equal([]float64{1.5, 2.5, 3.5}, tt.MeanAlong(1).Values)
+
+	assert.Equal([]float64{2, 5}, tt.MaxAlong(0).Values)
+	assert.Equal([]float64{3, 4, 5}, tt.MaxAlong(1).Values)
+
+	assert.InDeltaSlice([]float64{2.0 / 3, 2.0 / 3}, tt.VarianceAlong(0).Values, 1e-12)
+	assert.InDeltaSlice([]float64{2.25, 2.25, 2.25}, tt.VarianceAlong(1).Values, 1e-12)
+
+	//The result can be broadcast back to normalize the tensor
+	centered, err := Sub(tt, tt.MeanAlong(0))
+	assert.NoError(err)
+	assert.InDeltaSlice([]float64{0, 0}, centered.SumAlong(0).Values, 1e-12)
+}
+
+func TestArgMaxAlong(t *testing.T) {
+	assert := assert.New(t)
+
+	tt := &Tensor{
+		Values: []float64{
+			1, 7, 3,
+			9, 2, 9,
+		},
+		Size: []int{3, 2},
+	}
+
+	arg := tt.ArgMaxAlong(0)
+	assert.Equal([]int{1, 2}, arg.Size)
+	//The first maximum is returned
+	assert.Equal([]float64{1, 0}, arg.Values)
+
+	arg = tt.ArgMaxAlong(1)
+	assert.Equal([]int{3, 1}, arg.Size)
+	assert.Equal([]float64{1, 0, 1}, arg.Values)
+
+	assert.Panics(func() { tt.ArgMaxAlong(2) })
+}
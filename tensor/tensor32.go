@@ -0,0 +1,137 @@
+package tensor
+
+import "errors"
+
+//Tensor32 is like Tensor but stores the values as float32. It uses half the memory of a Tensor, and it is enough for inference in most networks.
+type Tensor32 struct {
+	Values []float32
+	Size   []int //Size of each dimension in order. Values should be bigger than 0
+}
+
+//NewTensor32 creates a Tensor32 with the given size, with all values set to 0
+func NewTensor32(size ...int) *Tensor32 {
+	t := &Tensor32{}
+	err := t.Allocate(size...)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+//Allocate sets the size of the tensor and allocates the necessary memory
+func (t *Tensor32) Allocate(size ...int) error {
+	if len(size) == 0 {
+		return errors.New("Allocate expects at least one dimension")
+	}
+
+	for i := 0; i < len(size); i++ {
+		if size[i] <= 0 {
+			return errors.New("Allocating a slice with a dimension of size zero or negative is not allowed")
+		}
+	}
+
+	t.Size = size
+	t.Values = make([]float32, SizeLength(size))
+
+	return nil
+}
+
+//GetVal returns the value at the index
+func (t *Tensor32) GetVal(index ...int) float32 {
+	return t.Values[flatIndex(t.Size, index)]
+}
+
+//SetVal sets the value at index to val
+func (t *Tensor32) SetVal(val float32, index ...int) {
+	t.Values[flatIndex(t.Size, index)] = val
+}
+
+//Zero sets all values to val
+func (t *Tensor32) Zero(val float32) {
+	for i := range t.Values {
+		t.Values[i] = val
+	}
+}
+
+//Copy the values into a new Tensor32 struct
+func (t *Tensor32) Copy() *Tensor32 {
+	c := &Tensor32{
+		Values: make([]float32, len(t.Values)),
+		Size:   make([]int, len(t.Size)),
+	}
+
+	copy(c.Values, t.Values)
+	copy(c.Size, t.Size)
+
+	return c
+}
+
+func (t *Tensor32) GetNumberOfValues() int {
+	return SizeLength(t.Size)
+}
+
+func (t *Tensor32) HasSize(size []int) bool {
+	return (&Tensor{Size: t.Size}).HasSize(size)
+}
+
+//ToFloat32 returns a Tensor32 with the values of the tensor converted to float32
+func (t *Tensor) ToFloat32() *Tensor32 {
+	c := NewTensor32(t.Size...)
+	CopyToFloat32(c.Values, t.Values)
+	return c
+}
+
+//ToFloat64 returns a Tensor with the values of the tensor converted to float64
+func (t *Tensor32) ToFloat64() *Tensor {
+	c := NewTensor(t.Size...)
+	CopyToFloat64(c.Values, t.Values)
+	return c
+}
+
+//CopyToFloat32 converts the values of src to float32 and stores them in dst. It returns the number of values copied, the minimum of both lengths, like the builtin copy.
+func CopyToFloat32(dst []float32, src []float64) int {
+	n := len(dst)
+	if len(src) < n {
+		n = len(src)
+	}
+
+	for i := 0; i < n; i++ {
+		dst[i] = float32(src[i])
+	}
+
+	return n
+}
+
+//CopyToFloat64 converts the values of src to float64 and stores them in dst. It returns the number of values copied, the minimum of both lengths, like the builtin copy.
+func CopyToFloat64(dst []float64, src []float32) int {
+	n := len(dst)
+	if len(src) < n {
+		n = len(src)
+	}
+
+	for i := 0; i < n; i++ {
+		dst[i] = float64(src[i])
+	}
+
+	return n
+}
+
+//flatIndex returns the position in the values of the given index. See DimToFlat.
+func flatIndex(size, index []int) int {
+	if len(index) != len(size) {
+		panic("Index dimensions do not match Tensor dimensions")
+	}
+
+	pos := 0
+	stride := 1
+	for i := range index {
+		if index[i] < 0 || index[i] >= size[i] {
+			panic("Index out of bounds")
+		}
+		pos += index[i] * stride
+		stride *= size[i]
+	}
+
+	return pos
+}
@@ -0,0 +1,47 @@
+package tensor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTensor32Conversion(t *testing.T) {
+	tt := generateRandomTensor()
+
+	t32 := tt.ToFloat32()
+	assert.Equal(t, tt.Size, t32.Size)
+	assert.InDeltaSlice(t, tt.Values, t32.ToFloat64().Values, 1e-4)
+
+	t32 = NewTensor32(3, 4, 2)
+	t32.SetVal(0.5, 2, 1, 1)
+	assert.Equal(t, float32(0.5), t32.GetVal(2, 1, 1))
+	assert.Equal(t, float32(0.5), t32.Values[2+1*3+1*12])
+}
+
+func TestMarshalFloat32(t *testing.T) {
+	tt := generateRandomTensor()
+
+	var b bytes.Buffer
+	assert.NoError(t, tt.MarshalFloat32(&b))
+	assert.Equal(t, 4+4*len(tt.Size)+4*len(tt.Values), b.Len(), "Values should be written as float32")
+
+	nt, err := Unmarshal(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, tt.Size, nt.Size)
+	assert.InDeltaSlice(t, tt.Values, nt.Values, 1e-4)
+
+	//Both types can be read as float32
+	b.Reset()
+	assert.NoError(t, tt.Marshal(&b))
+	t32, err := Unmarshal32(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, tt.ToFloat32().Values, t32.Values)
+
+	b.Reset()
+	assert.NoError(t, t32.Marshal(&b))
+	t32b, err := Unmarshal32(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, t32, t32b)
+}
@@ -0,0 +1,302 @@
+package tensor
+
+import (
+	"errors"
+	"fmt"
+)
+
+//View is a strided view of the values of a Tensor. It shares the values with the tensor, so changes through the view change the tensor and the other way around. Views can be sliced in any dimension, transposed, permuted and reshaped without copying any value. Use Contiguous to get a new Tensor with the values of the view.
+//
+//The value at index is Values[Offset + index[0]*Strides[0] + index[1]*Strides[1] + ...]. For a tensor the strides are the ones used by DimToFlat: 1 for the first dimension, Size[0] for the second, Size[0]*Size[1] for the third, and so on.
+type View struct {
+	Values  []float64
+	Size    []int
+	Strides []int
+	Offset  int
+}
+
+//View returns a view of all the values of the tensor
+func (t *Tensor) View() *View {
+	if len(t.tmpStrides) != len(t.Size) {
+		t.calcTmpStrides()
+	}
+
+	v := &View{
+		Values:  t.Values,
+		Size:    make([]int, len(t.Size)),
+		Strides: make([]int, len(t.Size)),
+	}
+
+	copy(v.Size, t.Size)
+	copy(v.Strides, t.tmpStrides)
+
+	return v
+}
+
+//GetDims returns the number of dimensions
+func (v *View) GetDims() int {
+	return len(v.Size)
+}
+
+//GetNumberOfValues returns the number of values in the view, which can be less than the length of Values
+func (v *View) GetNumberOfValues() int {
+	return SizeLength(v.Size)
+}
+
+//flat returns the position in Values of the value at index
+func (v *View) flat(index []int) int {
+	if len(index) != len(v.Size) {
+		panic("Index dimensions do not match View dimensions")
+	}
+
+	pos := v.Offset
+	for i := range index {
+		if index[i] < 0 || index[i] >= v.Size[i] {
+			panic("Index out of bounds")
+		}
+		pos += index[i] * v.Strides[i]
+	}
+
+	return pos
+}
+
+//GetVal returns the value at the index
+func (v *View) GetVal(index ...int) float64 {
+	return v.Values[v.flat(index)]
+}
+
+//SetVal sets the value at index to val
+func (v *View) SetVal(val float64, index ...int) {
+	v.Values[v.flat(index)] = val
+}
+
+//clone returns a copy of the view that shares the values
+func (v *View) clone() *View {
+	c := &View{
+		Values:  v.Values,
+		Size:    make([]int, len(v.Size)),
+		Strides: make([]int, len(v.Strides)),
+		Offset:  v.Offset,
+	}
+
+	copy(c.Size, v.Size)
+	copy(c.Strides, v.Strides)
+
+	return c
+}
+
+func (v *View) checkAxis(axis int) {
+	if axis < 0 || axis >= len(v.Size) {
+		panic(fmt.Sprintf("Axis %d out of range for a view with %d dimensions", axis, len(v.Size)))
+	}
+}
+
+//Range returns a view of the values from start (included) to end (excluded) in the given axis
+func (v *View) Range(axis, start, end int) *View {
+	return v.RangeStep(axis, start, end, 1)
+}
+
+//RangeStep returns a view of every step values from start (included) to end (excluded) in the given axis. For example, RangeStep(0, 0, n, 2) takes the even positions of the first dimension.
+func (v *View) RangeStep(axis, start, end, step int) *View {
+	v.checkAxis(axis)
+
+	if start < 0 || end > v.Size[axis] || start >= end || step <= 0 {
+		panic(fmt.Sprintf("Invalid range [%d, %d) with step %d in axis %d of size %d", start, end, step, axis, v.Size[axis]))
+	}
+
+	c := v.clone()
+	c.Offset += start * v.Strides[axis]
+	c.Size[axis] = (end-start-1)/step + 1
+	c.Strides[axis] *= step
+
+	return c
+}
+
+//Index returns the view of position i in the given axis, which has one dimension less
+func (v *View) Index(axis, i int) *View {
+	c := v.Range(axis, i, i+1)
+	return c.Squeeze(axis)
+}
+
+//Transpose returns the view with the axes a and b swapped
+func (v *View) Transpose(a, b int) *View {
+	v.checkAxis(a)
+	v.checkAxis(b)
+
+	c := v.clone()
+	c.Size[a], c.Size[b] = c.Size[b], c.Size[a]
+	c.Strides[a], c.Strides[b] = c.Strides[b], c.Strides[a]
+
+	return c
+}
+
+//Permute returns the view with the axes reordered, so the dimension i of the result is the dimension axes[i] of the view. All the axes must be given once.
+func (v *View) Permute(axes ...int) *View {
+	if len(axes) != len(v.Size) {
+		panic(fmt.Sprintf("Permute expects %d axes but got %d", len(v.Size), len(axes)))
+	}
+
+	c := v.clone()
+	used := make([]bool, len(axes))
+	for i, a := range axes {
+		v.checkAxis(a)
+		if used[a] {
+			panic(fmt.Sprintf("Axis %d is repeated in the permutation", a))
+		}
+		used[a] = true
+
+		c.Size[i] = v.Size[a]
+		c.Strides[i] = v.Strides[a]
+	}
+
+	return c
+}
+
+//Squeeze returns the view without the given axes, which must have size 1. Without axes, all the dimensions of size 1 are removed.
+func (v *View) Squeeze(axes ...int) *View {
+	remove := make([]bool, len(v.Size))
+	if len(axes) == 0 {
+		for i, s := range v.Size {
+			remove[i] = s == 1
+		}
+	}
+	for _, a := range axes {
+		v.checkAxis(a)
+		if v.Size[a] != 1 {
+			panic(fmt.Sprintf("Cannot squeeze axis %d of size %d", a, v.Size[a]))
+		}
+		remove[a] = true
+	}
+
+	c := &View{Values: v.Values, Offset: v.Offset, Size: []int{}, Strides: []int{}}
+	for i := range v.Size {
+		if !remove[i] {
+			c.Size = append(c.Size, v.Size[i])
+			c.Strides = append(c.Strides, v.Strides[i])
+		}
+	}
+
+	return c
+}
+
+//Unsqueeze returns the view with a new dimension of size 1 at the given axis. The axis can be equal to the number of dimensions to add it at the end.
+func (v *View) Unsqueeze(axis int) *View {
+	if axis < 0 || axis > len(v.Size) {
+		panic(fmt.Sprintf("Axis %d out of range for a view with %d dimensions", axis, len(v.Size)))
+	}
+
+	c := &View{Values: v.Values, Offset: v.Offset}
+	c.Size = append(append(append([]int{}, v.Size[:axis]...), 1), v.Size[axis:]...)
+	c.Strides = append(append(append([]int{}, v.Strides[:axis]...), 0), v.Strides[axis:]...)
+
+	return c
+}
+
+//IsContiguous returns true if the values of the view are stored one after the other in the same order as in a Tensor
+func (v *View) IsContiguous() bool {
+	stride := 1
+	for i := range v.Size {
+		if v.Size[i] != 1 && v.Strides[i] != stride {
+			return false
+		}
+		stride *= v.Size[i]
+	}
+
+	return true
+}
+
+//Reshape returns a view with the same values and a different size, without copying them. It is only possible if the view is contiguous, use Contiguous().View() for the others.
+func (v *View) Reshape(size ...int) (*View, error) {
+	if SizeLength(size) != v.GetNumberOfValues() {
+		return nil, fmt.Errorf("Cannot reshape a view of size %v to %v", v.Size, size)
+	}
+
+	if !v.IsContiguous() {
+		return nil, errors.New("Cannot reshape a view that is not contiguous without copying it")
+	}
+
+	c := &View{
+		Values:  v.Values,
+		Size:    make([]int, len(size)),
+		Strides: make([]int, len(size)),
+		Offset:  v.Offset,
+	}
+
+	copy(c.Size, size)
+	stride := 1
+	for i := range size {
+		c.Strides[i] = stride
+		stride *= size[i]
+	}
+
+	return c, nil
+}
+
+//each calls f with the position in Values of every value of the view, in the order of a Tensor (the first dimension is the fastest)
+func (v *View) each(f func(pos int)) {
+	if len(v.Size) == 0 {
+		f(v.Offset)
+		return
+	}
+
+	n := v.GetNumberOfValues()
+	index := make([]int, len(v.Size))
+	pos := v.Offset
+
+	for i := 0; i < n; i++ {
+		f(pos)
+
+		//Increase the index like a counter, moving the position by the stride of each dimension
+		for d := range index {
+			index[d]++
+			pos += v.Strides[d]
+			if index[d] < v.Size[d] {
+				break
+			}
+			pos -= index[d] * v.Strides[d]
+			index[d] = 0
+		}
+	}
+}
+
+//Contiguous returns a new Tensor with a copy of the values of the view. A view without dimensions (like the result of Squeeze on a single value) gives a tensor of size [1].
+func (v *View) Contiguous() *Tensor {
+	size := v.Size
+	if len(size) == 0 {
+		size = []int{1}
+	}
+
+	t := NewTensor(size...)
+
+	i := 0
+	v.each(func(pos int) {
+		t.Values[i] = v.Values[pos]
+		i++
+	})
+
+	return t
+}
+
+//CopyFrom copies the values of src into the view, which must have the same size. It can be used to write a region of a tensor, for example to paste an image into a bigger one.
+func (v *View) CopyFrom(src *View) error {
+	if !(&Tensor{Size: v.Size}).HasSize(src.Size) {
+		return fmt.Errorf("Cannot copy a view of size %v into a view of size %v", src.Size, v.Size)
+	}
+
+	values := src.Contiguous().Values
+
+	i := 0
+	v.each(func(pos int) {
+		v.Values[pos] = values[i]
+		i++
+	})
+
+	return nil
+}
+
+//Fill sets all the values of the view to val
+func (v *View) Fill(val float64) {
+	v.each(func(pos int) {
+		v.Values[pos] = val
+	})
+}
@@ -0,0 +1,134 @@
+package tensor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSequenceTensor(size ...int) *Tensor {
+	t := NewTensor(size...)
+	for i := range t.Values {
+		t.Values[i] = float64(i)
+	}
+	return t
+}
+
+func TestViewRange(t *testing.T) {
+	assert := assert.New(t)
+
+	//Values are 0..11 with size [3, 4], so the value at (x, y) is x + 3y
+	tt := newSequenceTensor(3, 4)
+
+	v := tt.View().Range(0, 1, 3)
+	assert.Equal([]int{2, 4}, v.Size)
+	assert.Equal([]float64{1, 2, 4, 5, 7, 8, 10, 11}, v.Contiguous().Values)
+	assert.False(v.IsContiguous())
+
+	v = tt.View().Range(1, 1, 3)
+	assert.Equal([]float64{3, 4, 5, 6, 7, 8}, v.Contiguous().Values)
+	assert.True(v.IsContiguous())
+
+	v = tt.View().RangeStep(1, 0, 4, 2).RangeStep(0, 0, 3, 2)
+	assert.Equal([]int{2, 2}, v.Size)
+	assert.Equal([]float64{0, 2, 6, 8}, v.Contiguous().Values)
+
+	v = tt.View().Index(1, 2)
+	assert.Equal([]int{3}, v.Size)
+	assert.Equal([]float64{6, 7, 8}, v.Contiguous().Values)
+
+	assert.Equal(tt.GetVal(2, 3), tt.View().GetVal(2, 3))
+	assert.Equal(tt.GetVal(2, 3), tt.View().Range(0, 1, 3).Range(1, 2, 4).GetVal(1, 1))
+
+	assert.Panics(func() { tt.View().Range(0, 2, 4) })
+	assert.Panics(func() { tt.View().Range(2, 0, 1) })
+}
+
+func TestViewTransposePermute(t *testing.T) {
+	assert := assert.New(t)
+
+	tt := newSequenceTensor(2, 3)
+
+	v := tt.View().Transpose(0, 1)
+	assert.Equal([]int{3, 2}, v.Size)
+	assert.Equal([]float64{0, 2, 4, 1, 3, 5}, v.Contiguous().Values)
+
+	//Transposing again gives back the tensor
+	assert.Equal(tt.Values, v.Transpose(0, 1).Contiguous().Values)
+
+	tt = newSequenceTensor(2, 3, 4)
+	v = tt.View().Permute(2, 0, 1)
+	assert.Equal([]int{4, 2, 3}, v.Size)
+	for x := 0; x < 2; x++ {
+		for y := 0; y < 3; y++ {
+			for z := 0; z < 4; z++ {
+				assert.Equal(tt.GetVal(x, y, z), v.GetVal(z, x, y))
+			}
+		}
+	}
+
+	c := v.Contiguous()
+	assert.Equal(v.Size, c.Size)
+	assert.Equal(tt.GetVal(1, 2, 3), c.GetVal(3, 1, 2))
+
+	assert.Panics(func() { tt.View().Permute(0, 0, 1) })
+	assert.Panics(func() { tt.View().Permute(0, 1) })
+}
+
+func TestViewSqueezeReshape(t *testing.T) {
+	assert := assert.New(t)
+
+	tt := newSequenceTensor(3, 1, 4)
+
+	v := tt.View().Squeeze()
+	assert.Equal([]int{3, 4}, v.Size)
+	assert.Equal(tt.Values, v.Contiguous().Values)
+
+	v = v.Unsqueeze(2)
+	assert.Equal([]int{3, 4, 1}, v.Size)
+	assert.Equal(tt.GetVal(2, 0, 3), v.GetVal(2, 3, 0))
+
+	assert.Panics(func() { tt.View().Squeeze(0) })
+
+	r, err := tt.View().Reshape(6, 2)
+	assert.NoError(err)
+	assert.Equal(tt.Values[7], r.GetVal(1, 1))
+
+	_, err = tt.View().Reshape(5, 2)
+	assert.Error(err)
+
+	//A transposed view cannot be reshaped without a copy
+	_, err = newSequenceTensor(2, 3).View().Transpose(0, 1).Reshape(6)
+	assert.Error(err)
+
+	//A single value
+	s := tt.View().Index(0, 1).Index(0, 0).Index(0, 2)
+	assert.Equal([]int{}, s.Size)
+	assert.Equal(tt.GetVal(1, 0, 2), s.GetVal())
+	assert.Equal([]float64{tt.GetVal(1, 0, 2)}, s.Contiguous().Values)
+}
+
+func TestViewWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	tt := NewTensor(4, 4)
+
+	//Views share the values with the tensor
+	tt.View().Range(0, 1, 3).Range(1, 1, 3).Fill(1)
+	assert.Equal([]float64{
+		0, 0, 0, 0,
+		0, 1, 1, 0,
+		0, 1, 1, 0,
+		0, 0, 0, 0,
+	}, tt.Values)
+
+	tt.View().Index(0, 0).SetVal(2, 3)
+	assert.Equal(2.0, tt.GetVal(0, 3))
+
+	src := newSequenceTensor(2, 2)
+	err := tt.View().Range(0, 2, 4).Range(1, 0, 2).CopyFrom(src.View().Transpose(0, 1))
+	assert.NoError(err)
+	assert.Equal([]float64{0, 2, 1, 3}, tt.View().Range(0, 2, 4).Range(1, 0, 2).Contiguous().Values)
+
+	assert.Error(tt.View().CopyFrom(src.View()))
+}
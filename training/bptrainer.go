@@ -1,14 +1,16 @@
 package training
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math"
+	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/gerardabello/weight"
 	"github.com/gerardabello/weight/debug"
+	"github.com/gerardabello/weight/tensor"
 )
 
 //BPTrainer trains a network given a train set and a learning configuration. It also returns debug information to monitor the process
@@ -25,15 +27,40 @@ type BPTrainer struct {
 	params []*float64
 	grads  [][]*float64
 
-	//Temp arrays to store values for momentum, adagrad, etc.
-	arr1 []float64
-	arr2 []float64
+	//Values of the layers that are not parameters but change during training, like the running statistics of batch normalization. See weight.StateLayer.
+	state []*float64
 
-	//parameters
-	eps   float64
-	ro    float64
-	beta1 float64
-	beta2 float64
+	optimizer Optimizer
+	schedule  LRSchedule
+
+	//Temp array to store the final gradient of each parameter before passing it to the optimizer
+	meanGrads []float64
+
+	//Position of the training process. It is stored in checkpoints so the training can be resumed.
+	epoch int
+	batch int
+
+	resuming bool
+
+	checkpointPath  string
+	checkpointEvery int
+
+	seed      int64
+	rngSource *countingSource
+	rng       *rand.Rand
+
+	//Seed of the train set in the current epoch. It is stored in checkpoints because the train set is seeded only at the beginning of the epoch.
+	dataSeed int64
+
+	stopper *earlyStopper
+
+	callbacks []Callback
+
+	//Learning rate used in the last update
+	learningRate float64
+
+	//True while Train is running
+	training bool
 }
 
 //NewBPTrainer creates a new BPTrainer
@@ -44,31 +71,51 @@ func NewBPTrainer(config LearningConfig, data *weight.PairSet, net weight.BPLear
 	t.data = data
 	t.costFunction = costFunction
 
-	p, _ := t.net.GetParamGradPointers()
-	t.arr1 = make([]float64, len(p))
-	t.arr2 = make([]float64, len(p))
+	t.optimizer = config.Optimizer
+	if t.optimizer == nil {
+		var err error
+		t.optimizer, err = config.newOptimizer()
+		if err != nil {
+			panic(err)
+		}
+	}
 
-	//TODO include this in learning params
-	t.eps = 1e-8
-	t.ro = 0.95
-	t.beta1 = 0.9   // used in adam
-	t.beta2 = 0.999 // used in adam
+	t.schedule = config.Schedule
+	if t.schedule == nil {
+		t.schedule = &ExponentialSchedule{Start: config.LearningRateStart, End: config.LearningRateEnd}
+	}
+
+	p, _ := t.net.GetParamGradPointers()
+	t.optimizer.Init(len(p))
+	t.meanGrads = make([]float64, len(p))
 
 	t.numRoutines = 4
 
+	t.seed = config.Seed
+	if t.seed == 0 {
+		t.seed = time.Now().UnixNano()
+	}
+	t.rngSource = newCountingSource(t.seed, 0)
+	t.rng = rand.New(t.rngSource)
+
 	return &t
 }
 
+//Rand returns the random source of the training process. Its state is saved in checkpoints. The trainer takes from it the seed of the train set at the beginning of each epoch (see weight.RandDataSet) and the seed of the layers before each batch (see weight.RandLayer), so with the same seed a training gives the same result, also if it is stopped and resumed from a checkpoint. Anything else that needs random numbers during the training, like a callback, should use it too.
+//
+//With more than one goroutine (see SetNumGoroutines) the results are not exactly reproducible, because the examples each goroutine takes from the train set change between runs.
+func (t *BPTrainer) Rand() *rand.Rand {
+	return t.rng
+}
+
 //SetDebugger sets the debugger to use during the train process
 func (t *BPTrainer) SetDebugger(debugger debug.NetDebugger) {
 	t.debugger = debugger
 }
 
-func (t *BPTrainer) updateParams(percent float64) {
-	k := int(percent * float64(t.config.BatchSize) * float64(t.config.Epochs))
-
-	//smooth transition between start and end
-	learningRate := (t.config.LearningRateEnd-t.config.LearningRateStart)*(-math.Pow(2, -10*(percent))+1) + t.config.LearningRateStart
+func (t *BPTrainer) updateParams(step, steps int) {
+	learningRate := t.schedule.LearningRate(step, steps)
+	t.learningRate = learningRate
 
 	for p := 0; p < len(t.params); p++ {
 		grad := 0.0
@@ -85,29 +132,22 @@ func (t *BPTrainer) updateParams(percent float64) {
 		l2grad := t.config.WeightDecay * (*t.params[p])
 
 		//final gradient calculation
-		grad = (grad + l2grad) / float64(t.config.BatchSize)
-
-		switch t.config.Method {
-		case Momentum:
-			dx := -grad*learningRate + t.arr1[p]
-			(*t.params[p]) += dx
-			t.arr1[p] = dx * t.config.Momentum
-		case AdaDelta:
-			t.arr1[p] = t.ro*t.arr1[p] + (1-t.ro)*grad*grad
-			dx := -math.Sqrt((t.arr2[p]+t.eps)/(t.arr1[p]+t.eps)) * grad
-			t.arr2[p] = t.ro*t.arr2[p] + (1-t.ro)*dx*dx // yes, arr2 lags behind arr1 by 1.
-			(*t.params[p]) += dx
-		case Adam:
-			t.arr1[p] = t.arr1[p]*t.beta1 + (1-t.beta1)*grad                // update biased first moment estimate
-			t.arr2[p] = t.arr2[p]*t.beta2 + (1-t.beta2)*grad*grad           // update biased second moment estimate
-			var biasCorr1 = t.arr1[p] * (1 - math.Pow(t.beta1, float64(k))) // correct bias first moment estimate
-			var biasCorr2 = t.arr2[p] * (1 - math.Pow(t.beta2, float64(k))) // correct bias second moment estimate
-			var dx = -learningRate * biasCorr1 / (math.Sqrt(biasCorr2) + t.eps)
-			(*t.params[p]) += dx
-		}
+		t.meanGrads[p] = (grad + l2grad) / float64(t.config.BatchSize)
+	}
 
+	if po, ok := t.optimizer.(progressOptimizer); ok {
+		po.setProgress(step, steps)
 	}
+	t.optimizer.Update(t.params, t.meanGrads, learningRate)
+}
 
+//zeroGrads sets the gradients of all goroutines to zero
+func (t *BPTrainer) zeroGrads() {
+	for g := range t.grads {
+		for p := range t.grads[g] {
+			*(t.grads[g][p]) = 0
+		}
+	}
 }
 
 //SetNumGoroutines sets the number of parallel goroutines that will train a part of each batch independently
@@ -121,6 +161,11 @@ func (t *BPTrainer) SetNumGoroutines(nt int) error {
 
 //Train tries to perform gradient descent using backpropagation
 func (t *BPTrainer) Train() error {
+	return t.TrainContext(context.Background())
+}
+
+//TrainContext is like Train, but stops when ctx is done. The cancellation is checked between batches, so the batch in progress is always finished. If checkpoints are enabled (see SetCheckpoint), one is saved before returning, so the training can be resumed later. It returns ctx.Err() if the training was stopped.
+func (t *BPTrainer) TrainContext(ctx context.Context) error {
 	//Everything inline to increase performance
 
 	if t.config.BatchSize == 0 {
@@ -158,6 +203,7 @@ func (t *BPTrainer) Train() error {
 	var pg1 []*float64
 	t.params, pg1 = t.net.GetParamGradPointers()
 	t.grads = [][]*float64{pg1}
+	t.state = weight.GetStatePointers(t.net)
 
 	//If we use more than 1 gorotuine, create slave layers to run in parallel
 	if t.numRoutines > 1 {
@@ -177,6 +223,22 @@ func (t *BPTrainer) Train() error {
 		}
 	}
 
+	//Layers like dropout behave differently while training. Leave the network in inference mode when the training ends.
+	for _, l := range layers {
+		weight.SetTraining(l, true)
+	}
+	t.training = true
+	defer func() {
+		t.training = false
+		weight.SetTraining(t.net, false)
+	}()
+
+	//If all the layers support it, each goroutine activates its part of the batch at once. See weight.BatchLayer.
+	batched := weight.SupportsBatch(t.net)
+	if batched && len(status) < cap(status) {
+		status <- "Training with batched layers"
+	}
+
 	//Number of batches
 	nbatch := t.data.TrainSet.GetSetSize() / t.config.BatchSize
 
@@ -184,6 +246,11 @@ func (t *BPTrainer) Train() error {
 
 	//temp variables to store accuracy, time, loss, etc.
 	accMutex := &sync.Mutex{}
+	batchCost := 0.0
+	batchCorrect := 0
+	epochCost := 0.0
+	epochCorrect := 0
+	epochBatches := 0
 	accCost := 0.0
 	accAcTime := 0.0
 	accBpTime := 0.0
@@ -194,27 +261,130 @@ func (t *BPTrainer) Train() error {
 
 	tt := time.Now()
 
-	for n := 0; n < t.config.Epochs; n++ {
+	//If we are not resuming a training, start from the beginning
+	if !t.resuming {
+		t.epoch = 0
+		t.batch = 0
+
+		if t.stopper != nil {
+			t.stopper.reset()
+		}
+	}
+	t.resuming = false
+
+	err := t.callback("OnTrainBegin", func(c Callback) error { return c.OnTrainBegin(t) })
+	if err != nil {
+		return err
+	}
+
+	//A training resumed from a checkpoint saved after stopping early does not run more epochs
+	for t.epoch < t.config.Epochs && (t.stopper == nil || !t.stopper.stopped) {
+		n := t.epoch
 		if len(status) < cap(status) {
 			status <- fmt.Sprintf("Starting training of epoch %d", n)
 		}
-		for i := 0; i < nbatch; i++ {
+
+		//A resumed epoch keeps the seed it had when the checkpoint was saved
+		if t.batch == 0 {
+			t.dataSeed = t.rng.Int63()
+		}
+		weight.SeedDataSet(t.data.TrainSet, t.dataSeed)
+		t.data.TrainSet.Reset()
+
+		//Move the train set to the position where the training was stopped
+		for j := 0; j < t.batch*t.config.BatchSize; j++ {
+			_, _, err := t.data.TrainSet.GetNextSet()
+			if err != nil {
+				return err
+			}
+		}
+
+		err := t.callback("OnEpochBegin", func(c Callback) error { return c.OnEpochBegin(t, n) })
+		if err != nil {
+			return err
+		}
+
+		epochCost = 0.0
+		epochCorrect = 0
+		epochBatches = 0
+
+		for t.batch < nbatch {
+			i := t.batch
+
+			if ctx.Err() != nil {
+				if t.checkpointPath != "" {
+					err := t.SaveCheckpoint(t.checkpointPath)
+					if err != nil {
+						return err
+					}
+				}
+
+				if len(status) < cap(status) {
+					status <- fmt.Sprintf("Training stopped in epoch %d, batch %d: %s", n, i, ctx.Err().Error())
+				}
+				return ctx.Err()
+			}
+
+			for _, l := range layers {
+				weight.Seed(l, t.rng.Int63())
+			}
 
 			//Calculate the number of training items each routine is gonna compute
 			sbs := t.config.BatchSize / t.numRoutines
 
+			//The first error of any goroutine is stored here. The rest of goroutines stop before their next item.
+			var batchErr error
+			errMutex := &sync.Mutex{}
+			setErr := func(err error) {
+				errMutex.Lock()
+				if batchErr == nil {
+					batchErr = err
+				}
+				errMutex.Unlock()
+			}
+			hasErr := func() bool {
+				errMutex.Lock()
+				defer errMutex.Unlock()
+				return batchErr != nil
+			}
+
 			for g := 0; g < t.numRoutines; g++ {
 				wg.Add(1)
 				go func(goroutineIndex, subBatchSize int) {
 					// Decrement the counter when the goroutine completes.
 					defer wg.Done()
 
-					for j := 0; j < subBatchSize; j++ {
+					//A panic in a layer, cost function or data set should not kill the whole program
+					defer func() {
+						if r := recover(); r != nil {
+							setErr(fmt.Errorf("panic: %v", r))
+						}
+					}()
+
+					if batched {
+						tm := time.Now()
+
+						cost, correct, err := t.trainBatch(layers[goroutineIndex].(weight.BatchLayer), costFuncs[goroutineIndex], subBatchSize)
+						if err != nil {
+							setErr(err)
+							return
+						}
+
+						accMutex.Lock()
+						batchCost += cost
+						batchCorrect += correct
+						accAcTime += time.Since(tm).Seconds()
+						accMutex.Unlock()
+						return
+					}
+
+					for j := 0; j < subBatchSize && !hasErr(); j++ {
 
 						//Get next data and answer
 						input, ans, err := t.data.TrainSet.GetNextSet()
 						if err != nil {
-							panic(err.Error())
+							setErr(fmt.Errorf("Could not get next item from train set: %s", err.Error()))
+							return
 						}
 
 						tm := time.Now()
@@ -222,28 +392,28 @@ func (t *BPTrainer) Train() error {
 						//Activate the Sequential
 						out, err := layers[goroutineIndex].Activate(input)
 						if err != nil {
-							panic(err.Error())
+							setErr(fmt.Errorf("Activation failed: %s", err.Error()))
+							return
 						}
 
 						//Calculate cost function of classification
 						cost := costFuncs[goroutineIndex].Cost(out, ans)
 
-						if t.debugger != nil {
-							accMutex.Lock()
-							accCost += cost
-							accAcTime += time.Since(tm).Seconds()
-							if t.data.TrainSet.IsAnswer(out, ans) {
-								nCorrect++
-							}
-							accMutex.Unlock()
+						accMutex.Lock()
+						batchCost += cost
+						accAcTime += time.Since(tm).Seconds()
+						if t.data.TrainSet.IsAnswer(out, ans) {
+							batchCorrect++
 						}
+						accMutex.Unlock()
 
 						tm = time.Now()
 						//Backpropagate = calculate gradients for all params. (we have pointers to all of them in t.grads)
 						_, err = layers[goroutineIndex].BackPropagate(costFuncs[goroutineIndex].BackPropagate())
 
 						if err != nil {
-							panic(err.Error())
+							setErr(fmt.Errorf("Backpropagation failed: %s", err.Error()))
+							return
 						}
 
 						if t.debugger != nil {
@@ -260,7 +430,31 @@ func (t *BPTrainer) Train() error {
 			//Syncronization
 			wg.Wait()
 
+			if batchErr != nil {
+				//Discard the gradients of the failed batch
+				t.zeroGrads()
+
+				err := fmt.Errorf("Training failed in epoch %d, batch %d: %s", n, i, batchErr.Error())
+				if len(status) < cap(status) {
+					status <- err.Error()
+				}
+				return err
+			}
+
+			epochCost += batchCost
+			epochCorrect += batchCorrect
+			epochBatches++
+
+			batchMetrics := Metrics{
+				Epoch:    n,
+				Batch:    i,
+				Loss:     batchCost / float64(t.config.BatchSize),
+				Accuracy: float64(batchCorrect) / float64(t.config.BatchSize),
+			}
+
 			if t.debugger != nil {
+				accCost += batchCost
+				nCorrect += batchCorrect
 				cBatch++
 				clog += t.config.BatchSize
 
@@ -302,41 +496,101 @@ func (t *BPTrainer) Train() error {
 				}
 			}
 
-			t.updateParams(float64(nbatch*n+i) / float64(nbatch*t.config.Epochs))
-		}
+			batchCost = 0.0
+			batchCorrect = 0
 
-		if t.debugger != nil {
-			//If no one is listening to testInfo, dont calculate it
-			if len(testInfo) < cap(testInfo) {
+			t.updateParams(nbatch*n+i, nbatch*t.config.Epochs)
 
-				if len(status) < cap(status) {
-					status <- fmt.Sprintf("Starting testing of epoch %d", n)
-				}
+			t.batch++
 
-				accuracy, loss, err := t.Test()
+			batchMetrics.LearningRate = t.learningRate
+			err := t.callback("OnBatchEnd", func(c Callback) error { return c.OnBatchEnd(t, batchMetrics) })
+			if err != nil {
+				return err
+			}
+
+			if t.checkpointPath != "" && t.checkpointEvery > 0 && (nbatch*n+t.batch)%t.checkpointEvery == 0 {
+				err := t.SaveCheckpoint(t.checkpointPath)
 				if err != nil {
 					return err
 				}
+			}
+		}
 
-				/*
-				     fmt.Printf("\rTrainAccuracy:%.4f TrainLoss:%.6f TestAccuracy:%.4f --- \n",
-				     float64(epochCorrectTrains)/float64(t.data.TrainSet.GetSetSize()),
-				     epochTrainLoss/float64(t.data.TrainSet.GetSetSize()),
-				     accuracy,
-				   )
-				*/
+		stop := false
 
+		//Test the network only if someone needs the result: the debugger (if it is listening to testInfo), the learning rate schedule, the early stopping or the callbacks
+		debugTest := t.debugger != nil && len(testInfo) < cap(testInfo)
+		if debugTest || needsLoss(t.schedule) || t.stopper != nil || len(t.callbacks) > 0 {
+
+			if len(status) < cap(status) {
+				status <- fmt.Sprintf("Starting testing of epoch %d", n)
+			}
+
+			accuracy, loss, err := t.Test()
+			if err != nil {
+				return err
+			}
+
+			if debugTest {
 				testInfo <- &debug.TestInfo{
 					Epoch:    n,
 					Loss:     loss,
 					Accuracy: accuracy,
 				}
+			}
 
+			if lo, ok := t.schedule.(LossObserver); ok {
+				lo.ObserveLoss(loss)
+			}
+
+			if t.stopper != nil {
+				stop = t.stopper.observe(accuracy, loss, t.params, t.state)
+			}
+
+			testMetrics := Metrics{Epoch: n, Batch: t.batch, Loss: loss, Accuracy: accuracy, LearningRate: t.learningRate}
+			err = t.callback("OnTestEnd", func(c Callback) error { return c.OnTestEnd(t, testMetrics) })
+			if err != nil {
+				return err
 			}
 		}
 
-		//Reset to start new epoch
-		t.data.TrainSet.Reset()
+		epochMetrics := Metrics{Epoch: n, Batch: epochBatches, LearningRate: t.learningRate}
+		if epochBatches > 0 {
+			epochMetrics.Loss = epochCost / float64(epochBatches*t.config.BatchSize)
+			epochMetrics.Accuracy = float64(epochCorrect) / float64(epochBatches*t.config.BatchSize)
+		}
+		err = t.callback("OnEpochEnd", func(c Callback) error { return c.OnEpochEnd(t, epochMetrics) })
+		if err != nil {
+			return err
+		}
+
+		//Start a new epoch. The train set is reset at the beginning of the next one.
+		t.epoch++
+		t.batch = 0
+
+		if t.checkpointPath != "" && t.checkpointEvery == 0 {
+			err := t.SaveCheckpoint(t.checkpointPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		if stop {
+			if len(status) < cap(status) {
+				status <- fmt.Sprintf("Stopping early after epoch %d", n)
+			}
+			break
+		}
+	}
+
+	if t.stopper != nil {
+		t.stopper.restore(t.params, t.state)
+	}
+
+	err = t.callback("OnTrainEnd", func(c Callback) error { return c.OnTrainEnd(t) })
+	if err != nil {
+		return err
 	}
 
 	if len(status) < cap(status) {
@@ -346,7 +600,67 @@ func (t *BPTrainer) Train() error {
 	return nil
 }
 
+//trainBatch takes n items of the train set, activates and backpropagates them as a single batch and returns the sum of their costs and the number of correct answers
+func (t *BPTrainer) trainBatch(layer weight.BatchLayer, costFunc weight.BPCostFunc, n int) (float64, int, error) {
+	inSize := layer.GetInputSize()
+	outSize := layer.GetOutputSize()
+	ni := tensor.SizeLength(inSize)
+	no := tensor.SizeLength(outSize)
+
+	input := tensor.NewTensor(append(append([]int{}, inSize...), n)...)
+	answers := make([]*tensor.Tensor, n)
+
+	for j := 0; j < n; j++ {
+		in, ans, err := t.data.TrainSet.GetNextSet()
+		if err != nil {
+			return 0, 0, fmt.Errorf("Could not get next item from train set: %s", err.Error())
+		}
+
+		if !in.HasSize(inSize) {
+			return 0, 0, fmt.Errorf("Activation failed: Layer has input size %v but input is size %v", inSize, in.Size)
+		}
+
+		copy(input.Values[j*ni:(j+1)*ni], in.Values)
+
+		//The data set may reuse the tensor of the answer
+		answers[j] = ans.Copy()
+	}
+
+	out, err := layer.ActivateBatch(input)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Activation failed: %s", err.Error())
+	}
+
+	//The cost is computed for each item, with a tensor that shares its part of the output
+	cost := 0.0
+	correct := 0
+	errs := tensor.NewTensor(out.Size...)
+	for j := 0; j < n; j++ {
+		o := &tensor.Tensor{Size: outSize, Values: out.Values[j*no : (j+1)*no]}
+
+		cost += costFunc.Cost(o, answers[j])
+		if t.data.TrainSet.IsAnswer(o, answers[j]) {
+			correct++
+		}
+
+		copy(errs.Values[j*no:(j+1)*no], costFunc.BackPropagate().Values)
+	}
+
+	_, err = layer.BackPropagateBatch(errs)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Backpropagation failed: %s", err.Error())
+	}
+
+	return cost, correct, nil
+}
+
+//Test returns the accuracy and the mean loss of the network on the test set. The network is activated in inference mode (see weight.ModeLayer).
 func (t *BPTrainer) Test() (accuracy, loss float64, err error) {
+	//Test in inference mode, and go back to training mode if the test is done during the training
+	weight.SetTraining(t.net, false)
+	if t.training {
+		defer weight.SetTraining(t.net, true)
+	}
 
 	ds := t.data.TestSet
 
@@ -355,14 +669,14 @@ func (t *BPTrainer) Test() (accuracy, loss float64, err error) {
 	ncorrect := 0
 	totalloss := 0.0
 	for i := 0; i < n; i++ {
-		input, lbl, e := ds.GetNextSet()
-		if e != nil {
-			return 0, 0, err
+		input, lbl, err := ds.GetNextSet()
+		if err != nil {
+			return 0, 0, fmt.Errorf("Could not get item %d from test set: %s", i, err.Error())
 		}
 
-		out, e := t.net.Activate(input)
+		out, err := t.net.Activate(input)
 		if err != nil {
-			return 0, 0, err
+			return 0, 0, fmt.Errorf("Activation of test item %d failed: %s", i, err.Error())
 		}
 
 		if ds.IsAnswer(out, lbl) {
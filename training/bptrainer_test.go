@@ -0,0 +1,83 @@
+package training
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//countBatches counts the trained batches and whether the training ended
+type countBatches struct {
+	BaseCallback
+	batches int
+	ended   bool
+}
+
+func (c *countBatches) OnBatchEnd(t *BPTrainer, m Metrics) error {
+	c.batches++
+	return nil
+}
+
+func (c *countBatches) OnTrainEnd(t *BPTrainer) error {
+	c.ended = true
+	return nil
+}
+
+func TestTrainContextCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	net := newTestNet(t, 1)
+	initial := paramValues(net)
+
+	trainer := newTestTrainer(net, newTestData(16))
+	c := &countBatches{}
+	trainer.AddCallback(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := trainer.TrainContext(ctx)
+	assert.Equal(context.Canceled, err, "A cancelled context should stop the training")
+	assert.Equal(0, c.batches, "No batch should be trained with a cancelled context")
+	assert.False(c.ended, "OnTrainEnd should not be called when the training is cancelled")
+	assert.Equal(initial, paramValues(net), "The parameters should not change")
+}
+
+func TestTrainContextCancelDuringTraining(t *testing.T) {
+	assert := assert.New(t)
+
+	trainer := newTestTrainer(newTestNet(t, 1), newTestData(16))
+	c := &countBatches{}
+	trainer.AddCallback(c)
+
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	err := trainer.SetCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Cancelled after the second batch of the second epoch. The batch in progress is finished.
+	ctx, cancel := context.WithCancel(context.Background())
+	trainer.AddCallback(&cancelAt{epoch: 1, batch: 1, cancel: cancel})
+
+	err = trainer.TrainContext(ctx)
+	assert.Equal(context.Canceled, err, "The training should return the error of the context")
+	assert.Equal(6, c.batches, "The training should stop after the batch where it was cancelled")
+	assert.False(c.ended, "OnTrainEnd should not be called when the training is cancelled")
+	assert.Equal(1, trainer.Epoch(), "The training should stop in the second epoch")
+
+	_, err = os.Stat(path)
+	assert.NoError(err, "A checkpoint should be saved when the training is cancelled")
+
+	//Training again starts from the beginning
+	c.batches = 0
+	err = trainer.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(12, c.batches, "A new training should run all the batches")
+	assert.True(c.ended, "OnTrainEnd should be called when the training finishes")
+}
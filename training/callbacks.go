@@ -0,0 +1,89 @@
+package training
+
+import "fmt"
+
+//Metrics contains the results of a part of the training
+type Metrics struct {
+	Epoch int
+	Batch int
+
+	//Mean loss and accuracy on the items of the batch or epoch. In OnTestEnd, they are the results on the test set.
+	Loss     float64
+	Accuracy float64
+
+	//Learning rate used in the last update of the parameters
+	LearningRate float64
+}
+
+//Callback is notified synchronously of the progress of the training. Unlike debug.NetDebugger, the training waits for each call to return, so callbacks can safely read and modify the trainer (parameters, learning rate schedule, checkpoints...). If a method returns an error, the training stops and returns it.
+type Callback interface {
+	//OnTrainBegin is called before the first batch is trained, also when resuming a training
+	OnTrainBegin(t *BPTrainer) error
+
+	//OnEpochBegin is called before the first batch of each epoch
+	OnEpochBegin(t *BPTrainer, epoch int) error
+
+	//OnBatchEnd is called after the parameters have been updated with the gradients of a batch
+	OnBatchEnd(t *BPTrainer, m Metrics) error
+
+	//OnTestEnd is called at the end of each epoch with the results on the test set. If the trainer has callbacks, it always tests the network after each epoch. It is not called when Test is used directly.
+	OnTestEnd(t *BPTrainer, m Metrics) error
+
+	//OnEpochEnd is called after the last batch of each epoch, and after the test if there is one. Batch is the number of batches trained in the epoch.
+	OnEpochEnd(t *BPTrainer, m Metrics) error
+
+	//OnTrainEnd is called when the training finishes successfully, including when it is stopped early. It is not called if the training fails or is cancelled.
+	OnTrainEnd(t *BPTrainer) error
+}
+
+//BaseCallback implements all the methods of Callback doing nothing. Embed it to implement only the methods you need.
+type BaseCallback struct{}
+
+func (BaseCallback) OnTrainBegin(t *BPTrainer) error            { return nil }
+func (BaseCallback) OnEpochBegin(t *BPTrainer, epoch int) error { return nil }
+func (BaseCallback) OnBatchEnd(t *BPTrainer, m Metrics) error   { return nil }
+func (BaseCallback) OnTestEnd(t *BPTrainer, m Metrics) error    { return nil }
+func (BaseCallback) OnEpochEnd(t *BPTrainer, m Metrics) error   { return nil }
+func (BaseCallback) OnTrainEnd(t *BPTrainer) error              { return nil }
+
+//AddCallback adds a callback to the trainer. Callbacks are called in the order they were added.
+func (t *BPTrainer) AddCallback(c Callback) {
+	t.callbacks = append(t.callbacks, c)
+}
+
+//callback calls f for each callback and stops at the first error
+func (t *BPTrainer) callback(name string, f func(c Callback) error) error {
+	for _, c := range t.callbacks {
+		err := f(c)
+		if err != nil {
+			return fmt.Errorf("Callback %s failed in epoch %d, batch %d: %s", name, t.epoch, t.batch, err.Error())
+		}
+	}
+	return nil
+}
+
+//Params returns pointers to all the parameters of the network, in the order given by GetParamGradPointers
+func (t *BPTrainer) Params() []*float64 {
+	params, _ := t.net.GetParamGradPointers()
+	return params
+}
+
+//Epoch returns the current epoch of the training
+func (t *BPTrainer) Epoch() int {
+	return t.epoch
+}
+
+//LearningRate returns the learning rate used in the last update of the parameters
+func (t *BPTrainer) LearningRate() float64 {
+	return t.learningRate
+}
+
+//Schedule returns the learning rate schedule
+func (t *BPTrainer) Schedule() LRSchedule {
+	return t.schedule
+}
+
+//SetSchedule replaces the learning rate schedule. It can be used during the training, for example from a callback.
+func (t *BPTrainer) SetSchedule(s LRSchedule) {
+	t.schedule = s
+}
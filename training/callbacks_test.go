@@ -0,0 +1,149 @@
+package training
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/debug"
+)
+
+//recorder writes the name of each call to log
+type recorder struct {
+	name string
+	log  *[]string
+
+	epochs []Metrics
+}
+
+func (r *recorder) add(format string, a ...interface{}) {
+	*r.log = append(*r.log, r.name+" "+fmt.Sprintf(format, a...))
+}
+
+func (r *recorder) OnTrainBegin(t *BPTrainer) error {
+	r.add("TrainBegin")
+	return nil
+}
+
+func (r *recorder) OnEpochBegin(t *BPTrainer, epoch int) error {
+	r.add("EpochBegin %d", epoch)
+	return nil
+}
+
+func (r *recorder) OnBatchEnd(t *BPTrainer, m Metrics) error {
+	r.add("BatchEnd %d %d", m.Epoch, m.Batch)
+	return nil
+}
+
+func (r *recorder) OnTestEnd(t *BPTrainer, m Metrics) error {
+	r.add("TestEnd %d", m.Epoch)
+	return nil
+}
+
+func (r *recorder) OnEpochEnd(t *BPTrainer, m Metrics) error {
+	r.add("EpochEnd %d", m.Epoch)
+	r.epochs = append(r.epochs, m)
+	return nil
+}
+
+func (r *recorder) OnTrainEnd(t *BPTrainer) error {
+	r.add("TrainEnd")
+	return nil
+}
+
+func TestCallbackOrder(t *testing.T) {
+	log := []string{}
+
+	//3 epochs of 2 batches
+	trainer := newTestTrainer(newTestNet(t, 1), newTestData(8))
+	trainer.AddCallback(&recorder{name: "a", log: &log})
+	trainer.AddCallback(&recorder{name: "b", log: &log})
+
+	err := trainer.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"TrainBegin"}
+	for e := 0; e < 3; e++ {
+		expected = append(expected,
+			fmt.Sprintf("EpochBegin %d", e),
+			fmt.Sprintf("BatchEnd %d 0", e),
+			fmt.Sprintf("BatchEnd %d 1", e),
+			fmt.Sprintf("TestEnd %d", e),
+			fmt.Sprintf("EpochEnd %d", e),
+		)
+	}
+	expected = append(expected, "TrainEnd")
+
+	//Each call goes to all the callbacks, in the order they were added
+	var both []string
+	for _, call := range expected {
+		both = append(both, "a "+call, "b "+call)
+	}
+
+	assert.Equal(t, both, log, "Callbacks should be called in order")
+}
+
+type failingCallback struct {
+	BaseCallback
+}
+
+func (failingCallback) OnBatchEnd(t *BPTrainer, m Metrics) error {
+	return fmt.Errorf("failed")
+}
+
+func TestCallbackError(t *testing.T) {
+	log := []string{}
+
+	trainer := newTestTrainer(newTestNet(t, 1), newTestData(8))
+	trainer.AddCallback(failingCallback{})
+	trainer.AddCallback(&recorder{name: "a", log: &log})
+
+	err := trainer.Train()
+	assert.Error(t, err, "An error in a callback should stop the training")
+	assert.Equal(t, []string{"a TrainBegin", "a EpochBegin 0"}, log, "The callbacks after the failing one should not be called")
+}
+
+//testDebugger sends the train info it receives to train
+type testDebugger struct {
+	train chan *debug.TrainInfo
+}
+
+func (d *testDebugger) Debug(status <-chan string, layerInfo <-chan []*debug.LayerInfo, trainInfo <-chan *debug.TrainInfo, testInfo <-chan *debug.TestInfo) {
+	for ti := range trainInfo {
+		select {
+		case d.train <- ti:
+		default:
+		}
+	}
+}
+
+func TestDebuggerTrainInfo(t *testing.T) {
+	trainer := newTestTrainer(newTestNet(t, 1), newTestData(8))
+
+	d := &testDebugger{train: make(chan *debug.TrainInfo, 1)}
+	trainer.SetDebugger(d)
+
+	log := []string{}
+	r := &recorder{name: "a", log: &log}
+	trainer.AddCallback(r)
+
+	err := trainer.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ti := <-d.train:
+		//The train info is sent at the end of each epoch (or every 2 seconds), so the first one has the results of the first epoch
+		m := r.epochs[ti.Epoch]
+		assert.True(t, ti.Loss > 0, "The debugger should receive the train loss")
+		assert.InDelta(t, m.Loss, ti.Loss, 1e-12, "The debugger should receive the mean loss of the epoch")
+		assert.InDelta(t, m.Accuracy, ti.Accuracy, 1e-12, "The debugger should receive the accuracy of the epoch")
+	case <-time.After(5 * time.Second):
+		t.Fatal("The debugger did not receive train info")
+	}
+}
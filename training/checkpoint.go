@@ -0,0 +1,251 @@
+package training
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+
+	"github.com/gerardabello/weight"
+)
+
+//checkpointVersion is increased every time the checkpoint structure changes in an incompatible way
+const checkpointVersion = 6
+
+//checkpoint contains everything needed to continue a training exactly where it stopped
+type checkpoint struct {
+	Version int
+
+	//Position of the training. Batch is the next batch to train in Epoch, so it is also the position in the train set (Batch*BatchSize).
+	Epoch int
+	Batch int
+
+	//Values of the network parameters, in the order given by GetParamGradPointers
+	Params []float64
+
+	//Values of the state of the layers, like the running statistics of batch normalization, in the order given by GetStatePointers. See weight.StateLayer.
+	State []float64
+
+	//Optimizer state (moments, accumulated gradients, etc.). Empty if the optimizer does not implement StatefulOptimizer.
+	OptimizerState [][]float64
+
+	//Learning rate schedule state. The position in the schedule is given by Epoch and Batch. Empty if the schedule does not implement StatefulSchedule.
+	ScheduleState [][]float64
+
+	//Early stopping state. EarlyStoppingParams and EarlyStoppingState are empty if early stopping is disabled or no epoch has been tested yet. EarlyStopped is true if the training was stopped early, so resuming it only restores the best parameters.
+	EarlyStoppingBest   float64
+	EarlyStoppingWait   int
+	EarlyStoppingParams []float64
+	EarlyStoppingState  []float64
+	EarlyStopped        bool
+
+	//State of the random source
+	Seed      int64
+	RandDraws uint64
+
+	//Seed of the train set in Epoch
+	DataSeed int64
+}
+
+//SetCheckpoint makes the trainer save a checkpoint to path every 'every' batches. If every is 0, a checkpoint is saved at the end of each epoch. Use an empty path to disable checkpoints.
+func (t *BPTrainer) SetCheckpoint(path string, every int) error {
+	if every < 0 {
+		return errors.New("Checkpoint interval cannot be negative")
+	}
+	t.checkpointPath = path
+	t.checkpointEvery = every
+	return nil
+}
+
+//SaveCheckpoint writes the current state of the training to path. The file is written atomically, so an interruption while saving does not corrupt the previous checkpoint.
+func (t *BPTrainer) SaveCheckpoint(path string) error {
+	params, _ := t.net.GetParamGradPointers()
+	state := weight.GetStatePointers(t.net)
+
+	cp := checkpoint{
+		Version:   checkpointVersion,
+		Epoch:     t.epoch,
+		Batch:     t.batch,
+		Params:    make([]float64, len(params)),
+		State:     make([]float64, len(state)),
+		Seed:      t.seed,
+		RandDraws: t.rngSource.draws,
+		DataSeed:  t.dataSeed,
+	}
+
+	if so, ok := t.optimizer.(StatefulOptimizer); ok {
+		cp.OptimizerState = so.State()
+	}
+
+	if ss, ok := t.schedule.(StatefulSchedule); ok {
+		cp.ScheduleState = ss.State()
+	}
+
+	if t.stopper != nil {
+		cp.EarlyStoppingBest = t.stopper.best
+		cp.EarlyStoppingWait = t.stopper.wait
+		cp.EarlyStoppingParams = t.stopper.bestVals
+		cp.EarlyStoppingState = t.stopper.bestState
+		cp.EarlyStopped = t.stopper.stopped
+	}
+
+	for i := range params {
+		cp.Params[i] = *params[i]
+	}
+	for i := range state {
+		cp.State[i] = *state[i]
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(f).Encode(&cp)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+//LoadCheckpoint restores the state of the training saved in path. The trainer must have been created with the same network and configuration used when the checkpoint was saved. The next call to Train will continue from the restored position.
+func (t *BPTrainer) LoadCheckpoint(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cp := checkpoint{}
+	err = gob.NewDecoder(f).Decode(&cp)
+	if err != nil {
+		return err
+	}
+
+	if cp.Version != checkpointVersion {
+		return fmt.Errorf("Checkpoint version %d is not supported", cp.Version)
+	}
+
+	params, _ := t.net.GetParamGradPointers()
+	if len(params) != len(cp.Params) {
+		return fmt.Errorf("Checkpoint has %d parameters but the network has %d", len(cp.Params), len(params))
+	}
+
+	state := weight.GetStatePointers(t.net)
+	if len(state) != len(cp.State) {
+		return fmt.Errorf("Checkpoint has %d state values but the network has %d", len(cp.State), len(state))
+	}
+
+	if cp.Epoch < 0 || cp.Epoch > t.config.Epochs || cp.Batch < 0 {
+		return fmt.Errorf("Checkpoint position (epoch %d, batch %d) is not valid for this configuration", cp.Epoch, cp.Batch)
+	}
+
+	//A checkpoint saved after the last batch of an epoch has Batch equal to the number of batches
+	nbatch := t.data.TrainSet.GetSetSize() / t.config.BatchSize
+	if cp.Batch > nbatch || (cp.Epoch == t.config.Epochs && cp.Batch != 0) {
+		return fmt.Errorf("Checkpoint is at batch %d of epoch %d, but epochs have %d batches of %d items with this train set. The checkpoint was saved with a different train set or batch size", cp.Batch, cp.Epoch, nbatch, t.config.BatchSize)
+	}
+
+	for i := range params {
+		*params[i] = cp.Params[i]
+	}
+	for i := range state {
+		*state[i] = cp.State[i]
+	}
+
+	if so, ok := t.optimizer.(StatefulOptimizer); ok {
+		err = so.SetState(cp.OptimizerState)
+		if err != nil {
+			return fmt.Errorf("Checkpoint optimizer state does not match the optimizer: %s", err.Error())
+		}
+	}
+
+	if ss, ok := t.schedule.(StatefulSchedule); ok {
+		err = ss.SetState(cp.ScheduleState)
+		if err != nil {
+			return fmt.Errorf("Checkpoint schedule state does not match the schedule: %s", err.Error())
+		}
+	}
+
+	if t.stopper != nil {
+		if len(cp.EarlyStoppingParams) != 0 && (len(cp.EarlyStoppingParams) != len(params) || len(cp.EarlyStoppingState) != len(state)) {
+			return errors.New("Checkpoint early stopping parameters do not match the network")
+		}
+
+		t.stopper.best = cp.EarlyStoppingBest
+		t.stopper.wait = cp.EarlyStoppingWait
+		t.stopper.stopped = cp.EarlyStopped
+		t.stopper.bestVals = nil
+		t.stopper.bestState = nil
+		if len(cp.EarlyStoppingParams) != 0 {
+			t.stopper.bestVals = cp.EarlyStoppingParams
+			t.stopper.bestState = cp.EarlyStoppingState
+		}
+	}
+
+	t.epoch = cp.Epoch
+	t.batch = cp.Batch
+
+	t.seed = cp.Seed
+	t.rngSource = newCountingSource(cp.Seed, cp.RandDraws)
+	t.rng = rand.New(t.rngSource)
+	t.dataSeed = cp.DataSeed
+
+	t.resuming = true
+
+	return nil
+}
+
+//ResumeFrom loads the checkpoint in path and continues the training until the end
+func (t *BPTrainer) ResumeFrom(path string) error {
+	return t.ResumeFromContext(context.Background(), path)
+}
+
+//ResumeFromContext is like ResumeFrom, but the training stops when ctx is done. See TrainContext.
+func (t *BPTrainer) ResumeFromContext(ctx context.Context, path string) error {
+	err := t.LoadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+
+	return t.TrainContext(ctx)
+}
+
+//countingSource is a rand.Source that counts the number of values it has generated, so its state can be restored by seeding it again and discarding the same amount of values.
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func newCountingSource(seed int64, draws uint64) *countingSource {
+	s := &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+	for s.draws < draws {
+		s.Uint64()
+	}
+	return s
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
@@ -0,0 +1,242 @@
+package training
+
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight"
+	"github.com/gerardabello/weight/augmentation"
+	"github.com/gerardabello/weight/costs"
+	"github.com/gerardabello/weight/layers"
+	"github.com/gerardabello/weight/loaders/tensorset"
+	"github.com/gerardabello/weight/tensor"
+)
+
+//newTestNet creates a small classifier for [4, 4] inputs, with dropout. Networks created with the same seed have the same parameters.
+func newTestNet(t *testing.T, seed int64) *layers.FFNet {
+	net, err := layers.NewSequentialNet(
+		layers.NewReshaperLayer([]int{4, 4}, []int{16}),
+		layers.NewDenseLayer([]int{16}, []int{8}),
+		layers.NewSigmoidLayer(8),
+		layers.NewDropoutLayer(0.25, 8),
+		layers.NewDenseLayer([]int{8}, []int{2}),
+		layers.NewSoftmaxLayer(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	params, _ := net.GetParamGradPointers()
+	for _, p := range params {
+		*p = rng.NormFloat64() * 0.5
+	}
+
+	return net
+}
+
+//newTestData creates n random examples of two classes, with the train set augmented with random shifts
+func newTestData(n int) *weight.PairSet {
+	rng := rand.New(rand.NewSource(1))
+
+	var data, ans []*tensor.Tensor
+	for i := 0; i < n; i++ {
+		d := tensor.NewTensor(4, 4)
+		for j := range d.Values {
+			d.Values[j] = rng.Float64()
+		}
+		a := tensor.NewTensor(2)
+		a.Values[i%2] = 1
+		d.Values[0] += float64(i % 2)
+
+		data = append(data, d)
+		ans = append(ans, a)
+	}
+
+	train := &augmentation.Shifter{DataSet: classSet{tensorset.NewTensorSet(data, ans)}, MaxAmount: []int{2, 2}, Method: augmentation.Zeros}
+	return &weight.PairSet{TrainSet: train, TestSet: classSet{tensorset.NewTensorSet(data, ans)}}
+}
+
+//classSet is a data set where the answer is correct if the maximum of the output is in the label of the answer
+type classSet struct {
+	weight.DataSet
+}
+
+func (s classSet) IsAnswer(out *tensor.Tensor, ans *tensor.Tensor) bool {
+	o, _ := out.Max()
+	a, _ := ans.Max()
+	return o == a
+}
+
+func newTestTrainer(net *layers.FFNet, data *weight.PairSet) *BPTrainer {
+	config := LearningConfig{
+		Optimizer: NewAdamOptimizer(),
+		Schedule:  &ConstantSchedule{Rate: 0.01},
+		Epochs:    3,
+		BatchSize: 4,
+		Seed:      42,
+	}
+
+	trainer := NewBPTrainer(config, data, net, newTestCost())
+	trainer.SetNumGoroutines(1)
+
+	return trainer
+}
+
+func newTestCost() weight.BPCostFunc {
+	return costs.NewCrossEntropyCostFunction(2)
+}
+
+func paramValues(net weight.BPLearnerLayer) []float64 {
+	params, _ := net.GetParamGradPointers()
+	values := make([]float64, len(params))
+	for i, p := range params {
+		values[i] = *p
+	}
+	return values
+}
+
+//cancelAt cancels the training after a batch
+type cancelAt struct {
+	BaseCallback
+	epoch, batch int
+	cancel       context.CancelFunc
+}
+
+func (c *cancelAt) OnBatchEnd(t *BPTrainer, m Metrics) error {
+	if m.Epoch == c.epoch && m.Batch == c.batch {
+		c.cancel()
+	}
+	return nil
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	assert := assert.New(t)
+
+	//Training without interruptions
+	net := newTestNet(t, 7)
+	err := newTestTrainer(net, newTestData(16)).Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := paramValues(net)
+
+	//The same training stopped in the middle of the second epoch
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := newTestTrainer(newTestNet(t, 7), newTestData(16))
+	stopped.AddCallback(&cancelAt{epoch: 1, batch: 1, cancel: cancel})
+	err = stopped.SetCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stopped.TrainContext(ctx)
+	assert.Equal(context.Canceled, err, "The training should be cancelled")
+
+	//Resuming with a different network and data sets, as if it was a new process
+	net = newTestNet(t, 8)
+	err = newTestTrainer(net, newTestData(16)).ResumeFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(expected, paramValues(net), "A resumed training should end with the same parameters as an uninterrupted one")
+}
+
+func TestLoadCheckpointPosition(t *testing.T) {
+	assert := assert.New(t)
+
+	//With 16 examples in batches of 4 and a checkpoint every 3 batches, the last one is saved after the last batch of the last epoch (epoch 2, batch 4)
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	trainer := newTestTrainer(newTestNet(t, 7), newTestData(16))
+	err := trainer.SetCheckpoint(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = trainer.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(newTestTrainer(newTestNet(t, 7), newTestData(16)).LoadCheckpoint(path))
+
+	//Epochs of 2 batches
+	assert.Error(newTestTrainer(newTestNet(t, 7), newTestData(8)).LoadCheckpoint(path), "A smaller train set should be rejected")
+
+	bigBatches := newTestTrainer(newTestNet(t, 7), newTestData(16))
+	bigBatches.config.BatchSize = 8
+	assert.Error(bigBatches.LoadCheckpoint(path), "Bigger batches should be rejected")
+}
+
+//newTestBNNet is like newTestNet, with batch normalization instead of dropout
+func newTestBNNet(t *testing.T, seed int64) *layers.FFNet {
+	net, err := layers.NewSequentialNet(
+		layers.NewReshaperLayer([]int{4, 4}, []int{16}),
+		layers.NewDenseLayer([]int{16}, []int{8}),
+		layers.NewBatchNormLayer(8),
+		layers.NewSigmoidLayer(8),
+		layers.NewDenseLayer([]int{8}, []int{2}),
+		layers.NewSoftmaxLayer(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	params, _ := net.GetParamGradPointers()
+	for _, p := range params {
+		*p = rng.NormFloat64() * 0.5
+	}
+
+	return net
+}
+
+func TestResumeBatchNorm(t *testing.T) {
+	assert := assert.New(t)
+
+	input := tensor.NewTensor(4, 4)
+	for i := range input.Values {
+		input.Values[i] = float64(i) / 16
+	}
+
+	net := newTestBNNet(t, 7)
+	err := newTestTrainer(net, newTestData(16)).Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := net.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := out.Copy()
+
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := newTestTrainer(newTestBNNet(t, 7), newTestData(16))
+	stopped.AddCallback(&cancelAt{epoch: 1, batch: 1, cancel: cancel})
+	err = stopped.SetCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(context.Canceled, stopped.TrainContext(ctx))
+
+	//The running statistics are restored with the parameters, so the resumed network gives the same outputs in inference mode
+	net = newTestBNNet(t, 8)
+	err = newTestTrainer(net, newTestData(16)).ResumeFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = net.Activate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(expected.Values, out.Values)
+}
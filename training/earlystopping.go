@@ -0,0 +1,108 @@
+package training
+
+import "errors"
+
+//Metric is a value computed by BPTrainer.Test that can be monitored during the training
+type Metric int
+
+const (
+	//TestLoss is the mean loss on the test set. Lower is better.
+	TestLoss Metric = iota
+	//TestAccuracy is the accuracy on the test set. Higher is better.
+	TestAccuracy
+)
+
+//EarlyStopping configures when to stop a training before all epochs are run
+type EarlyStopping struct {
+	//Metric is the value monitored after each epoch
+	Metric Metric
+
+	//Patience is the number of epochs without improvement after which the training stops
+	Patience int
+
+	//MinDelta is the minimum change of the metric to be considered an improvement
+	MinDelta float64
+}
+
+//earlyStopper keeps track of the monitored metric and a copy of the best parameters and layer state (see weight.StateLayer)
+type earlyStopper struct {
+	config EarlyStopping
+
+	best      float64
+	wait      int
+	bestVals  []float64 //nil until the first epoch is tested
+	bestState []float64
+	stopped  bool      //true once the training has been stopped, so a resumed training does not run more epochs
+}
+
+//SetEarlyStopping makes the trainer test the network after each epoch and stop when the monitored metric has not improved for Patience epochs. At the end of the training, the parameters of the best epoch are restored. Use nil to disable it.
+func (t *BPTrainer) SetEarlyStopping(config *EarlyStopping) error {
+	if config == nil {
+		t.stopper = nil
+		return nil
+	}
+
+	if config.Patience < 0 {
+		return errors.New("Early stopping patience cannot be negative")
+	}
+
+	if config.MinDelta < 0 {
+		return errors.New("Early stopping MinDelta cannot be negative")
+	}
+
+	t.stopper = &earlyStopper{config: *config}
+	return nil
+}
+
+//observe updates the state with the test results and returns true if the training should stop
+func (s *earlyStopper) observe(accuracy, loss float64, params, state []*float64) bool {
+	//Use the negative loss so bigger is always better
+	value := -loss
+	if s.config.Metric == TestAccuracy {
+		value = accuracy
+	}
+
+	if s.bestVals == nil || value > s.best+s.config.MinDelta {
+		s.best = value
+		s.wait = 0
+
+		if s.bestVals == nil {
+			s.bestVals = make([]float64, len(params))
+			s.bestState = make([]float64, len(state))
+		}
+		for i := range params {
+			s.bestVals[i] = *params[i]
+		}
+		for i := range state {
+			s.bestState[i] = *state[i]
+		}
+
+		return false
+	}
+
+	s.wait++
+	s.stopped = s.wait >= s.config.Patience
+	return s.stopped
+}
+
+//restore sets the best parameters and state found, if any epoch has been tested
+func (s *earlyStopper) restore(params, state []*float64) {
+	if s.bestVals == nil {
+		return
+	}
+
+	for i := range params {
+		*params[i] = s.bestVals[i]
+	}
+	for i := range state {
+		*state[i] = s.bestState[i]
+	}
+}
+
+//reset forgets the results of a previous training
+func (s *earlyStopper) reset() {
+	s.wait = 0
+	s.bestVals = nil
+	s.bestState = nil
+	s.stopped = false
+}
@@ -0,0 +1,157 @@
+package training
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gerardabello/weight/layers"
+)
+
+func TestEarlyStopper(t *testing.T) {
+	s := &earlyStopper{config: EarlyStopping{Metric: TestLoss, Patience: 2, MinDelta: 0.1}}
+
+	x, y := 0.0, 0.0
+	params := []*float64{&x}
+	state := []*float64{&y}
+
+	//The parameter and the state are set to the epoch, so the best epoch can be identified after restore
+	tests := []struct {
+		loss float64
+		stop bool
+		best float64
+	}{
+		{1.0, false, 0},
+		{0.8, false, 1},
+		{0.75, false, 1}, //improvement smaller than MinDelta
+		{0.6, false, 3},
+		{0.7, false, 3},
+		{0.55, true, 3},
+	}
+
+	for i, test := range tests {
+		x, y = float64(i), float64(i)
+		assert.Equal(t, test.stop, s.observe(0, test.loss, params, state), "Stop after epoch %d", i)
+
+		s.restore(params, state)
+		assert.Equal(t, test.best, x, "Best parameters after epoch %d", i)
+		assert.Equal(t, test.best, y, "Best state after epoch %d", i)
+	}
+}
+
+func TestEarlyStopperAccuracy(t *testing.T) {
+	s := &earlyStopper{config: EarlyStopping{Metric: TestAccuracy, Patience: 1}}
+
+	x := 0.0
+	params := []*float64{&x}
+
+	assert.False(t, s.observe(0.5, 10, params, nil))
+	assert.False(t, s.observe(0.6, 20, params, nil), "Higher accuracy is an improvement even if the loss is worse")
+	assert.True(t, s.observe(0.55, 1, params, nil), "Lower accuracy is not an improvement even if the loss is better")
+}
+
+//perturbParams sets the parameters at the beginning of each epoch: the initial ones in the first epoch and much bigger random values in the rest
+type perturbParams struct {
+	BaseCallback
+	initial []float64
+	epochs  int
+}
+
+func (c *perturbParams) OnEpochBegin(t *BPTrainer, epoch int) error {
+	rng := rand.New(rand.NewSource(int64(epoch)))
+	for i, p := range t.Params() {
+		*p = c.initial[i]
+		if epoch > 0 {
+			*p += rng.NormFloat64() * 20
+		}
+	}
+	return nil
+}
+
+func (c *perturbParams) OnEpochEnd(t *BPTrainer, m Metrics) error {
+	c.epochs++
+	return nil
+}
+
+//newStoppingTrainer creates a trainer with early stopping for 10 epochs. The learning rate is 0, so only callbacks change the parameters.
+func newStoppingTrainer(t *testing.T, net *layers.FFNet) *BPTrainer {
+	config := LearningConfig{
+		Optimizer: NewSGDOptimizer(),
+		Schedule:  &ConstantSchedule{Rate: 0},
+		Epochs:    10,
+		BatchSize: 4,
+		Seed:      1,
+	}
+	trainer := NewBPTrainer(config, newTestData(8), net, newTestCost())
+	trainer.SetNumGoroutines(1)
+
+	err := trainer.SetEarlyStopping(&EarlyStopping{Metric: TestLoss, Patience: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return trainer
+}
+
+func TestEarlyStoppingRestore(t *testing.T) {
+	assert := assert.New(t)
+
+	net := newTestNet(t, 3)
+	initial := paramValues(net)
+
+	trainer := newStoppingTrainer(t, net)
+	c := &perturbParams{initial: initial}
+	trainer.AddCallback(c)
+
+	err := trainer.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(3, c.epochs, "The training should stop after two epochs without improvement")
+	assert.Equal(initial, paramValues(net), "The parameters of the first epoch should be restored")
+}
+
+func TestEarlyStoppingResume(t *testing.T) {
+	assert := assert.New(t)
+
+	net := newTestNet(t, 3)
+	initial := paramValues(net)
+
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	trainer := newStoppingTrainer(t, net)
+	trainer.AddCallback(&perturbParams{initial: initial})
+	err := trainer.SetCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trainer.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Resuming a training that stopped early only restores the best parameters
+	net = newTestNet(t, 4)
+	resumed := newStoppingTrainer(t, net)
+	c := &perturbParams{initial: initial}
+	resumed.AddCallback(c)
+
+	err = resumed.ResumeFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(0, c.epochs, "A stopped training should not run more epochs")
+	assert.Equal(initial, paramValues(net), "The parameters of the best epoch should be restored")
+}
+
+func TestSetEarlyStoppingErrors(t *testing.T) {
+	trainer := newTestTrainer(newTestNet(t, 1), newTestData(8))
+
+	assert.Error(t, trainer.SetEarlyStopping(&EarlyStopping{Patience: -1}))
+	assert.Error(t, trainer.SetEarlyStopping(&EarlyStopping{MinDelta: -1}))
+	assert.NoError(t, trainer.SetEarlyStopping(nil))
+}
@@ -1,6 +1,8 @@
 package training
 
-//ParamUpdateMethod defines the method used to update parameters in each layer
+import "fmt"
+
+//ParamUpdateMethod defines the method used to update parameters in each layer. It is only used if LearningConfig.Optimizer is nil.
 type ParamUpdateMethod int
 
 const (
@@ -8,13 +10,20 @@ const (
 	Momentum ParamUpdateMethod = iota
 	//AdaDelta updates each parameter independently, without a global learning rate
 	AdaDelta
-	//Adam is a mix of AdaDelta + Momentum
+	//Adam is a mix of AdaDelta + Momentum. It computes the bias correction like older versions, so existing configurations train the same way. Use NewAdamOptimizer for the Adam of the original paper.
 	Adam
 )
 
 //LearningConfig contains the parameters used in the learning process
 type LearningConfig struct {
-	Method            ParamUpdateMethod
+	//Optimizer updates the parameters after each batch. If it is nil, one is created using Method (and Momentum if needed).
+	Optimizer Optimizer
+
+	Method ParamUpdateMethod
+
+	//Schedule gives the learning rate for each batch. If it is nil, an ExponentialSchedule from LearningRateStart to LearningRateEnd is used.
+	Schedule LRSchedule
+
 	LearningRateStart float64
 	LearningRateEnd   float64
 
@@ -22,4 +31,21 @@ type LearningConfig struct {
 	BatchSize   int
 	WeightDecay float64
 	Momentum    float64
+
+	//Seed initializes the random source of the trainer, used to shuffle and augment the train set and by the layers that use random numbers. See BPTrainer.Rand. If it is 0 a seed based on the current time is used.
+	Seed int64
+}
+
+//newOptimizer creates the Optimizer corresponding to the ParamUpdateMethod in the configuration
+func (c *LearningConfig) newOptimizer() (Optimizer, error) {
+	switch c.Method {
+	case Momentum:
+		return NewMomentumOptimizer(c.Momentum), nil
+	case AdaDelta:
+		return NewAdaDeltaOptimizer(), nil
+	case Adam:
+		return newLegacyAdamOptimizer(c.BatchSize, c.Epochs), nil
+	}
+
+	return nil, fmt.Errorf("Unknown parameter update method %d", c.Method)
 }
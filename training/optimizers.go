@@ -0,0 +1,338 @@
+package training
+
+import (
+	"errors"
+	"math"
+)
+
+//Optimizer updates the parameters of a network using their gradients. See http://sebastianruder.com/optimizing-gradient-descent/ for an overview of the different methods.
+type Optimizer interface {
+	//Init is called by the trainer before training with the number of parameters, so the optimizer can allocate and reset its state
+	Init(nParams int)
+
+	//Update modifies each parameter using its gradient. Gradients are already averaged over the batch and include the weight decay term.
+	Update(params []*float64, grads []float64, learningRate float64)
+}
+
+//StatefulOptimizer is an optimizer with internal state (moment estimates, accumulated gradients, etc.) that can be saved and restored. The trainer uses it to write checkpoints.
+type StatefulOptimizer interface {
+	Optimizer
+
+	State() [][]float64
+	SetState(state [][]float64) error
+}
+
+func setState(dst []*[]float64, state [][]float64) error {
+	if len(dst) != len(state) {
+		return errors.New("Optimizer state has wrong number of arrays")
+	}
+
+	for i := range dst {
+		if len(*dst[i]) != len(state[i]) {
+			return errors.New("Optimizer state has wrong size")
+		}
+		copy(*dst[i], state[i])
+	}
+
+	return nil
+}
+
+//SGDOptimizer is the plain stochastic gradient descent
+type SGDOptimizer struct{}
+
+//NewSGDOptimizer creates a new SGDOptimizer
+func NewSGDOptimizer() *SGDOptimizer {
+	return &SGDOptimizer{}
+}
+
+func (o *SGDOptimizer) Init(nParams int) {}
+
+func (o *SGDOptimizer) Update(params []*float64, grads []float64, learningRate float64) {
+	for p := range params {
+		(*params[p]) -= learningRate * grads[p]
+	}
+}
+
+//MomentumOptimizer uses the momentum as a low-pass filter to smooth the gradient descent
+type MomentumOptimizer struct {
+	Momentum float64
+
+	velocity []float64
+}
+
+//NewMomentumOptimizer creates a new MomentumOptimizer
+func NewMomentumOptimizer(momentum float64) *MomentumOptimizer {
+	return &MomentumOptimizer{Momentum: momentum}
+}
+
+func (o *MomentumOptimizer) Init(nParams int) {
+	o.velocity = make([]float64, nParams)
+}
+
+func (o *MomentumOptimizer) Update(params []*float64, grads []float64, learningRate float64) {
+	for p := range params {
+		dx := -grads[p]*learningRate + o.velocity[p]
+		(*params[p]) += dx
+		o.velocity[p] = dx * o.Momentum
+	}
+}
+
+func (o *MomentumOptimizer) State() [][]float64 {
+	return [][]float64{o.velocity}
+}
+
+func (o *MomentumOptimizer) SetState(state [][]float64) error {
+	return setState([]*[]float64{&o.velocity}, state)
+}
+
+//NesterovOptimizer is like MomentumOptimizer, but the gradient is evaluated after applying the momentum step
+type NesterovOptimizer struct {
+	Momentum float64
+
+	velocity []float64
+}
+
+//NewNesterovOptimizer creates a new NesterovOptimizer
+func NewNesterovOptimizer(momentum float64) *NesterovOptimizer {
+	return &NesterovOptimizer{Momentum: momentum}
+}
+
+func (o *NesterovOptimizer) Init(nParams int) {
+	o.velocity = make([]float64, nParams)
+}
+
+func (o *NesterovOptimizer) Update(params []*float64, grads []float64, learningRate float64) {
+	for p := range params {
+		prev := o.velocity[p]
+		o.velocity[p] = o.Momentum*o.velocity[p] - learningRate*grads[p]
+		(*params[p]) += -o.Momentum*prev + (1+o.Momentum)*o.velocity[p]
+	}
+}
+
+func (o *NesterovOptimizer) State() [][]float64 {
+	return [][]float64{o.velocity}
+}
+
+func (o *NesterovOptimizer) SetState(state [][]float64) error {
+	return setState([]*[]float64{&o.velocity}, state)
+}
+
+//AdaGradOptimizer scales the learning rate of each parameter by the inverse of its accumulated squared gradients
+type AdaGradOptimizer struct {
+	Eps float64
+
+	cache []float64
+}
+
+//NewAdaGradOptimizer creates a new AdaGradOptimizer
+func NewAdaGradOptimizer() *AdaGradOptimizer {
+	return &AdaGradOptimizer{Eps: 1e-8}
+}
+
+func (o *AdaGradOptimizer) Init(nParams int) {
+	o.cache = make([]float64, nParams)
+}
+
+func (o *AdaGradOptimizer) Update(params []*float64, grads []float64, learningRate float64) {
+	for p := range params {
+		g := grads[p]
+		o.cache[p] += g * g
+		(*params[p]) -= learningRate * g / (math.Sqrt(o.cache[p]) + o.Eps)
+	}
+}
+
+func (o *AdaGradOptimizer) State() [][]float64 {
+	return [][]float64{o.cache}
+}
+
+func (o *AdaGradOptimizer) SetState(state [][]float64) error {
+	return setState([]*[]float64{&o.cache}, state)
+}
+
+//RMSPropOptimizer is like AdaGradOptimizer, but uses a moving average of the squared gradients so the learning rate does not vanish
+type RMSPropOptimizer struct {
+	Decay float64
+	Eps   float64
+
+	cache []float64
+}
+
+//NewRMSPropOptimizer creates a new RMSPropOptimizer
+func NewRMSPropOptimizer() *RMSPropOptimizer {
+	return &RMSPropOptimizer{Decay: 0.9, Eps: 1e-8}
+}
+
+func (o *RMSPropOptimizer) Init(nParams int) {
+	o.cache = make([]float64, nParams)
+}
+
+func (o *RMSPropOptimizer) Update(params []*float64, grads []float64, learningRate float64) {
+	for p := range params {
+		g := grads[p]
+		o.cache[p] = o.Decay*o.cache[p] + (1-o.Decay)*g*g
+		(*params[p]) -= learningRate * g / (math.Sqrt(o.cache[p]) + o.Eps)
+	}
+}
+
+func (o *RMSPropOptimizer) State() [][]float64 {
+	return [][]float64{o.cache}
+}
+
+func (o *RMSPropOptimizer) SetState(state [][]float64) error {
+	return setState([]*[]float64{&o.cache}, state)
+}
+
+//AdaDeltaOptimizer updates each parameter independently, without a global learning rate
+type AdaDeltaOptimizer struct {
+	Ro  float64
+	Eps float64
+
+	gsum []float64
+	xsum []float64
+}
+
+//NewAdaDeltaOptimizer creates a new AdaDeltaOptimizer
+func NewAdaDeltaOptimizer() *AdaDeltaOptimizer {
+	return &AdaDeltaOptimizer{Ro: 0.95, Eps: 1e-8}
+}
+
+func (o *AdaDeltaOptimizer) Init(nParams int) {
+	o.gsum = make([]float64, nParams)
+	o.xsum = make([]float64, nParams)
+}
+
+func (o *AdaDeltaOptimizer) Update(params []*float64, grads []float64, learningRate float64) {
+	for p := range params {
+		g := grads[p]
+		o.gsum[p] = o.Ro*o.gsum[p] + (1-o.Ro)*g*g
+		dx := -math.Sqrt((o.xsum[p]+o.Eps)/(o.gsum[p]+o.Eps)) * g
+		o.xsum[p] = o.Ro*o.xsum[p] + (1-o.Ro)*dx*dx // yes, xsum lags behind gsum by 1.
+		(*params[p]) += dx
+	}
+}
+
+func (o *AdaDeltaOptimizer) State() [][]float64 {
+	return [][]float64{o.gsum, o.xsum}
+}
+
+func (o *AdaDeltaOptimizer) SetState(state [][]float64) error {
+	return setState([]*[]float64{&o.gsum, &o.xsum}, state)
+}
+
+//AdamOptimizer is a mix of AdaDelta + Momentum. It follows the original paper (https://arxiv.org/abs/1412.6980): the moment estimates are divided by 1-Beta^t, being t the number of updates done by this optimizer, so the first update of each parameter moves it by the learning rate. The number of updates is part of the state, so it is saved in checkpoints and continues when a training is resumed.
+type AdamOptimizer struct {
+	Beta1 float64
+	Beta2 float64
+	Eps   float64
+
+	m    []float64
+	v    []float64
+	step []float64 //only one value, stored as a slice so it can be part of the state
+}
+
+//NewAdamOptimizer creates a new AdamOptimizer
+func NewAdamOptimizer() *AdamOptimizer {
+	return &AdamOptimizer{Beta1: 0.9, Beta2: 0.999, Eps: 1e-8}
+}
+
+func (o *AdamOptimizer) Init(nParams int) {
+	o.m = make([]float64, nParams)
+	o.v = make([]float64, nParams)
+	o.step = []float64{0}
+}
+
+func (o *AdamOptimizer) Update(params []*float64, grads []float64, learningRate float64) {
+	o.step[0]++
+	c1 := 1 - math.Pow(o.Beta1, o.step[0])
+	c2 := 1 - math.Pow(o.Beta2, o.step[0])
+
+	for p := range params {
+		g := grads[p]
+		o.m[p] = o.m[p]*o.Beta1 + (1-o.Beta1)*g   // update biased first moment estimate
+		o.v[p] = o.v[p]*o.Beta2 + (1-o.Beta2)*g*g // update biased second moment estimate
+		mc := o.m[p] / c1                         // correct bias first moment estimate
+		vc := o.v[p] / c2                         // correct bias second moment estimate
+		(*params[p]) -= learningRate * mc / (math.Sqrt(vc) + o.Eps)
+	}
+}
+
+func (o *AdamOptimizer) State() [][]float64 {
+	return [][]float64{o.m, o.v, o.step}
+}
+
+func (o *AdamOptimizer) SetState(state [][]float64) error {
+	return setState([]*[]float64{&o.m, &o.v, &o.step}, state)
+}
+
+//AdamWOptimizer is Adam with decoupled weight decay: the decay is applied directly to the parameters instead of being added to the gradient. Each update first multiplies the parameters by 1 - learningRate*WeightDecay and then does the AdamOptimizer update, with the same moment estimates and number of updates. Use it with LearningConfig.WeightDecay set to 0.
+type AdamWOptimizer struct {
+	AdamOptimizer
+
+	WeightDecay float64
+}
+
+//NewAdamWOptimizer creates a new AdamWOptimizer
+func NewAdamWOptimizer(weightDecay float64) *AdamWOptimizer {
+	return &AdamWOptimizer{AdamOptimizer: *NewAdamOptimizer(), WeightDecay: weightDecay}
+}
+
+func (o *AdamWOptimizer) Update(params []*float64, grads []float64, learningRate float64) {
+	for p := range params {
+		(*params[p]) -= learningRate * o.WeightDecay * (*params[p])
+	}
+
+	o.AdamOptimizer.Update(params, grads, learningRate)
+}
+
+//progressOptimizer is an optimizer that needs to know the progress of the training. The trainer calls setProgress before each update.
+type progressOptimizer interface {
+	setProgress(step, steps int)
+}
+
+//legacyAdamOptimizer is the Adam used with Method: Adam, kept so old configurations train exactly as before. Instead of dividing the moment estimates by 1-Beta^t, it multiplies them by 1-Beta^k, being k the progress of the training multiplied by BatchSize*Epochs.
+type legacyAdamOptimizer struct {
+	Beta1 float64
+	Beta2 float64
+	Eps   float64
+
+	batchSize int
+	epochs    int
+	percent   float64
+
+	m []float64
+	v []float64
+}
+
+func newLegacyAdamOptimizer(batchSize, epochs int) *legacyAdamOptimizer {
+	return &legacyAdamOptimizer{Beta1: 0.9, Beta2: 0.999, Eps: 1e-8, batchSize: batchSize, epochs: epochs}
+}
+
+func (o *legacyAdamOptimizer) Init(nParams int) {
+	o.m = make([]float64, nParams)
+	o.v = make([]float64, nParams)
+}
+
+func (o *legacyAdamOptimizer) setProgress(step, steps int) {
+	o.percent = float64(step) / float64(steps)
+}
+
+func (o *legacyAdamOptimizer) Update(params []*float64, grads []float64, learningRate float64) {
+	k := int(o.percent * float64(o.batchSize) * float64(o.epochs))
+
+	for p := range params {
+		g := grads[p]
+		o.m[p] = o.m[p]*o.Beta1 + (1-o.Beta1)*g                   // update biased first moment estimate
+		o.v[p] = o.v[p]*o.Beta2 + (1-o.Beta2)*g*g                 // update biased second moment estimate
+		biasCorr1 := o.m[p] * (1 - math.Pow(o.Beta1, float64(k))) // correct bias first moment estimate
+		biasCorr2 := o.v[p] * (1 - math.Pow(o.Beta2, float64(k))) // correct bias second moment estimate
+		(*params[p]) -= learningRate * biasCorr1 / (math.Sqrt(biasCorr2) + o.Eps)
+	}
+}
+
+func (o *legacyAdamOptimizer) State() [][]float64 {
+	return [][]float64{o.m, o.v}
+}
+
+func (o *legacyAdamOptimizer) SetState(state [][]float64) error {
+	return setState([]*[]float64{&o.m, &o.v}, state)
+}
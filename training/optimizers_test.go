@@ -0,0 +1,150 @@
+package training
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//optimizerGrads are the gradients of two parameters in three consecutive updates
+var optimizerGrads = [][]float64{{0.5, -2}, {0.5, 1}, {-1, 0.25}}
+
+//runOptimizer updates the parameters {1, -0.5} with optimizerGrads and a learning rate of 0.1, and returns their values after each update
+func runOptimizer(o Optimizer) [][]float64 {
+	x := []float64{1, -0.5}
+	params := []*float64{&x[0], &x[1]}
+	o.Init(len(params))
+
+	var steps [][]float64
+	for _, g := range optimizerGrads {
+		o.Update(params, g, 0.1)
+		steps = append(steps, []float64{x[0], x[1]})
+	}
+
+	return steps
+}
+
+func TestOptimizers(t *testing.T) {
+	tests := []struct {
+		name      string
+		optimizer Optimizer
+		expected  [][]float64
+	}{
+		{
+			//x -= 0.1*g
+			"SGD", NewSGDOptimizer(),
+			[][]float64{{0.95, -0.3}, {0.9, -0.4}, {1, -0.425}},
+		},
+		{
+			//v = -0.1*g + v, x += v, v *= 0.9
+			"Momentum", NewMomentumOptimizer(0.9),
+			[][]float64{{0.95, -0.3}, {0.855, -0.22}, {0.8695, -0.173}},
+		},
+		{
+			//v' = 0.9*v - 0.1*g, x += -0.9*v + 1.9*v'
+			"Nesterov", NewNesterovOptimizer(0.9),
+			[][]float64{{0.905, -0.12}, {0.7695, -0.148}, {0.88255, -0.1307}},
+		},
+		{
+			//The first update is 0.1*g/|g|
+			"AdaGrad", NewAdaGradOptimizer(),
+			[][]float64{{0.9, -0.4}, {0.8292893248813452, -0.44472135984999583}, {0.9109389823074512, -0.45583247091172424}},
+		},
+		{
+			//The first update is 0.1*g/(sqrt(0.1)*|g|)
+			"RMSProp", NewRMSPropOptimizer(),
+			[][]float64{{0.6837722539831608, -0.18377223898316203}, {0.4543565306389143, -0.3312141929641461}, {0.7190309262580046, -0.3697785540786326}},
+		},
+		{
+			//AdaDelta does not use the learning rate
+			"AdaDelta", NewAdaDeltaOptimizer(),
+			[][]float64{{0.9995527865833854, -0.4995527864156804}, {0.9990998759532144, -0.49984146154065273}, {0.9997376464213112, -0.4999214606362566}},
+		},
+		{
+			//With the bias correction, the first update is 0.1*g/|g|. In the second one, the first parameter has the same gradient, so it moves 0.1 again.
+			"Adam", NewAdamOptimizer(),
+			[][]float64{{0.9, -0.4}, {0.8, -0.37336629670243143}, {0.8075649369687122, -0.36001031852604637}},
+		},
+		{
+			//Like Adam, but the parameters are first multiplied by 1 - 0.1*0.01
+			"AdamW", NewAdamWOptimizer(0.01),
+			[][]float64{{0.899, -0.3995}, {0.7981010039980004, -0.37246679670193145}, {0.8048678359627142, -0.35873835172884444}},
+		},
+	}
+
+	for _, test := range tests {
+		steps := runOptimizer(test.optimizer)
+		for i := range steps {
+			assert.InDeltaSlice(t, test.expected[i], steps[i], 1e-8, "%s update %d", test.name, i+1)
+		}
+	}
+}
+
+func TestOptimizerState(t *testing.T) {
+	optimizers := []StatefulOptimizer{
+		NewMomentumOptimizer(0.9),
+		NewNesterovOptimizer(0.9),
+		NewAdaGradOptimizer(),
+		NewRMSPropOptimizer(),
+		NewAdaDeltaOptimizer(),
+		NewAdamOptimizer(),
+		NewAdamWOptimizer(0.01),
+	}
+	restored := []StatefulOptimizer{
+		NewMomentumOptimizer(0.9),
+		NewNesterovOptimizer(0.9),
+		NewAdaGradOptimizer(),
+		NewRMSPropOptimizer(),
+		NewAdaDeltaOptimizer(),
+		NewAdamOptimizer(),
+		NewAdamWOptimizer(0.01),
+	}
+
+	for i, o := range optimizers {
+		expected := runOptimizer(o)
+
+		//Do the first update, and continue with a new optimizer with the same state
+		x := []float64{1, -0.5}
+		params := []*float64{&x[0], &x[1]}
+		o.Init(len(params))
+		o.Update(params, optimizerGrads[0], 0.1)
+
+		r := restored[i]
+		r.Init(len(params))
+		err := r.SetState(o.State())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, g := range optimizerGrads[1:] {
+			r.Update(params, g, 0.1)
+		}
+
+		assert.Equal(t, expected[len(expected)-1], x, "Optimizer %d should continue from the restored state", i)
+	}
+}
+
+func TestLegacyAdam(t *testing.T) {
+	//Method: Adam keeps the Adam of older versions
+	config := LearningConfig{Method: Adam, BatchSize: 2, Epochs: 3}
+	o, err := config.newOptimizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, ok := o.(*legacyAdamOptimizer)
+	if !assert.True(t, ok, "Method: Adam should use the legacy Adam") {
+		return
+	}
+
+	x := []float64{1, -0.5}
+	params := []*float64{&x[0], &x[1]}
+	legacy.Init(len(params))
+
+	//Updates at steps 0, 3 and 5 of a training of 6 steps, so k is 0, 3 and 5. With k = 0 the parameters do not change.
+	expected := [][]float64{{1, -0.5}, {-1.1036307096528333, 0.06027790587071924}, {-0.886539042826166, 0.44355643383785603}}
+	for i, step := range []int{0, 3, 5} {
+		legacy.setProgress(step, 6)
+		legacy.Update(params, optimizerGrads[i], 0.1)
+		assert.InDeltaSlice(t, expected[i], x, 1e-8, "Update %d", i+1)
+	}
+}
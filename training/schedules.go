@@ -0,0 +1,222 @@
+package training
+
+import (
+	"math"
+)
+
+//LRSchedule gives the learning rate to use in each step of the training. A step is the update of the parameters after one batch.
+type LRSchedule interface {
+	//LearningRate returns the learning rate for step, being steps the total number of steps of the training
+	LearningRate(step, steps int) float64
+}
+
+//LossObserver is a schedule that adapts to the test loss. If the schedule of a trainer implements it, the trainer tests the network after each epoch and calls ObserveLoss with the result.
+type LossObserver interface {
+	ObserveLoss(loss float64)
+}
+
+//StatefulSchedule is a schedule with internal state that can be saved and restored. The trainer uses it to write checkpoints.
+type StatefulSchedule interface {
+	LRSchedule
+
+	State() [][]float64
+	SetState(state [][]float64) error
+}
+
+//needsLoss returns true if the schedule has to be informed of the test loss after each epoch
+func needsLoss(s LRSchedule) bool {
+	if w, ok := s.(*WarmupSchedule); ok {
+		return needsLoss(w.After)
+	}
+	_, ok := s.(LossObserver)
+	return ok
+}
+
+//ConstantSchedule always returns the same learning rate
+type ConstantSchedule struct {
+	Rate float64
+}
+
+func (s *ConstantSchedule) LearningRate(step, steps int) float64 {
+	return s.Rate
+}
+
+//ExponentialSchedule makes a smooth exponential transition from Start to End. Most of the change happens at the beginning of the training. This is the schedule used if LearningConfig.Schedule is nil.
+type ExponentialSchedule struct {
+	Start float64
+	End   float64
+}
+
+func (s *ExponentialSchedule) LearningRate(step, steps int) float64 {
+	percent := float64(step) / float64(steps)
+	return (s.End-s.Start)*(-math.Pow(2, -10*percent)+1) + s.Start
+}
+
+//StepDecaySchedule multiplies the learning rate by Factor every StepSize steps. If StepSize is 0 or negative, the rate never decays.
+type StepDecaySchedule struct {
+	Initial  float64
+	Factor   float64
+	StepSize int
+}
+
+func (s *StepDecaySchedule) LearningRate(step, steps int) float64 {
+	if s.StepSize <= 0 {
+		return s.Initial
+	}
+	return s.Initial * math.Pow(s.Factor, float64(step/s.StepSize))
+}
+
+//CosineSchedule anneals the learning rate from Max to Min following a cosine. If Period is 0 or negative, there is only one cycle that lasts the whole training. Otherwise the schedule restarts after Period steps, and each cycle is PeriodMult times longer than the previous one (SGDR).
+type CosineSchedule struct {
+	Max float64
+	Min float64
+
+	Period     int
+	PeriodMult float64
+}
+
+func (s *CosineSchedule) LearningRate(step, steps int) float64 {
+	period := float64(s.Period)
+	if s.Period <= 0 {
+		period = math.Max(float64(steps), 1)
+	}
+
+	mult := s.PeriodMult
+	if mult < 1 {
+		mult = 1
+	}
+
+	//Find the position inside the current cycle
+	pos := float64(step)
+	for pos >= period {
+		pos -= period
+		period *= mult
+	}
+
+	return s.Min + (s.Max-s.Min)*(1+math.Cos(math.Pi*pos/period))/2
+}
+
+//WarmupSchedule increases the learning rate linearly from Start during the first Steps steps. After that it uses the After schedule as if the training started at that moment. If the training is not longer than the warmup, After sees a training of 1 step.
+type WarmupSchedule struct {
+	Steps int
+	Start float64
+	After LRSchedule
+}
+
+func (s *WarmupSchedule) LearningRate(step, steps int) float64 {
+	rest := steps - s.Steps
+	if rest < 1 {
+		rest = 1
+	}
+
+	if step >= s.Steps {
+		return s.After.LearningRate(step-s.Steps, rest)
+	}
+
+	end := s.After.LearningRate(0, rest)
+	return s.Start + (end-s.Start)*float64(step)/float64(s.Steps)
+}
+
+//ObserveLoss passes the loss to the After schedule if it needs it
+func (s *WarmupSchedule) ObserveLoss(loss float64) {
+	if lo, ok := s.After.(LossObserver); ok {
+		lo.ObserveLoss(loss)
+	}
+}
+
+func (s *WarmupSchedule) State() [][]float64 {
+	if ss, ok := s.After.(StatefulSchedule); ok {
+		return ss.State()
+	}
+	return nil
+}
+
+func (s *WarmupSchedule) SetState(state [][]float64) error {
+	if ss, ok := s.After.(StatefulSchedule); ok {
+		return ss.SetState(state)
+	}
+	return nil
+}
+
+//OneCycleSchedule increases the learning rate from Max/DivStart to Max during the first WarmupFraction of the training, and then anneals it to Max/DivEnd. Both phases follow a cosine.
+type OneCycleSchedule struct {
+	Max            float64
+	DivStart       float64
+	DivEnd         float64
+	WarmupFraction float64
+}
+
+//NewOneCycleSchedule creates a OneCycleSchedule with the usual values for the rest of parameters
+func NewOneCycleSchedule(max float64) *OneCycleSchedule {
+	return &OneCycleSchedule{Max: max, DivStart: 25, DivEnd: 1e4, WarmupFraction: 0.3}
+}
+
+func (s *OneCycleSchedule) LearningRate(step, steps int) float64 {
+	up := int(s.WarmupFraction * float64(steps))
+
+	anneal := func(from, to float64, pos, length int) float64 {
+		if length <= 0 {
+			return to
+		}
+		return to + (from-to)*(1+math.Cos(math.Pi*float64(pos)/float64(length)))/2
+	}
+
+	if step < up {
+		return anneal(s.Max/s.DivStart, s.Max, step, up)
+	}
+	return anneal(s.Max, s.Max/s.DivEnd, step-up, steps-up)
+}
+
+//PlateauSchedule starts with Rate and multiplies it by Factor every time the test loss has not improved at least MinDelta for Patience epochs, counted like in EarlyStopping. The rate never goes below MinRate.
+type PlateauSchedule struct {
+	Rate     float64
+	Factor   float64
+	Patience int
+	MinDelta float64
+	MinRate  float64
+
+	//current rate, best loss and number of epochs without improvement
+	state []float64
+}
+
+//NewPlateauSchedule creates a PlateauSchedule
+func NewPlateauSchedule(rate, factor float64, patience int) *PlateauSchedule {
+	return &PlateauSchedule{Rate: rate, Factor: factor, Patience: patience}
+}
+
+func (s *PlateauSchedule) init() {
+	if s.state == nil {
+		s.state = []float64{s.Rate, math.Inf(1), 0}
+	}
+}
+
+func (s *PlateauSchedule) LearningRate(step, steps int) float64 {
+	s.init()
+	return s.state[0]
+}
+
+func (s *PlateauSchedule) ObserveLoss(loss float64) {
+	s.init()
+
+	if loss < s.state[1]-s.MinDelta {
+		s.state[1] = loss
+		s.state[2] = 0
+		return
+	}
+
+	s.state[2]++
+	if s.state[2] >= float64(s.Patience) {
+		s.state[0] = math.Max(s.state[0]*s.Factor, s.MinRate)
+		s.state[2] = 0
+	}
+}
+
+func (s *PlateauSchedule) State() [][]float64 {
+	s.init()
+	return [][]float64{s.state}
+}
+
+func (s *PlateauSchedule) SetState(state [][]float64) error {
+	s.init()
+	return setState([]*[]float64{&s.state}, state)
+}
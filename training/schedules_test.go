@@ -0,0 +1,98 @@
+package training
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedules(t *testing.T) {
+	//Rates of each schedule in a training of 100 steps
+	tests := []struct {
+		name     string
+		schedule LRSchedule
+		rates    map[int]float64
+	}{
+		{
+			"Constant", &ConstantSchedule{Rate: 0.3},
+			map[int]float64{0: 0.3, 50: 0.3, 99: 0.3},
+		},
+		{
+			//Half of the change happens in the first 10% of the training
+			"Exponential", &ExponentialSchedule{Start: 1, End: 0},
+			map[int]float64{0: 1, 10: 0.5, 20: 0.25},
+		},
+		{
+			"StepDecay", &StepDecaySchedule{Initial: 0.1, Factor: 0.5, StepSize: 10},
+			map[int]float64{0: 0.1, 9: 0.1, 10: 0.05, 25: 0.025, 99: 0.1 / 512},
+		},
+		{
+			"StepDecay without step size", &StepDecaySchedule{Initial: 0.1, Factor: 0.5},
+			map[int]float64{0: 0.1, 10: 0.1, 99: 0.1},
+		},
+		{
+			//One cycle for the whole training
+			"Cosine", &CosineSchedule{Max: 1, Min: 0.2},
+			map[int]float64{0: 1, 25: 0.2 + 0.8*0.8535533905932737, 50: 0.6, 75: 0.2 + 0.8*0.14644660940672627},
+		},
+		{
+			//Cycles of 10, 20 and 40 steps
+			"SGDR", &CosineSchedule{Max: 1, Min: 0, Period: 10, PeriodMult: 2},
+			map[int]float64{0: 1, 5: 0.5, 10: 1, 20: 0.5, 30: 1, 50: 0.5, 70: 1},
+		},
+		{
+			//The warmup ends at the first rate of the cosine, which lasts the remaining 90 steps
+			"Warmup", &WarmupSchedule{Steps: 10, Start: 0, After: &CosineSchedule{Max: 1, Min: 0}},
+			map[int]float64{0: 0, 5: 0.5, 10: 1, 55: 0.5},
+		},
+		{
+			//Up from 0.1 to 1 in the first 50 steps, down to 0.01 in the rest
+			"OneCycle", &OneCycleSchedule{Max: 1, DivStart: 10, DivEnd: 100, WarmupFraction: 0.5},
+			map[int]float64{0: 0.1, 25: 0.55, 50: 1, 75: 0.505},
+		},
+	}
+
+	for _, test := range tests {
+		for step, rate := range test.rates {
+			assert.InDelta(t, rate, test.schedule.LearningRate(step, 100), 1e-12, "%s at step %d", test.name, step)
+		}
+	}
+}
+
+func TestSchedulesShortTraining(t *testing.T) {
+	//A warmup as long as the training leaves no steps for the cosine, which used to loop forever
+	warmup := &WarmupSchedule{Steps: 100, After: &CosineSchedule{Max: 1}}
+	assert.InDelta(t, 0.05, warmup.LearningRate(5, 100), 1e-12)
+	assert.InDelta(t, 0.5, warmup.LearningRate(50, 40), 1e-12)
+	assert.InDelta(t, 1, warmup.LearningRate(100, 40), 1e-12)
+
+	//A negative period is one cycle, like 0
+	cosine := &CosineSchedule{Max: 1, Period: -5}
+	assert.InDelta(t, 0.5, cosine.LearningRate(50, 100), 1e-12)
+	assert.InDelta(t, 1, cosine.LearningRate(0, 0), 1e-12)
+}
+
+func TestPlateauSchedule(t *testing.T) {
+	s := NewPlateauSchedule(1, 0.5, 2)
+	s.MinRate = 0.2
+
+	//Rate after observing each loss. The rate decays when the loss does not improve for two epochs.
+	tests := []struct {
+		loss float64
+		rate float64
+	}{
+		{1.0, 1},
+		{1.0, 1},
+		{1.0, 0.5},
+		{0.9, 0.5},
+		{0.95, 0.5},
+		{0.95, 0.25},
+		{0.95, 0.25},
+		{0.95, 0.2},
+	}
+
+	for i, test := range tests {
+		s.ObserveLoss(test.loss)
+		assert.Equal(t, test.rate, s.LearningRate(i, 100), "Rate after observing loss %d", i)
+	}
+}